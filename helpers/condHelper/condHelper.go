@@ -0,0 +1,675 @@
+package condHelper
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrInvalidColumn 列名不是合法标识符（只允许 column 或 table.column）
+	ErrInvalidColumn = errors.New("condHelper: invalid column")
+	// ErrColumnNotAllowed 列不在白名单中
+	ErrColumnNotAllowed = errors.New("condHelper: column not allowed")
+	// ErrInvalidOperator 不支持的比较符
+	ErrInvalidOperator = errors.New("condHelper: invalid operator")
+	// ErrInvalidValue 值的类型不符合要求，例如 IN 传入了非切片
+	ErrInvalidValue = errors.New("condHelper: invalid value")
+	// ErrUnsupportedDialect 当前数据库不支持该操作符
+	ErrUnsupportedDialect = errors.New("condHelper: operator not supported by dialect")
+)
+
+var (
+	identRegexp    = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+	jsonPathRegexp = regexp.MustCompile(`^\$(\.[A-Za-z_][A-Za-z0-9_]*|\[[0-9]+\])*$`)
+)
+
+// Cond 一个查询条件，可以直接作为 gorm 的 clause.Expression 使用
+// 条件按构造顺序输出，生成的 SQL 是稳定的
+type Cond interface {
+	clause.Expression
+	// columns 条件中引用到的列，用于白名单校验
+	columns() []string
+}
+
+// -------BEGIN------组合条件-----BEGIN--------
+
+// group AND / OR 组合
+type group struct {
+	op    string
+	conds []Cond
+}
+
+// And 所有条件都满足，nil 条件会被忽略
+func And(conds ...Cond) Cond {
+	return &group{op: "AND", conds: compact(conds)}
+}
+
+// Or 任一条件满足，nil 条件会被忽略
+func Or(conds ...Cond) Cond {
+	return &group{op: "OR", conds: compact(conds)}
+}
+
+// Append 向 And/Or 组追加条件，返回新的条件，原条件不变
+func Append(cond Cond, conds ...Cond) Cond {
+	if g, ok := cond.(*group); ok {
+		merged := make([]Cond, 0, len(g.conds)+len(conds))
+		merged = append(merged, g.conds...)
+		merged = append(merged, compact(conds)...)
+		return &group{op: g.op, conds: merged}
+	}
+	return And(append([]Cond{cond}, conds...)...)
+}
+
+func compact(conds []Cond) []Cond {
+	res := make([]Cond, 0, len(conds))
+	for _, c := range conds {
+		if c != nil {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// IsEmpty 是否为空组合
+func IsEmpty(cond Cond) bool {
+	if cond == nil {
+		return true
+	}
+	g, ok := cond.(*group)
+	if !ok {
+		return false
+	}
+	for _, c := range g.conds {
+		if !IsEmpty(c) {
+			return false
+		}
+	}
+	return true
+}
+
+func (g *group) Build(builder clause.Builder) {
+	conds := make([]Cond, 0, len(g.conds))
+	for _, c := range g.conds {
+		if !IsEmpty(c) {
+			conds = append(conds, c)
+		}
+	}
+
+	// 空的 AND 恒真，空的 OR 恒假
+	if len(conds) == 0 {
+		if g.op == "AND" {
+			builder.WriteString("1 = 1")
+		} else {
+			builder.WriteString("1 = 0")
+		}
+		return
+	}
+	if len(conds) == 1 {
+		conds[0].Build(builder)
+		return
+	}
+
+	builder.WriteByte('(')
+	for i, c := range conds {
+		if i > 0 {
+			builder.WriteString(" " + g.op + " ")
+		}
+		c.Build(builder)
+	}
+	builder.WriteByte(')')
+}
+
+func (g *group) columns() []string {
+	var cols []string
+	for _, c := range g.conds {
+		cols = append(cols, c.columns()...)
+	}
+	return cols
+}
+
+// not 取反
+type not struct {
+	cond Cond
+}
+
+// Not 条件取反
+func Not(cond Cond) Cond {
+	return &not{cond: cond}
+}
+
+func (n *not) Build(builder clause.Builder) {
+	builder.WriteString("NOT (")
+	n.cond.Build(builder)
+	builder.WriteByte(')')
+}
+
+func (n *not) columns() []string {
+	return n.cond.columns()
+}
+
+// -------END------组合条件----END---------
+
+// -------BEGIN------比较条件-----BEGIN--------
+
+// compare 二元比较
+type compare struct {
+	column string
+	op     string
+	value  interface{}
+}
+
+var compareOps = map[string]string{
+	"=": "=", "!=": "<>", "<>": "<>", ">": ">", ">=": ">=", "<": "<", "<=": "<=",
+	"like": "LIKE", "not like": "NOT LIKE",
+}
+
+// Compare 通用比较，op 可选 = != <> > >= < <= like "not like"
+func Compare(column, op string, value interface{}) Cond {
+	return &compare{column: column, op: strings.ToLower(strings.TrimSpace(op)), value: value}
+}
+
+func Eq(column string, value interface{}) Cond  { return Compare(column, "=", value) }
+func Neq(column string, value interface{}) Cond { return Compare(column, "<>", value) }
+func Gt(column string, value interface{}) Cond  { return Compare(column, ">", value) }
+func Gte(column string, value interface{}) Cond { return Compare(column, ">=", value) }
+func Lt(column string, value interface{}) Cond  { return Compare(column, "<", value) }
+func Lte(column string, value interface{}) Cond { return Compare(column, "<=", value) }
+
+// Like 模糊匹配，pattern 原样传入，需要自行添加 %
+func Like(column, pattern string) Cond { return Compare(column, "like", pattern) }
+
+// Contains 包含关键字，会转义关键字中的 % 与 _
+func Contains(column, keyword string) Cond {
+	return Compare(column, "like", "%"+EscapeLike(keyword)+"%")
+}
+
+// EscapeLike 转义 LIKE 通配符
+func EscapeLike(keyword string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(keyword)
+}
+
+func (c *compare) Build(builder clause.Builder) {
+	op, ok := compareOps[c.op]
+	if !ok {
+		_ = builder.AddError(fmt.Errorf("%w: %s", ErrInvalidOperator, c.op))
+		return
+	}
+	builder.WriteQuoted(toColumn(c.column))
+	builder.WriteString(" " + op + " ")
+	builder.AddVar(builder, c.value)
+}
+
+func (c *compare) columns() []string { return []string{c.column} }
+
+// isNull 空值判断
+type isNull struct {
+	column string
+	not    bool
+}
+
+// IsNull IS NULL
+func IsNull(column string) Cond { return &isNull{column: column} }
+
+// IsNotNull IS NOT NULL
+func IsNotNull(column string) Cond { return &isNull{column: column, not: true} }
+
+func (c *isNull) Build(builder clause.Builder) {
+	builder.WriteQuoted(toColumn(c.column))
+	if c.not {
+		builder.WriteString(" IS NOT NULL")
+	} else {
+		builder.WriteString(" IS NULL")
+	}
+}
+
+func (c *isNull) columns() []string { return []string{c.column} }
+
+// between 区间
+type between struct {
+	column   string
+	from, to interface{}
+}
+
+// Between 闭区间 [from, to]
+func Between(column string, from, to interface{}) Cond {
+	return &between{column: column, from: from, to: to}
+}
+
+func (c *between) Build(builder clause.Builder) {
+	builder.WriteQuoted(toColumn(c.column))
+	builder.WriteString(" BETWEEN ")
+	builder.AddVar(builder, c.from)
+	builder.WriteString(" AND ")
+	builder.AddVar(builder, c.to)
+}
+
+func (c *between) columns() []string { return []string{c.column} }
+
+// in 集合
+type in struct {
+	column string
+	values interface{}
+	not    bool
+}
+
+// In 任意类型的切片或数组（也可以是指向切片的指针），空集合恒假
+func In(column string, values interface{}) Cond {
+	return &in{column: column, values: values}
+}
+
+// NotIn 不在集合中，空集合恒真
+func NotIn(column string, values interface{}) Cond {
+	return &in{column: column, values: values, not: true}
+}
+
+func (c *in) Build(builder clause.Builder) {
+	values, err := toSlice(c.values)
+	if err != nil {
+		_ = builder.AddError(err)
+		return
+	}
+	if len(values) == 0 {
+		if c.not {
+			builder.WriteString("1 = 1")
+		} else {
+			builder.WriteString("1 = 0")
+		}
+		return
+	}
+
+	builder.WriteQuoted(toColumn(c.column))
+	if c.not {
+		builder.WriteString(" NOT IN (")
+	} else {
+		builder.WriteString(" IN (")
+	}
+	for i, v := range values {
+		if i > 0 {
+			builder.WriteByte(',')
+		}
+		builder.AddVar(builder, v)
+	}
+	builder.WriteByte(')')
+}
+
+func (c *in) columns() []string { return []string{c.column} }
+
+// toSlice 将任意切片展开为 []interface{}，[]byte 视为单个值
+func toSlice(values interface{}) ([]interface{}, error) {
+	rv := reflect.ValueOf(values)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+	if (rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array) || rv.Type().Elem().Kind() == reflect.Uint8 {
+		return nil, fmt.Errorf("%w: IN requires a slice, got %T", ErrInvalidValue, values)
+	}
+	res := make([]interface{}, rv.Len())
+	for i := range res {
+		res[i] = rv.Index(i).Interface()
+	}
+	return res, nil
+}
+
+// -------END------比较条件----END---------
+
+// -------BEGIN------JSON 与全文检索-----BEGIN--------
+
+// jsonPath JSON 字段中某个路径的值比较，按文本比较
+type jsonPath struct {
+	column string
+	path   string
+	op     string
+	value  interface{}
+}
+
+// JSONPath 比较 JSON 字段中 path 处的值（按文本比较），path 形如 $.a.b 或 $.list[0]
+func JSONPath(column, path, op string, value interface{}) Cond {
+	return &jsonPath{column: column, path: path, op: strings.ToLower(strings.TrimSpace(op)), value: value}
+}
+
+// JSONEq JSON 字段中 path 处的值等于 value
+func JSONEq(column, path string, value interface{}) Cond {
+	return JSONPath(column, path, "=", value)
+}
+
+func (c *jsonPath) Build(builder clause.Builder) {
+	op, ok := compareOps[c.op]
+	if !ok {
+		_ = builder.AddError(fmt.Errorf("%w: %s", ErrInvalidOperator, c.op))
+		return
+	}
+	if !jsonPathRegexp.MatchString(c.path) {
+		_ = builder.AddError(fmt.Errorf("%w: json path %q", ErrInvalidValue, c.path))
+		return
+	}
+
+	switch dialectOf(builder) {
+	case "postgres":
+		builder.WriteQuoted(toColumn(c.column))
+		builder.WriteString(" #>> CAST(")
+		builder.AddVar(builder, pgPath(c.path))
+		builder.WriteString(" AS text[])")
+	case "sqlite":
+		builder.WriteString("json_extract(")
+		builder.WriteQuoted(toColumn(c.column))
+		builder.WriteString(", ")
+		builder.AddVar(builder, c.path)
+		builder.WriteByte(')')
+	default:
+		builder.WriteString("JSON_UNQUOTE(JSON_EXTRACT(")
+		builder.WriteQuoted(toColumn(c.column))
+		builder.WriteString(", ")
+		builder.AddVar(builder, c.path)
+		builder.WriteString("))")
+	}
+	builder.WriteString(" " + op + " ")
+	builder.AddVar(builder, c.value)
+}
+
+func (c *jsonPath) columns() []string { return []string{c.column} }
+
+// pgPath $.a.list[0] => {a,list,0}
+func pgPath(path string) string {
+	path = strings.TrimPrefix(path, "$")
+	path = strings.NewReplacer("[", ".", "]", "").Replace(path)
+	return "{" + strings.Join(strings.FieldsFunc(path, func(r rune) bool { return r == '.' }), ",") + "}"
+}
+
+// jsonContains JSON 包含
+type jsonContains struct {
+	column string
+	value  string
+}
+
+// JSONContains JSON 字段包含 value（value 为 JSON 文本，例如 `{"tag":"a"}` 或 `[1]`）
+func JSONContains(column, value string) Cond {
+	return &jsonContains{column: column, value: value}
+}
+
+func (c *jsonContains) Build(builder clause.Builder) {
+	switch dialectOf(builder) {
+	case "postgres":
+		builder.WriteQuoted(toColumn(c.column))
+		builder.WriteString(" @> CAST(")
+		builder.AddVar(builder, c.value)
+		builder.WriteString(" AS jsonb)")
+	case "sqlite":
+		_ = builder.AddError(fmt.Errorf("%w: JSONContains", ErrUnsupportedDialect))
+	default:
+		builder.WriteString("JSON_CONTAINS(")
+		builder.WriteQuoted(toColumn(c.column))
+		builder.WriteString(", ")
+		builder.AddVar(builder, c.value)
+		builder.WriteByte(')')
+	}
+}
+
+func (c *jsonContains) columns() []string { return []string{c.column} }
+
+// jsonHasKey JSON 路径存在
+type jsonHasKey struct {
+	column string
+	path   string
+}
+
+// JSONHasKey JSON 字段中存在 path（值为 JSON null 也算存在），path 形如 $.a.b
+func JSONHasKey(column, path string) Cond {
+	return &jsonHasKey{column: column, path: path}
+}
+
+func (c *jsonHasKey) Build(builder clause.Builder) {
+	if !jsonPathRegexp.MatchString(c.path) {
+		_ = builder.AddError(fmt.Errorf("%w: json path %q", ErrInvalidValue, c.path))
+		return
+	}
+	switch dialectOf(builder) {
+	case "postgres":
+		// 不使用 ? 运算符，避免与占位符冲突
+		builder.WriteQuoted(toColumn(c.column))
+		builder.WriteString(" #> CAST(")
+		builder.AddVar(builder, pgPath(c.path))
+		builder.WriteString(" AS text[]) IS NOT NULL")
+	case "sqlite":
+		builder.WriteString("json_type(")
+		builder.WriteQuoted(toColumn(c.column))
+		builder.WriteString(", ")
+		builder.AddVar(builder, c.path)
+		builder.WriteString(") IS NOT NULL")
+	default:
+		builder.WriteString("JSON_CONTAINS_PATH(")
+		builder.WriteQuoted(toColumn(c.column))
+		builder.WriteString(", 'one', ")
+		builder.AddVar(builder, c.path)
+		builder.WriteByte(')')
+	}
+}
+
+func (c *jsonHasKey) columns() []string { return []string{c.column} }
+
+// MatchMode 全文检索模式
+type MatchMode int8
+
+const (
+	NaturalMode MatchMode = 1 // 自然语言（pg 使用 plainto_tsquery）
+	BooleanMode MatchMode = 2 // 布尔模式（pg 使用 to_tsquery）
+)
+
+// match 全文检索
+type match struct {
+	cols  []string
+	query string
+	mode  MatchMode
+}
+
+// Match 全文检索，mysql 需要在 cols 上建立 FULLTEXT 索引
+func Match(cols []string, query string, mode MatchMode) Cond {
+	return &match{cols: cols, query: query, mode: mode}
+}
+
+func (c *match) Build(builder clause.Builder) {
+	if len(c.cols) == 0 {
+		_ = builder.AddError(fmt.Errorf("%w: match without columns", ErrInvalidColumn))
+		return
+	}
+
+	switch dialectOf(builder) {
+	case "postgres":
+		builder.WriteString("to_tsvector(concat_ws(' '")
+		for _, col := range c.cols {
+			builder.WriteString(", ")
+			builder.WriteQuoted(toColumn(col))
+		}
+		if c.mode == BooleanMode {
+			builder.WriteString(")) @@ to_tsquery(")
+		} else {
+			builder.WriteString(")) @@ plainto_tsquery(")
+		}
+		builder.AddVar(builder, c.query)
+		builder.WriteByte(')')
+	case "sqlite":
+		_ = builder.AddError(fmt.Errorf("%w: Match", ErrUnsupportedDialect))
+	default:
+		builder.WriteString("MATCH (")
+		for i, col := range c.cols {
+			if i > 0 {
+				builder.WriteByte(',')
+			}
+			builder.WriteQuoted(toColumn(col))
+		}
+		builder.WriteString(") AGAINST (")
+		builder.AddVar(builder, c.query)
+		if c.mode == BooleanMode {
+			builder.WriteString(" IN BOOLEAN MODE)")
+		} else {
+			builder.WriteString(" IN NATURAL LANGUAGE MODE)")
+		}
+	}
+}
+
+func (c *match) columns() []string { return c.cols }
+
+// -------END------JSON 与全文检索----END---------
+
+// -------BEGIN------校验与输出-----BEGIN--------
+
+// Whitelist 允许出现在条件中的列，键为 column 或 table.column
+type Whitelist map[string]struct{}
+
+// Allow 构造白名单
+func Allow(cols ...string) Whitelist {
+	w := make(Whitelist, len(cols))
+	for _, col := range cols {
+		w[col] = struct{}{}
+	}
+	return w
+}
+
+// Check 校验条件中的列：必须是合法标识符，白名单非空时必须在白名单中
+// table.column 在白名单只包含 column 时同样放行
+func (w Whitelist) Check(cond Cond) error {
+	if cond == nil {
+		return nil
+	}
+	for _, col := range cond.columns() {
+		if !ValidColumn(col) {
+			return fmt.Errorf("%w: %q", ErrInvalidColumn, col)
+		}
+		if len(w) == 0 {
+			continue
+		}
+		if _, ok := w[col]; ok {
+			continue
+		}
+		if idx := strings.IndexByte(col, '.'); idx >= 0 {
+			if _, ok := w[col[idx+1:]]; ok {
+				continue
+			}
+		}
+		return fmt.Errorf("%w: %q", ErrColumnNotAllowed, col)
+	}
+	return nil
+}
+
+// Scope 校验后转为 gorm scope，校验失败时错误写入 db.Error
+func Scope(cond Cond, allow Whitelist) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if IsEmpty(cond) {
+			return db
+		}
+		if err := allow.Check(cond); err != nil {
+			_ = db.AddError(err)
+			return db
+		}
+		return db.Clauses(clause.Where{Exprs: []clause.Expression{cond}})
+	}
+}
+
+// ToSQL 校验后输出 where 语句与参数，用于 db.Where(sql, vals...)
+// dialect 为 mysql / postgres / sqlite，决定标识符引号与 JSON、全文检索的写法
+func ToSQL(cond Cond, allow Whitelist, dialect string) (whereSQL string, vals []interface{}, err error) {
+	if IsEmpty(cond) {
+		return "", nil, nil
+	}
+	if err = allow.Check(cond); err != nil {
+		return "", nil, err
+	}
+	b := &sqlBuilder{dialect: dialect}
+	cond.Build(b)
+	if b.err != nil {
+		return "", nil, b.err
+	}
+	return b.sql.String(), b.vars, nil
+}
+
+// ValidColumn 是否为合法的 column 或 table.column
+func ValidColumn(col string) bool {
+	return identRegexp.MatchString(col)
+}
+
+// Column 将 column 或 table.column 转为 gorm 的列，由 gorm 按数据库加引号
+func Column(col string) clause.Column {
+	return toColumn(col)
+}
+
+// toColumn column 或 table.column
+func toColumn(col string) clause.Column {
+	if idx := strings.IndexByte(col, '.'); idx >= 0 {
+		return clause.Column{Table: col[:idx], Name: col[idx+1:]}
+	}
+	return clause.Column{Name: col}
+}
+
+// dialectOf 当前数据库类型
+func dialectOf(builder clause.Builder) string {
+	switch b := builder.(type) {
+	case *gorm.Statement:
+		if b.DB != nil && b.DB.Dialector != nil {
+			return b.DB.Dialector.Name()
+		}
+	case *sqlBuilder:
+		return b.dialect
+	}
+	return "mysql"
+}
+
+// sqlBuilder 不依赖数据库连接的 clause.Builder，占位符统一为 ?
+type sqlBuilder struct {
+	dialect string
+	sql     strings.Builder
+	vars    []interface{}
+	err     error
+}
+
+func (b *sqlBuilder) WriteByte(c byte) error { return b.sql.WriteByte(c) }
+
+func (b *sqlBuilder) WriteString(s string) (int, error) { return b.sql.WriteString(s) }
+
+func (b *sqlBuilder) WriteQuoted(field interface{}) {
+	quote := byte('`')
+	if b.dialect == "postgres" || b.dialect == "sqlite" {
+		quote = '"'
+	}
+	write := func(name string) {
+		b.sql.WriteByte(quote)
+		b.sql.WriteString(name)
+		b.sql.WriteByte(quote)
+	}
+	switch v := field.(type) {
+	case clause.Column:
+		if v.Table != "" {
+			write(v.Table)
+			b.sql.WriteByte('.')
+		}
+		write(v.Name)
+	default:
+		_ = b.AddError(fmt.Errorf("%w: %v", ErrInvalidColumn, field))
+	}
+}
+
+func (b *sqlBuilder) AddVar(writer clause.Writer, vars ...interface{}) {
+	for i, v := range vars {
+		if i > 0 {
+			writer.WriteByte(',')
+		}
+		writer.WriteByte('?')
+		b.vars = append(b.vars, v)
+	}
+}
+
+func (b *sqlBuilder) AddError(err error) error {
+	if b.err == nil {
+		b.err = err
+	}
+	return err
+}
+
+// -------END------校验与输出----END---------
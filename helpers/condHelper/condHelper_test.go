@@ -0,0 +1,81 @@
+package condHelper
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestToSQL(t *testing.T) {
+	tests := []struct {
+		name    string
+		cond    Cond
+		allow   Whitelist
+		dialect string
+		sql     string
+		vals    []interface{}
+	}{
+		{"eq", Eq("status", 1), nil, "mysql", "`status` = ?", []interface{}{1}},
+		{"table column", Gt("u.id", 10), nil, "mysql", "`u`.`id` > ?", []interface{}{10}},
+		{"op case and spaces", Compare("name", " NOT LIKE ", "a%"), nil, "mysql", "`name` NOT LIKE ?", []interface{}{"a%"}},
+		{"bang equal", Compare("id", "!=", 1), nil, "mysql", "`id` <> ?", []interface{}{1}},
+		{"postgres quote", Lte("id", 5), nil, "postgres", `"id" <= ?`, []interface{}{5}},
+		{"in", In("id", []int64{1, 2}), nil, "mysql", "`id` IN (?,?)", []interface{}{int64(1), int64(2)}},
+		{"empty in", In("id", []int{}), nil, "mysql", "1 = 0", nil},
+		{"empty not in", NotIn("id", []int{}), nil, "mysql", "1 = 1", nil},
+		{"between", Between("age", 1, 9), nil, "mysql", "`age` BETWEEN ? AND ?", []interface{}{1, 9}},
+		{"is null", IsNull("deleted_at"), nil, "mysql", "`deleted_at` IS NULL", nil},
+		{"and or", And(Eq("a", 1), Or(Eq("b", 2), IsNotNull("c"))), nil, "mysql",
+			"(`a` = ? AND (`b` = ? OR `c` IS NOT NULL))", []interface{}{1, 2}},
+		{"not", Not(Eq("a", 1)), nil, "mysql", "NOT (`a` = ?)", []interface{}{1}},
+		{"escaped contains", Contains("title", "50%_off"), nil, "mysql", "`title` LIKE ?", []interface{}{`%50\%\_off%`}},
+		{"whitelisted", Eq("u.status", 1), Allow("status"), "mysql", "`u`.`status` = ?", []interface{}{1}},
+		{"empty", And(), nil, "mysql", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, vals, err := ToSQL(tt.cond, tt.allow, tt.dialect)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if sql != tt.sql {
+				t.Errorf("sql = %q, want %q", sql, tt.sql)
+			}
+			if !reflect.DeepEqual(vals, tt.vals) {
+				t.Errorf("vals = %#v, want %#v", vals, tt.vals)
+			}
+		})
+	}
+}
+
+func TestToSQLInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		cond  Cond
+		allow Whitelist
+		want  error
+	}{
+		{"injected column", Eq("id = 1 OR 1", 1), nil, ErrInvalidColumn},
+		{"quoted column", Eq("`id`", 1), nil, ErrInvalidColumn},
+		{"three part column", Eq("db.t.id", 1), nil, ErrInvalidColumn},
+		{"empty column", IsNull(""), nil, ErrInvalidColumn},
+		{"nested invalid column", And(Eq("a", 1), Or(Eq("b;--", 2))), nil, ErrInvalidColumn},
+		{"not whitelisted", Eq("password", "x"), Allow("id", "name"), ErrColumnNotAllowed},
+		{"unknown operator", Compare("id", "regexp", ".*"), nil, ErrInvalidOperator},
+		{"sql in operator", Compare("id", "= 1 OR", 1), nil, ErrInvalidOperator},
+		{"in non slice", In("id", 1), nil, ErrInvalidValue},
+		{"in bytes", In("id", []byte("ab")), nil, ErrInvalidValue},
+		{"bad json path", JSONEq("data", "a.b') OR 1", 1), nil, ErrInvalidValue},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, vals, err := ToSQL(tt.cond, tt.allow, "mysql")
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+			if sql != "" || vals != nil {
+				t.Errorf("got sql %q vals %v on error", sql, vals)
+			}
+		})
+	}
+}
@@ -0,0 +1,404 @@
+package cursorHelper
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wiidz/goutil/helpers/condHelper"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+var (
+	// ErrInvalidCursor 游标格式错误、签名不匹配或与排序键不一致
+	ErrInvalidCursor = errors.New("cursorHelper: invalid cursor")
+	// ErrInvalidOrder 排序键不是合法列名
+	ErrInvalidOrder = errors.New("cursorHelper: invalid order")
+)
+
+// Token 不透明的游标，带 HMAC 签名，前端原样回传即可
+type Token string
+
+// Direction 翻页方向
+type Direction int8
+
+const (
+	Next Direction = 1 // 下一页
+	Prev Direction = 2 // 上一页
+)
+
+// SortKey 排序键，列必须非 NULL，最后一个键必须唯一（通常为主键），否则翻页可能丢行
+type SortKey struct {
+	Column string
+	Desc   bool
+}
+
+// Page 游标分页请求
+type Page struct {
+	Keys      []SortKey
+	Size      int   // 每页条数，默认 10，最大 100
+	Cursor    Token // 为空时返回第一页
+	SkipCount bool  // 不统计总数，大表建议开启
+}
+
+// PageInfo 游标分页结果
+type PageInfo struct {
+	Next    Token `json:"next_cursor,omitempty"` // 下一页游标，HasNext 为 false 时为空
+	Prev    Token `json:"prev_cursor,omitempty"` // 上一页游标，HasPrev 为 false 时为空
+	HasNext bool  `json:"has_next"`
+	HasPrev bool  `json:"has_prev"`
+	Total   int64 `json:"total"` // SkipCount 时为 -1
+}
+
+// Request 可嵌入请求参数结构体中，供 HTTP 层透传游标
+type Request struct {
+	Cursor    Token     `json:"cursor"`     // 上一次返回的 next_cursor / prev_cursor
+	UseCursor bool      `json:"use_cursor"` // 使用游标分页，Cursor 非空时自动开启
+	SkipCount bool      `json:"skip_count"` // 不统计总数
+	PageInfo  *PageInfo `json:"-"`          // 查询后回填
+}
+
+// CursorPage 按排序语句生成分页请求，未开启游标分页时返回 nil
+// 排序键中没有 id 时会按最后一个键的方向追加 id，保证排序唯一
+func (r *Request) CursorPage(order string, size int) (*Page, error) {
+	if !r.UseCursor && r.Cursor == "" {
+		return nil, nil
+	}
+	keys, err := ParseOrder(order)
+	if err != nil {
+		return nil, err
+	}
+	return &Page{Keys: WithTieBreaker(keys, "id"), Size: size, Cursor: r.Cursor, SkipCount: r.SkipCount}, nil
+}
+
+// SetPageInfo 回填分页结果
+func (r *Request) SetPageInfo(info *PageInfo) {
+	r.PageInfo = info
+}
+
+// GetPageInfo 分页结果
+func (r *Request) GetPageInfo() *PageInfo {
+	return r.PageInfo
+}
+
+// ParseOrder 解析 "created_at desc, id desc" 形式的排序语句
+func ParseOrder(order string) ([]SortKey, error) {
+	var keys []SortKey
+	for _, part := range strings.Split(order, ",") {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			continue
+		}
+		key := SortKey{Column: fields[0]}
+		if len(fields) > 2 {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidOrder, part)
+		}
+		if len(fields) == 2 {
+			switch strings.ToLower(fields[1]) {
+			case "asc":
+			case "desc":
+				key.Desc = true
+			default:
+				return nil, fmt.Errorf("%w: %q", ErrInvalidOrder, part)
+			}
+		}
+		if !condHelper.ValidColumn(key.Column) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidOrder, key.Column)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// WithTieBreaker 排序键中没有 column 时追加，方向与最后一个键一致
+func WithTieBreaker(keys []SortKey, column string) []SortKey {
+	for _, key := range keys {
+		if key.Column == column || strings.HasSuffix(key.Column, "."+column) {
+			return keys
+		}
+	}
+	desc := len(keys) > 0 && keys[len(keys)-1].Desc
+	return append(append([]SortKey{}, keys...), SortKey{Column: column, Desc: desc})
+}
+
+// -------BEGIN------签名-----BEGIN--------
+
+var (
+	secretMu sync.RWMutex
+	secret   = randomSecret()
+)
+
+func randomSecret() []byte {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return b
+}
+
+// SetSecret 设置签名密钥；默认使用进程启动时生成的随机密钥，多实例部署时必须设置相同的密钥
+func SetSecret(key []byte) {
+	secretMu.Lock()
+	secret = append([]byte{}, key...)
+	secretMu.Unlock()
+}
+
+func sign(payload []byte) []byte {
+	secretMu.RLock()
+	mac := hmac.New(sha256.New, secret)
+	secretMu.RUnlock()
+	mac.Write(payload)
+	return mac.Sum(nil)[:16]
+}
+
+// payload 游标内容
+type payload struct {
+	Keys   string       `json:"k"` // 排序键签名，防止换了排序条件后继续使用旧游标
+	Dir    Direction    `json:"d"`
+	Values []typedValue `json:"v"`
+}
+
+// typedValue 保留值的类型，解码后与原类型一致
+type typedValue struct {
+	Type  string `json:"t"`
+	Value string `json:"v"`
+}
+
+func keysSignature(keys []SortKey) string {
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = key.Column
+		if key.Desc {
+			parts[i] += "-"
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func encode(keys []SortKey, dir Direction, values []interface{}) (Token, error) {
+	p := payload{Keys: keysSignature(keys), Dir: dir, Values: make([]typedValue, len(values))}
+	for i, v := range values {
+		tv, err := toTyped(v)
+		if err != nil {
+			return "", err
+		}
+		p.Values[i] = tv
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		return "", err
+	}
+	return Token(base64.RawURLEncoding.EncodeToString(b) + "." + base64.RawURLEncoding.EncodeToString(sign(b))), nil
+}
+
+func decode(token Token, keys []SortKey) (Direction, []interface{}, error) {
+	body, sig, ok := strings.Cut(string(token), ".")
+	if !ok {
+		return 0, nil, ErrInvalidCursor
+	}
+	b, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return 0, nil, ErrInvalidCursor
+	}
+	s, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(s, sign(b)) {
+		return 0, nil, ErrInvalidCursor
+	}
+
+	var p payload
+	if err = json.Unmarshal(b, &p); err != nil {
+		return 0, nil, ErrInvalidCursor
+	}
+	if p.Keys != keysSignature(keys) || len(p.Values) != len(keys) || (p.Dir != Next && p.Dir != Prev) {
+		return 0, nil, ErrInvalidCursor
+	}
+	values := make([]interface{}, len(p.Values))
+	for i, tv := range p.Values {
+		if values[i], err = fromTyped(tv); err != nil {
+			return 0, nil, ErrInvalidCursor
+		}
+	}
+	return p.Dir, values, nil
+}
+
+func toTyped(v interface{}) (typedValue, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return typedValue{Type: "n"}, nil
+		}
+		rv = rv.Elem()
+	}
+	if t, ok := rv.Interface().(time.Time); ok {
+		return typedValue{Type: "t", Value: t.Format(time.RFC3339Nano)}, nil
+	}
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return typedValue{Type: "i", Value: strconv.FormatInt(rv.Int(), 10)}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return typedValue{Type: "u", Value: strconv.FormatUint(rv.Uint(), 10)}, nil
+	case reflect.Float32, reflect.Float64:
+		return typedValue{Type: "f", Value: strconv.FormatFloat(rv.Float(), 'g', -1, 64)}, nil
+	case reflect.String:
+		return typedValue{Type: "s", Value: rv.String()}, nil
+	case reflect.Bool:
+		return typedValue{Type: "b", Value: strconv.FormatBool(rv.Bool())}, nil
+	}
+	return typedValue{}, fmt.Errorf("cursorHelper: unsupported sort key type %T", v)
+}
+
+func fromTyped(tv typedValue) (interface{}, error) {
+	switch tv.Type {
+	case "n":
+		return nil, nil
+	case "t":
+		return time.Parse(time.RFC3339Nano, tv.Value)
+	case "i":
+		return strconv.ParseInt(tv.Value, 10, 64)
+	case "u":
+		return strconv.ParseUint(tv.Value, 10, 64)
+	case "f":
+		return strconv.ParseFloat(tv.Value, 64)
+	case "s":
+		return tv.Value, nil
+	case "b":
+		return strconv.ParseBool(tv.Value)
+	}
+	return nil, ErrInvalidCursor
+}
+
+// -------END------签名----END---------
+
+// -------BEGIN------查询-----BEGIN--------
+
+// Paginate 在已拼好条件的 db 上执行游标分页，dest 为指向切片的指针
+// db 上不要再设置 Order / Limit / Offset，排序由 page.Keys 决定
+func Paginate(db *gorm.DB, page Page, dest interface{}) (*PageInfo, error) {
+	if len(page.Keys) == 0 {
+		return nil, fmt.Errorf("%w: empty sort keys", ErrInvalidOrder)
+	}
+	size := page.Size
+	if size <= 0 || size > 100 {
+		size = 10
+	}
+
+	dir := Next
+	var values []interface{}
+	if page.Cursor != "" {
+		var err error
+		if dir, values, err = decode(page.Cursor, page.Keys); err != nil {
+			return nil, err
+		}
+	}
+
+	info := &PageInfo{Total: -1}
+
+	//【1】总数
+	if !page.SkipCount {
+		if err := db.Session(&gorm.Session{}).Model(dest).Count(&info.Total).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	//【2】定位到游标之后，上一页时反向排序
+	query := db.Session(&gorm.Session{})
+	if values != nil {
+		query = query.Scopes(condHelper.Scope(seekCond(page.Keys, values, dir), nil))
+	}
+	for _, key := range page.Keys {
+		query = query.Order(clause.OrderByColumn{Column: condHelper.Column(key.Column), Desc: key.Desc != (dir == Prev)})
+	}
+	result := query.Limit(size + 1).Find(dest)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	//【3】多查的一条用于判断是否还有数据
+	rows := reflect.ValueOf(dest).Elem()
+	hasMore := rows.Len() > size
+	if hasMore {
+		rows.Set(rows.Slice(0, size))
+	}
+	if dir == Prev {
+		reverse(rows)
+		info.HasPrev, info.HasNext = hasMore, true
+	} else {
+		info.HasNext, info.HasPrev = hasMore, page.Cursor != ""
+	}
+	if rows.Len() == 0 {
+		return info, nil
+	}
+
+	//【4】生成首尾游标
+	sch := result.Statement.Schema
+	if sch == nil {
+		return nil, fmt.Errorf("%w: dest must be a pointer to a slice of models", ErrInvalidOrder)
+	}
+	ctx := result.Statement.Context
+	var err error
+	if info.HasNext {
+		if info.Next, err = rowToken(ctx, sch, page.Keys, rows.Index(rows.Len()-1), Next); err != nil {
+			return nil, err
+		}
+	}
+	if info.HasPrev {
+		if info.Prev, err = rowToken(ctx, sch, page.Keys, rows.Index(0), Prev); err != nil {
+			return nil, err
+		}
+	}
+	return info, nil
+}
+
+// seekCond (k1, k2, ...) 元组比较展开为 k1 > v1 OR (k1 = v1 AND k2 > v2) ...
+func seekCond(keys []SortKey, values []interface{}, dir Direction) condHelper.Cond {
+	ors := make([]condHelper.Cond, 0, len(keys))
+	for i, key := range keys {
+		ands := make([]condHelper.Cond, 0, i+1)
+		for j := 0; j < i; j++ {
+			ands = append(ands, condHelper.Eq(keys[j].Column, values[j]))
+		}
+		op := ">"
+		if key.Desc != (dir == Prev) {
+			op = "<"
+		}
+		ands = append(ands, condHelper.Compare(key.Column, op, values[i]))
+		ors = append(ors, condHelper.And(ands...))
+	}
+	return condHelper.Or(ors...)
+}
+
+func rowToken(ctx context.Context, sch *schema.Schema, keys []SortKey, row reflect.Value, dir Direction) (Token, error) {
+	row = reflect.Indirect(row)
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		column := key.Column
+		if idx := strings.LastIndexByte(column, '.'); idx >= 0 {
+			column = column[idx+1:]
+		}
+		field := sch.LookUpField(column)
+		if field == nil {
+			return "", fmt.Errorf("%w: %s not found in %s", ErrInvalidOrder, key.Column, sch.Name)
+		}
+		values[i], _ = field.ValueOf(ctx, row)
+	}
+	return encode(keys, dir, values)
+}
+
+func reverse(rows reflect.Value) {
+	swap := reflect.Swapper(rows.Interface())
+	for i, j := 0, rows.Len()-1; i < j; i, j = i+1, j-1 {
+		swap(i, j)
+	}
+}
+
+// -------END------查询----END---------
@@ -0,0 +1,182 @@
+package cursorHelper
+
+import (
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wiidz/goutil/helpers/condHelper"
+)
+
+var testKeys = []SortKey{{Column: "created_at", Desc: true}, {Column: "id", Desc: true}}
+
+func TestEncodeDecode(t *testing.T) {
+	at := time.Date(2024, 5, 6, 7, 8, 9, 123456789, time.UTC)
+	name := "bob"
+	tests := []struct {
+		name   string
+		keys   []SortKey
+		dir    Direction
+		values []interface{}
+		want   []interface{}
+	}{
+		{"time and int", testKeys, Next, []interface{}{at, 42}, []interface{}{at, int64(42)}},
+		{"prev", testKeys, Prev, []interface{}{at, int64(-1)}, []interface{}{at, int64(-1)}},
+		{"uint float", []SortKey{{Column: "score"}, {Column: "id"}}, Next, []interface{}{1.5, uint32(7)}, []interface{}{1.5, uint64(7)}},
+		{"string pointer", []SortKey{{Column: "name"}}, Next, []interface{}{&name}, []interface{}{"bob"}},
+		{"bool nil", []SortKey{{Column: "top"}, {Column: "t.id"}}, Next, []interface{}{true, (*int)(nil)}, []interface{}{true, nil}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := encode(tt.keys, tt.dir, tt.values)
+			if err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+			dir, values, err := decode(token, tt.keys)
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if dir != tt.dir {
+				t.Errorf("dir = %v, want %v", dir, tt.dir)
+			}
+			if !reflect.DeepEqual(values, tt.want) {
+				t.Errorf("values = %#v, want %#v", values, tt.want)
+			}
+		})
+	}
+
+	if _, err := encode([]SortKey{{Column: "tags"}}, Next, []interface{}{[]string{"a"}}); err == nil {
+		t.Error("encode accepted an unsupported value type")
+	}
+}
+
+func TestDecodeRejectsTampering(t *testing.T) {
+	token, err := encode(testKeys, Next, []interface{}{time.Unix(1700000000, 0).UTC(), 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, sig, _ := strings.Cut(string(token), ".")
+	raw, _ := base64.RawURLEncoding.DecodeString(body)
+	forged := strings.Replace(string(raw), `"10"`, `"99"`, 1)
+	if forged == string(raw) {
+		t.Fatal("payload layout changed, update the test")
+	}
+
+	resigned := func(payload string) Token {
+		return Token(base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." +
+			base64.RawURLEncoding.EncodeToString(sign([]byte(payload))))
+	}
+
+	tests := []struct {
+		name  string
+		token Token
+		keys  []SortKey
+	}{
+		{"empty", "", testKeys},
+		{"no signature", Token(body), testKeys},
+		{"garbage", "!!!.???", testKeys},
+		{"forged value", Token(base64.RawURLEncoding.EncodeToString([]byte(forged)) + "." + sig), testKeys},
+		{"truncated signature", Token(body + "." + sig[:len(sig)-2]), testKeys},
+		{"other sort keys", token, []SortKey{{Column: "created_at"}, {Column: "id"}}},
+		{"fewer sort keys", token, testKeys[:1]},
+		{"bad direction", resigned(strings.Replace(string(raw), `"d":1`, `"d":3`, 1)), testKeys},
+		{"bad value type", resigned(strings.Replace(string(raw), `"t":"i"`, `"t":"x"`, 1)), testKeys},
+		{"not json", resigned("not json"), testKeys},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := decode(tt.token, tt.keys); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("err = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+
+	t.Run("rotated secret", func(t *testing.T) {
+		secretMu.RLock()
+		old := append([]byte{}, secret...)
+		secretMu.RUnlock()
+		defer SetSecret(old)
+
+		SetSecret([]byte("another secret"))
+		if _, _, err := decode(token, testKeys); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("err = %v, want ErrInvalidCursor", err)
+		}
+	})
+}
+
+func TestSeekCond(t *testing.T) {
+	at := time.Unix(1700000000, 0)
+	tests := []struct {
+		name string
+		keys []SortKey
+		dir  Direction
+		sql  string
+	}{
+		{"desc next", testKeys, Next,
+			"(`created_at` < ? OR (`created_at` = ? AND `id` < ?))"},
+		{"desc prev", testKeys, Prev,
+			"(`created_at` > ? OR (`created_at` = ? AND `id` > ?))"},
+		{"asc next", []SortKey{{Column: "created_at"}, {Column: "id"}}, Next,
+			"(`created_at` > ? OR (`created_at` = ? AND `id` > ?))"},
+		{"mixed next", []SortKey{{Column: "created_at", Desc: true}, {Column: "id"}}, Next,
+			"(`created_at` < ? OR (`created_at` = ? AND `id` > ?))"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, vals, err := condHelper.ToSQL(seekCond(tt.keys, []interface{}{at, int64(5)}, tt.dir), nil, "mysql")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sql != tt.sql {
+				t.Errorf("sql = %q, want %q", sql, tt.sql)
+			}
+			if want := []interface{}{at, at, int64(5)}; !reflect.DeepEqual(vals, want) {
+				t.Errorf("vals = %#v, want %#v", vals, want)
+			}
+		})
+	}
+
+	sql, _, err := condHelper.ToSQL(seekCond([]SortKey{{Column: "id"}}, []interface{}{1}, Next), nil, "mysql")
+	if err != nil || sql != "`id` > ?" {
+		t.Errorf("single key: sql = %q, err = %v", sql, err)
+	}
+}
+
+func TestParseOrder(t *testing.T) {
+	tests := []struct {
+		order string
+		want  []SortKey
+		err   bool
+	}{
+		{"created_at desc, id desc", testKeys, false},
+		{" u.name ASC ,, id", []SortKey{{Column: "u.name"}, {Column: "id"}}, false},
+		{"", nil, false},
+		{"id sideways", nil, true},
+		{"id desc nulls", nil, true},
+		{"id;drop table", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.order, func(t *testing.T) {
+			keys, err := ParseOrder(tt.order)
+			if tt.err {
+				if !errors.Is(err, ErrInvalidOrder) {
+					t.Errorf("err = %v, want ErrInvalidOrder", err)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(keys, tt.want) {
+				t.Errorf("keys = %v, err = %v, want %v", keys, err, tt.want)
+			}
+		})
+	}
+
+	if keys := WithTieBreaker([]SortKey{{Column: "score", Desc: true}}, "id"); !reflect.DeepEqual(keys, []SortKey{{Column: "score", Desc: true}, {Column: "id", Desc: true}}) {
+		t.Errorf("WithTieBreaker = %v", keys)
+	}
+	if keys := WithTieBreaker([]SortKey{{Column: "t.id"}}, "id"); len(keys) != 1 {
+		t.Errorf("WithTieBreaker appended a duplicate id: %v", keys)
+	}
+}
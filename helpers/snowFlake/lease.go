@@ -0,0 +1,327 @@
+package snowFlake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wiidz/goutil/mngs/redisMng"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrNoWorkerID 所有机器id都已被占用
+var ErrNoWorkerID = errors.New("snowFlake: no free worker id")
+
+// WorkerLeaser 机器id租约，保证同一时刻每个机器id只被一个实例持有
+type WorkerLeaser interface {
+	// Acquire 在 [0, maxWorkerID] 中申请一个空闲的机器id
+	Acquire(ctx context.Context, maxWorkerID int64) (int64, error)
+	// Renew 续期，租约已被他人占用时返回 ErrLeaseLost
+	Renew(ctx context.Context) error
+	// Release 释放租约
+	Release(ctx context.Context) error
+	// TTL 租约有效期，续期间隔为 TTL/3
+	TTL() time.Duration
+}
+
+// workerLease 生成器持有的租约及续期协程
+type workerLease struct {
+	leaser WorkerLeaser
+	stop   chan struct{}
+	done   chan struct{}
+
+	deadline time.Time // 本地记录的租约到期时间，由 Snowflake 的锁保护；续期失败超过该时间后停止发号
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// NewLeasedSnowflake 通过租约自动分配机器id，并在后台定期续期
+// config.WorkerID 会被忽略；续期失败时生成器停止发号并返回 ErrLeaseLost
+func NewLeasedSnowflake(ctx context.Context, leaser WorkerLeaser, config *Config) (*Snowflake, error) {
+	if config == nil {
+		config = &Config{}
+	}
+	layout := config.Layout
+	if layout == (Layout{}) {
+		layout = DefaultLayout
+	}
+
+	// 以发起申请的时间计算到期时间，宁可提前停止发号也不与新持有者重叠
+	acquiredAt := time.Now()
+	workerID, err := leaser.Acquire(ctx, layout.MaxWorkerID())
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := *config
+	cfg.Layout = layout
+	cfg.WorkerID = workerID
+	s, err := NewSnowflakeWithConfig(&cfg)
+	if err != nil {
+		_ = leaser.Release(ctx)
+		return nil, err
+	}
+
+	s.lease = &workerLease{
+		leaser: leaser,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+
+		deadline: acquiredAt.Add(leaser.TTL()),
+	}
+	go s.keepLease()
+	return s, nil
+}
+
+// Close 停止续期并释放租约，重复调用返回首次的结果
+func (s *Snowflake) Close(ctx context.Context) error {
+	if s.lease == nil {
+		return nil
+	}
+	s.lease.closeOnce.Do(func() {
+		close(s.lease.stop)
+		<-s.lease.done
+
+		s.Lock()
+		s.leaseLost = true
+		s.Unlock()
+		s.lease.closeErr = s.lease.leaser.Release(ctx)
+	})
+	return s.lease.closeErr
+}
+
+// keepLease 定期续期，租约丢失后停止发号
+func (s *Snowflake) keepLease() {
+	defer close(s.lease.done)
+
+	interval := s.lease.leaser.TTL() / 3
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.lease.stop:
+			return
+		case <-ticker.C:
+			renewedAt := time.Now()
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			err := s.lease.leaser.Renew(ctx)
+			cancel()
+			if errors.Is(err, ErrLeaseLost) {
+				log.Println("snowFlake worker id lease lost", s.workerid)
+				s.Lock()
+				s.leaseLost = true
+				s.Unlock()
+				return
+			}
+			if err != nil {
+				// 暂时性错误：继续重试，本地到期时间不前移，到期后 NextID 拒绝发号
+				log.Println("snowFlake renew lease err", err)
+				continue
+			}
+			s.Lock()
+			s.lease.deadline = renewedAt.Add(s.lease.leaser.TTL())
+			s.Unlock()
+		}
+	}
+}
+
+// -------BEGIN------Redis 租约-----BEGIN--------
+
+// RedisLeaser 基于 redisMng 分布式锁的机器id租约，每个机器id对应一个键
+type RedisLeaser struct {
+	RedisMng *redisMng.RedisMng
+	Prefix   string        // 键前缀，默认 snowflake:worker
+	LeaseTTL time.Duration // 默认 30 秒
+
+	lock *redisMng.Lock
+}
+
+// NewRedisLeaser 返回 redis 租约
+func NewRedisLeaser(redisM *redisMng.RedisMng, prefix string, ttl time.Duration) *RedisLeaser {
+	if prefix == "" {
+		prefix = "snowflake:worker"
+	}
+	if ttl <= 0 {
+		ttl = 30 * time.Second
+	}
+	return &RedisLeaser{RedisMng: redisM, Prefix: prefix, LeaseTTL: ttl}
+}
+
+// Acquire 从随机位置开始依次尝试，避免多个实例同时启动时互相争抢
+func (l *RedisLeaser) Acquire(ctx context.Context, maxWorkerID int64) (int64, error) {
+	total := maxWorkerID + 1
+	start := rand.Int63n(total)
+	for i := int64(0); i < total; i++ {
+		workerID := (start + i) % total
+		lock, err := l.RedisMng.TryLock(ctx, fmt.Sprintf("%s:%d", l.Prefix, workerID), l.LeaseTTL)
+		if errors.Is(err, redisMng.ErrLockNotObtained) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		l.lock = lock
+		return workerID, nil
+	}
+	return 0, ErrNoWorkerID
+}
+
+// Renew 续期
+func (l *RedisLeaser) Renew(ctx context.Context) error {
+	err := l.lock.Refresh(ctx, l.LeaseTTL)
+	if errors.Is(err, redisMng.ErrLockNotHeld) {
+		return ErrLeaseLost
+	}
+	return err
+}
+
+// Release 释放
+func (l *RedisLeaser) Release(ctx context.Context) error {
+	err := l.lock.Unlock(ctx)
+	if errors.Is(err, redisMng.ErrLockNotHeld) {
+		return nil
+	}
+	return err
+}
+
+// TTL 租约有效期
+func (l *RedisLeaser) TTL() time.Duration {
+	return l.LeaseTTL
+}
+
+// -------END------Redis 租约----END---------
+
+// -------BEGIN------数据库租约-----BEGIN--------
+
+// WorkerLeaseRow 机器id租约表，每个机器id一行
+type WorkerLeaseRow struct {
+	WorkerID  int64     `gorm:"column:worker_id;primaryKey;autoIncrement:false" json:"worker_id"`
+	Holder    string    `gorm:"column:holder;size:128;not null;default:''" json:"holder"`
+	ExpiredAt time.Time `gorm:"column:expired_at;not null;index" json:"expired_at"`
+}
+
+// TableName 表名
+func (WorkerLeaseRow) TableName() string {
+	return "snowflake_worker_leases"
+}
+
+// DBLeaser 基于数据库行的机器id租约
+type DBLeaser struct {
+	DB       *gorm.DB
+	LeaseTTL time.Duration // 默认 30 秒
+
+	holder   string
+	workerID int64
+}
+
+// NewDBLeaser 返回数据库租约，需先执行 AutoMigrate
+func NewDBLeaser(db *gorm.DB, ttl time.Duration) *DBLeaser {
+	if ttl <= 0 {
+		ttl = 30 * time.Second
+	}
+	hostname, _ := os.Hostname()
+	return &DBLeaser{
+		DB:       db,
+		LeaseTTL: ttl,
+		holder:   hostname + ":" + uuid.NewString(),
+		workerID: -1,
+	}
+}
+
+// AutoMigrate 创建租约表
+func (l *DBLeaser) AutoMigrate(ctx context.Context) error {
+	return l.DB.WithContext(ctx).AutoMigrate(&WorkerLeaseRow{})
+}
+
+// Acquire 优先抢占已过期的行，没有时插入新行
+func (l *DBLeaser) Acquire(ctx context.Context, maxWorkerID int64) (int64, error) {
+	db := l.DB.WithContext(ctx)
+	now := time.Now()
+
+	//【1】抢占已过期的租约
+	var expired []int64
+	if err := db.Model(&WorkerLeaseRow{}).Where("expired_at < ? AND worker_id <= ?", now, maxWorkerID).
+		Order("worker_id").Pluck("worker_id", &expired).Error; err != nil {
+		return 0, err
+	}
+	for _, workerID := range expired {
+		res := db.Model(&WorkerLeaseRow{}).
+			Where("worker_id = ? AND expired_at < ?", workerID, now).
+			Updates(map[string]interface{}{"holder": l.holder, "expired_at": now.Add(l.LeaseTTL)})
+		if res.Error != nil {
+			return 0, res.Error
+		}
+		if res.RowsAffected == 1 {
+			l.workerID = workerID
+			return workerID, nil
+		}
+	}
+
+	//【2】插入尚未使用过的机器id
+	var used []int64
+	if err := db.Model(&WorkerLeaseRow{}).Order("worker_id").Pluck("worker_id", &used).Error; err != nil {
+		return 0, err
+	}
+	usedMap := make(map[int64]struct{}, len(used))
+	for _, id := range used {
+		usedMap[id] = struct{}{}
+	}
+	for workerID := int64(0); workerID <= maxWorkerID; workerID++ {
+		if _, ok := usedMap[workerID]; ok {
+			continue
+		}
+		res := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&WorkerLeaseRow{
+			WorkerID:  workerID,
+			Holder:    l.holder,
+			ExpiredAt: now.Add(l.LeaseTTL),
+		})
+		if res.Error != nil {
+			return 0, res.Error
+		}
+		if res.RowsAffected == 1 {
+			l.workerID = workerID
+			return workerID, nil
+		}
+	}
+	return 0, ErrNoWorkerID
+}
+
+// Renew 续期
+func (l *DBLeaser) Renew(ctx context.Context) error {
+	res := l.DB.WithContext(ctx).Model(&WorkerLeaseRow{}).
+		Where("worker_id = ? AND holder = ?", l.workerID, l.holder).
+		Update("expired_at", time.Now().Add(l.LeaseTTL))
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// Release 释放，将过期时间置为当前时间
+func (l *DBLeaser) Release(ctx context.Context) error {
+	return l.DB.WithContext(ctx).Model(&WorkerLeaseRow{}).
+		Where("worker_id = ? AND holder = ?", l.workerID, l.holder).
+		Update("expired_at", time.Now()).Error
+}
+
+// TTL 租约有效期
+func (l *DBLeaser) TTL() time.Duration {
+	return l.LeaseTTL
+}
+
+// -------END------数据库租约----END---------
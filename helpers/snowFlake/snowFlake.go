@@ -2,6 +2,8 @@ package snowFlake
 
 import (
 	"errors"
+	"fmt"
+	"log"
 	"sync"
 	"time"
 )
@@ -14,14 +16,67 @@ const (
 	sequenceMask   = int64(-1 ^ (-1 << sequenceBits)) //
 	workeridShift  = sequenceBits                     //机器id左移位数
 	timestampShift = sequenceBits + workeridBits      //时间戳左移位数
+
+	minTimestampBits = uint(39) //时间戳最少位数，约 17 年
+)
+
+var (
+	// ErrClockRollback 时钟回拨超出容忍范围
+	ErrClockRollback = errors.New("snowFlake: clock moved backwards")
+	// ErrLeaseLost 机器id租约已丢失，继续生成可能与其他实例重复
+	ErrLeaseLost = errors.New("snowFlake: worker id lease lost")
+	// ErrTimestampOverflow 距 Epoch 的时间超出布局的时间戳位数
+	ErrTimestampOverflow = errors.New("snowFlake: timestamp overflows layout")
+)
+
+// RollbackStrategy 时钟回拨处理策略
+type RollbackStrategy int8
+
+const (
+	RollbackWait   RollbackStrategy = 1 // 等待时钟追上（默认）
+	RollbackBorrow RollbackStrategy = 2 // 沿用上次时间戳继续借用序列号，序列耗尽时向后借 1 毫秒
+	RollbackError  RollbackStrategy = 3 // 直接返回 ErrClockRollback
 )
 
+// Layout 位布局，时间戳位数 = 63 - WorkerBits - SequenceBits，至少 39 位
+type Layout struct {
+	Epoch        int64 // 开始时间截（毫秒）
+	WorkerBits   uint  // 机器id所占的位数
+	SequenceBits uint  // 序列所占的位数
+}
+
+// DefaultLayout 默认布局：2017-01-01 起，10 位机器id，12 位序列
+var DefaultLayout = Layout{Epoch: twepoch, WorkerBits: workeridBits, SequenceBits: sequenceBits}
+
+// Config 生成器配置
+type Config struct {
+	Layout      Layout
+	WorkerID    int64
+	Rollback    RollbackStrategy
+	MaxRollback time.Duration // 可容忍的最大回拨时长，超出后返回 ErrClockRollback（默认 5 秒）
+}
+
+// Parts 雪花id拆解结果
+type Parts struct {
+	Time      time.Time `json:"time"`
+	Timestamp int64     `json:"timestamp"` // 毫秒时间戳
+	WorkerID  int64     `json:"worker_id"`
+	Sequence  int64     `json:"sequence"`
+}
+
 // A Snowflake struct holds the basic information needed for a snowflake generator worker
 type Snowflake struct {
 	sync.Mutex
 	timestamp int64
 	workerid  int64
 	sequence  int64
+
+	layout      Layout
+	rollback    RollbackStrategy
+	maxRollback int64 // 毫秒
+
+	lease     *workerLease
+	leaseLost bool
 }
 
 // NewNode returns a new snowflake worker that can be used to generate snowflake IDs
@@ -31,45 +86,180 @@ func NewSnowflake(workerid int64) (*Snowflake, error) {
 		return nil, errors.New("workerid must be between 0 and 1023")
 	}
 
-	return &Snowflake{
-		timestamp: 0,
-		workerid:  workerid,
-		sequence:  0,
-	}, nil
+	return NewSnowflakeWithConfig(&Config{WorkerID: workerid})
 }
 
 // NewNode returns a new snowflake worker that can be used to generate snowflake IDs
 func NewSnowflakeMax() (*Snowflake, error) {
+	return NewSnowflakeWithConfig(&Config{WorkerID: workeridMax})
+}
+
+// NewSnowflakeWithConfig 按自定义布局与回拨策略创建生成器
+func NewSnowflakeWithConfig(config *Config) (*Snowflake, error) {
+	layout := config.Layout
+	if layout == (Layout{}) {
+		layout = DefaultLayout
+	}
+	if err := layout.validate(); err != nil {
+		return nil, err
+	}
+	if config.WorkerID < 0 || config.WorkerID > layout.MaxWorkerID() {
+		return nil, fmt.Errorf("workerid must be between 0 and %d", layout.MaxWorkerID())
+	}
+
+	rollback := config.Rollback
+	if rollback == 0 {
+		rollback = RollbackWait
+	}
+	maxRollback := config.MaxRollback
+	if maxRollback <= 0 {
+		maxRollback = 5 * time.Second
+	}
+
 	return &Snowflake{
-		timestamp: 0,
-		workerid:  workeridMax,
-		sequence:  0,
+		workerid:    config.WorkerID,
+		layout:      layout,
+		rollback:    rollback,
+		maxRollback: maxRollback.Milliseconds(),
 	}, nil
 }
 
 // Generate creates and returns a unique snowflake ID
+// 时钟回拨时等待时钟追上；租约丢失或时间戳溢出时记录日志并返回 0
+//
+// Deprecated: 无法感知错误，请使用 NextID
 func (s *Snowflake) Generate() int64 {
+	for {
+		id, err := s.NextID()
+		if err == nil {
+			return id
+		}
+		if !errors.Is(err, ErrClockRollback) {
+			log.Println("【snowFlake】generate err:", err)
+			return 0
+		}
+		s.Lock()
+		wait := s.timestamp - time.Now().UnixMilli()
+		s.Unlock()
+		time.Sleep(time.Duration(wait) * time.Millisecond)
+	}
+}
 
-	s.Lock()
+// NextID 生成一个id，并返回时钟回拨、租约丢失或时间戳溢出的错误；
+// RollbackWait 的等待在锁外进行，不阻塞其他调用方持锁
+func (s *Snowflake) NextID() (int64, error) {
+	for {
+		s.Lock()
+		id, wait, err := s.next()
+		s.Unlock()
+		if wait <= 0 {
+			return id, err
+		}
+		time.Sleep(wait)
+	}
+}
+
+// next 生成一个id，调用方持有锁；RollbackWait 下回拨时返回需要等待的时长
+func (s *Snowflake) next() (int64, time.Duration, error) {
+	if s.leaseLost {
+		return 0, 0, ErrLeaseLost
+	}
+	if s.lease != nil && !time.Now().Before(s.lease.deadline) {
+		// 长时间未能续期，租约可能已被其他实例接管
+		return 0, 0, fmt.Errorf("%w: not renewed before %s", ErrLeaseLost, s.lease.deadline.Format(time.RFC3339Nano))
+	}
+
+	sequenceMask := s.layout.sequenceMask()
+	now := time.Now().UnixMilli()
 
-	now := time.Now().UnixNano() / 1000000
+	//【1】时钟回拨
+	if now < s.timestamp {
+		rollback := s.timestamp - now
+		if s.rollback == RollbackError || rollback > s.maxRollback {
+			return 0, 0, fmt.Errorf("%w by %dms", ErrClockRollback, rollback)
+		}
+		if s.rollback == RollbackWait {
+			return 0, time.Duration(rollback) * time.Millisecond, nil
+		}
+		now = s.timestamp
+	}
 
+	//【2】同一毫秒内递增序列
 	if s.timestamp == now {
 		s.sequence = (s.sequence + 1) & sequenceMask
 
 		if s.sequence == 0 {
-			for now <= s.timestamp {
-				now = time.Now().UnixNano() / 1000000
+			if s.rollback == RollbackBorrow && time.Now().UnixMilli() <= s.timestamp {
+				// 借用下一毫秒，避免回拨期间阻塞
+				now = s.timestamp + 1
+			} else {
+				for now <= s.timestamp {
+					now = time.Now().UnixMilli()
+				}
 			}
 		}
 	} else {
 		s.sequence = 0
 	}
 
+	if now-s.layout.Epoch > s.layout.maxTimestamp() {
+		return 0, 0, fmt.Errorf("%w: %dms since epoch", ErrTimestampOverflow, now-s.layout.Epoch)
+	}
+
 	s.timestamp = now
 
-	r := int64((now-twepoch)<<timestampShift | (s.workerid << workeridShift) | (s.sequence))
+	return s.layout.compose(now, s.workerid, s.sequence), 0, nil
+}
+
+// WorkerID 当前机器id
+func (s *Snowflake) WorkerID() int64 {
+	return s.workerid
+}
+
+// Decompose 按当前生成器的布局拆解id
+func (s *Snowflake) Decompose(id int64) Parts {
+	return s.layout.Decompose(id)
+}
 
-	s.Unlock()
-	return r
+// Decompose 按默认布局拆解id
+func Decompose(id int64) Parts {
+	return DefaultLayout.Decompose(id)
+}
+
+// Decompose 按布局拆解id
+func (l Layout) Decompose(id int64) Parts {
+	timestamp := (id >> (l.WorkerBits + l.SequenceBits)) + l.Epoch
+	return Parts{
+		Time:      time.UnixMilli(timestamp),
+		Timestamp: timestamp,
+		WorkerID:  (id >> l.SequenceBits) & l.MaxWorkerID(),
+		Sequence:  id & l.sequenceMask(),
+	}
+}
+
+// MaxWorkerID 布局支持的最大机器id
+func (l Layout) MaxWorkerID() int64 {
+	return int64(-1 ^ (-1 << l.WorkerBits))
+}
+
+func (l Layout) sequenceMask() int64 {
+	return int64(-1 ^ (-1 << l.SequenceBits))
+}
+
+func (l Layout) maxTimestamp() int64 {
+	return int64(-1 ^ (-1 << (63 - l.WorkerBits - l.SequenceBits)))
+}
+
+func (l Layout) compose(timestamp, workerid, sequence int64) int64 {
+	return (timestamp-l.Epoch)<<(l.WorkerBits+l.SequenceBits) | (workerid << l.SequenceBits) | sequence
+}
+
+func (l Layout) validate() error {
+	if l.WorkerBits == 0 || l.SequenceBits == 0 || l.WorkerBits+l.SequenceBits > 63-minTimestampBits {
+		return fmt.Errorf("snowFlake: invalid layout bits, timestamp needs at least %d bits", minTimestampBits)
+	}
+	if l.Epoch < 0 || l.Epoch > time.Now().UnixMilli() {
+		return errors.New("snowFlake: epoch must be in the past")
+	}
+	return nil
 }
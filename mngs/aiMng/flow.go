@@ -16,10 +16,11 @@ import (
 
 // aiSearch 调用模型执行一次简单会话。
 func (m *Manager) aiSearch(ctx *gin.Context, prompt string, content string, returnTokenUsage bool) (replyContent string, err error) {
-	if err = m.acquireLock(true); err != nil {
+	lock, err := m.acquireLock(ctx, true)
+	if err != nil {
 		return "", err
 	}
-	defer m.releaseLock()
+	defer m.releaseLock(lock)
 
 	resp, err := m.aiMng.CreateChatCompletionRequestSimple(ctx, volcengineMng.Doubao, volcengineMng.Disabled, []*volcengineMng.ChatParam{
 		{
@@ -52,10 +53,11 @@ func (m *Manager) aiSearch(ctx *gin.Context, prompt string, content string, retu
 func (m *Manager) searchWithProductPrice(ctx context.Context, sessionID string, prompt string, content string, messageTime *time.Time, returnTokenUsage bool) (messages []msgSend.MessagePayload, err error) {
 
 	//【1】锁
-	if err = m.acquireLock(true); err != nil {
+	lock, err := m.acquireLock(ctx, true)
+	if err != nil {
 		return nil, err
 	}
-	defer m.releaseLock()
+	defer m.releaseLock(lock)
 
 	//【2】读取会话历史
 	conversation, historyErr := m.loadSessionConversation(ctx, sessionID)
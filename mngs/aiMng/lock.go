@@ -1,55 +1,59 @@
 package aiMng
 
 import (
-	"sync"
+	"context"
+	"errors"
 	"sync/atomic"
 	"time"
+
+	"github.com/wiidz/goutil/mngs/redisMng"
 )
 
-// 全局互斥锁和执行状态控制
+// aiServiceLockKey 是 AI 服务执行锁在 Redis 中的键，多副本共享同一把锁。
+const aiServiceLockKey = "chat:lock:ai_service"
+
+// 执行状态统计（仅记录本实例）
 var (
-	aiServiceMutex sync.Mutex
 	executionCount int64
 	isExecuting    int32
 	lastExecTime   time.Time
 )
 
-// acquireLock 是一个带超时控制的执行锁获取函数。
-func (m *Manager) acquireLock(skipIntervalCheck bool) error {
-	if atomic.LoadInt32(&isExecuting) == 1 {
-		return ErrServiceBusy
-	}
-
+// acquireLock 是一个带超时控制的执行锁获取函数，基于 Redis 分布式锁实现跨副本互斥。
+func (m *Manager) acquireLock(ctx context.Context, skipIntervalCheck bool) (*redisMng.Lock, error) {
 	if !skipIntervalCheck && time.Since(lastExecTime) < m.config.MinInterval {
-		return ErrServiceBusy
+		return nil, ErrServiceBusy
 	}
 
-	lockAcquired := make(chan bool, 1)
-	go func() {
-		aiServiceMutex.Lock()
-		lockAcquired <- true
-	}()
-
-	select {
-	case <-lockAcquired:
-		if atomic.LoadInt32(&isExecuting) == 1 {
-			aiServiceMutex.Unlock()
-			return ErrServiceBusy
-		}
+	lockCtx, cancel := context.WithTimeout(ctx, m.config.MaxExecutionTime)
+	defer cancel()
 
-		atomic.StoreInt32(&isExecuting, 1)
-		atomic.AddInt64(&executionCount, 1)
-		lastExecTime = time.Now()
-		return nil
-	case <-time.After(m.config.MaxExecutionTime):
-		return ErrTimeout
+	lock, err := m.redis.TryLock(lockCtx, aiServiceLockKey, m.config.MaxExecutionTime, redisMng.WithLockWatchdog(0))
+	if errors.Is(err, redisMng.ErrLockNotObtained) {
+		return nil, ErrServiceBusy
 	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return nil, ErrTimeout
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	atomic.StoreInt32(&isExecuting, 1)
+	atomic.AddInt64(&executionCount, 1)
+	lastExecTime = time.Now()
+	return lock, nil
 }
 
 // releaseLock 是对应的执行锁释放函数。
-func (m *Manager) releaseLock() {
+func (m *Manager) releaseLock(lock *redisMng.Lock) {
 	atomic.StoreInt32(&isExecuting, 0)
-	aiServiceMutex.Unlock()
+	if lock == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	_ = lock.Unlock(ctx)
 }
 
 // GetExecutionStatus 是一个状态查询函数，返回执行锁的状态数据。
@@ -61,4 +65,3 @@ func GetExecutionStatus() map[string]interface{} {
 		"time_since_last": time.Since(lastExecTime),
 	}
 }
-
@@ -1,15 +1,18 @@
 package amqpMng
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/streadway/amqp"
 	"github.com/wiidz/goutil/structs/configStruct"
 	"log"
 	"strconv"
+	"sync"
+	"time"
 )
 
-var conn *amqp.Connection // 全局 Connection
+var conn *Connection // 全局 Connection（断线自动重连）
 
 // Init 初始化 Connection
 func Init(config *configStruct.RabbitMQConfig) (err error) {
@@ -17,9 +20,9 @@ func Init(config *configStruct.RabbitMQConfig) (err error) {
 	//【1】构建DSN
 	dsn := "amqp://" + config.Username + ":" + config.Password + "@" + config.Host + "/"
 
-	//【2】构建DB对象
+	//【2】建立连接，之后的断线由 Connection 自动重连
 	log.Println("【rabbitMq-dsn】", dsn)
-	conn, err = amqp.Dial(dsn)
+	conn, err = Dial(dsn, nil)
 	if err != nil {
 		log.Println("【rabbit-mq-init-err】", err)
 		return
@@ -27,17 +30,36 @@ func Init(config *configStruct.RabbitMQConfig) (err error) {
 	return
 }
 
-// NewRabbitMQ 新建管理对象
+// GetConnection 获取 Init 建立的全局连接
+func GetConnection() *Connection {
+	return conn
+}
+
+// NewRabbitMQ 新建管理对象（使用 Init 建立的全局连接）
 func NewRabbitMQ(cfg *Config) (*RabbitMQ, error) {
 	if conn == nil {
 		return nil, errors.New("[RabbitMQ] conn not initialized, call Init first")
 	}
+	return NewRabbitMQWithConn(cfg, conn)
+}
+
+// NewRabbitMQWithConn 使用指定连接新建管理对象
+func NewRabbitMQWithConn(cfg *Config, c *Connection) (*RabbitMQ, error) {
+	if c == nil {
+		return nil, errors.New("[RabbitMQ] conn is nil")
+	}
 	return &RabbitMQ{
 		Config: cfg,
-		Conn:   conn,
+		Conn:   c.Raw(),
+		conn:   c,
 	}, nil
 }
 
+// Connection 底层自愈连接
+func (mng *RabbitMQ) Connection() *Connection {
+	return mng.conn
+}
+
 // SetExchange 声明
 func (mng *RabbitMQ) SetExchange(channel *amqp.Channel) error {
 	args := mng.Config.ExchangeDeclareArgs
@@ -109,35 +131,50 @@ func (mng *RabbitMQ) BindQueue(channel *amqp.Channel) error {
 	)
 }
 
-// Publish 发布消息（参数 expiration 单位毫秒。reliable 表示用 Publisher Confirm）
-func (mng *RabbitMQ) Publish(body string, expiration int, reliable bool) error {
-	channel, err := mng.Conn.Channel()
-	if err != nil {
-		return fmt.Errorf("SetChannel: %w", err)
-	}
-	defer channel.Close()
-
-	// 声明 Exchange
-	if err := mng.SetExchange(channel); err != nil {
-		return fmt.Errorf("SetExchange: %w", err)
+// declareAll 声明 exchange、queue 并绑定；ExchangeName 为空时只声明队列（默认交换机按队列名投递）
+func (mng *RabbitMQ) declareAll(channel *amqp.Channel) error {
+	if mng.Config.ExchangeName != "" {
+		if err := mng.SetExchange(channel); err != nil {
+			return fmt.Errorf("SetExchange: %w", err)
+		}
 	}
-	// 声明 Queue （可选，通常生产前先确保队列存在）
 	if _, err := mng.DeclareQueue(channel); err != nil {
 		return fmt.Errorf("DeclareQueue: %w", err)
 	}
-	// 绑定
-	if err := mng.BindQueue(channel); err != nil {
-		return fmt.Errorf("BindQueue: %w", err)
+	if mng.Config.ExchangeName != "" {
+		if err := mng.BindQueue(channel); err != nil {
+			return fmt.Errorf("BindQueue: %w", err)
+		}
 	}
+	return nil
+}
 
-	if reliable {
-		if err := channel.Confirm(false); err != nil {
-			return fmt.Errorf("channel confirm mode: %w", err)
-		}
-		confirms := channel.NotifyPublish(make(chan amqp.Confirmation, 1))
-		defer confirmOne(confirms)
+// topologyKey 同一拓扑在连接上只登记一次
+func (mng *RabbitMQ) topologyKey() string {
+	return mng.Config.ExchangeName + "|" + mng.Config.QueueName + "|" + mng.Config.BindingKey
+}
+
+// ensureTopology 首次使用时声明拓扑并登记到连接，重连后由连接自动重新声明
+func (mng *RabbitMQ) ensureTopology(ctx context.Context) error {
+	mng.topoMu.Lock()
+	defer mng.topoMu.Unlock()
+	if mng.declared {
+		return nil
+	}
+	if err := mng.conn.Declare(ctx, mng.topologyKey(), mng.declareAll); err != nil {
+		return err
 	}
+	mng.declared = true
+	return nil
+}
 
+// Publish 发布消息（参数 expiration 单位毫秒。reliable 表示用 Publisher Confirm）
+func (mng *RabbitMQ) Publish(body string, expiration int, reliable bool) error {
+	return mng.PublishWithContext(context.Background(), body, expiration, reliable)
+}
+
+// PublishWithContext 发布消息，断线期间等待重连直到 ctx 结束；reliable 时 broker nack 返回错误
+func (mng *RabbitMQ) PublishWithContext(ctx context.Context, body string, expiration int, reliable bool) error {
 	var pub amqp.Publishing
 	bodyBytes := []byte(body)
 	switch mng.Config.ExchangeType {
@@ -159,39 +196,104 @@ func (mng *RabbitMQ) Publish(body string, expiration int, reliable bool) error {
 		}
 	}
 
-	return channel.Publish(
-		mng.Config.ExchangeName,
-		mng.Config.RoutingKey,
-		false,
-		false,
-		pub,
-	)
+	return mng.PublishMessage(ctx, pub, reliable)
 }
 
-func confirmOne(confirms <-chan amqp.Confirmation) {
-	if confirmed := <-confirms; confirmed.Ack {
-		log.Printf("[RabbitMQ] confirmed delivery: %d", confirmed.DeliveryTag)
-	} else {
-		log.Printf("[RabbitMQ] failed delivery: %d", confirmed.DeliveryTag)
+// PublishMessage 发布完整的消息（可带 MessageId、Headers 等属性），投递到 Config 中的 exchange / routing key
+func (mng *RabbitMQ) PublishMessage(ctx context.Context, pub amqp.Publishing, reliable bool) error {
+	if err := mng.ensureTopology(ctx); err != nil {
+		return err
+	}
+	if !reliable {
+		return mng.conn.withChannel(ctx, func(channel *amqp.Channel) error {
+			return channel.Publish(mng.Config.ExchangeName, mng.Config.RoutingKey, false, false, pub)
+		})
+	}
+	return mng.conn.withConfirmChannel(ctx, func(pc *pooledChannel) error {
+		if err := pc.ch.Publish(mng.Config.ExchangeName, mng.Config.RoutingKey, false, false, pub); err != nil {
+			return err
+		}
+		return confirmOne(ctx, pc.confirms)
+	})
+}
+
+// confirmOne 等待一条确认；返回错误时调用方会丢弃该 channel，避免迟到的确认串到下一条消息
+func confirmOne(ctx context.Context, confirms <-chan amqp.Confirmation) error {
+	select {
+	case confirmed, ok := <-confirms:
+		if !ok {
+			return errors.New("[RabbitMQ] channel closed before confirm")
+		}
+		if !confirmed.Ack {
+			return fmt.Errorf("[RabbitMQ] failed delivery: %d", confirmed.DeliveryTag)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
 
-// Consume 消费队列（handleFunc 业务处理，每次消费一个消息）
+// Consume 消费队列（handleFunc 业务处理，每次消费一个消息），断线重连后自动恢复
 func (mng *RabbitMQ) Consume(consumerTag string, handleFunc func(d amqp.Delivery) error) error {
-	channel, err := mng.Conn.Channel()
+	return mng.ConsumeContext(context.Background(), consumerTag, handleFunc)
+}
+
+// ConsumeContext 消费队列直到 ctx 结束（返回 nil）或连接被 Close（返回 ErrClosed）；
+// 首次订阅失败直接返回错误，之后的断线会等待重连并重新订阅
+func (mng *RabbitMQ) ConsumeContext(ctx context.Context, consumerTag string, handleFunc func(d amqp.Delivery) error) error {
+	return mng.ConsumeWithOptions(ctx, consumerTag, nil, handleFunc)
+}
+
+// ConsumeWithOptions 按 opts 消费（并发、QoS、重试与死信，见 ConsumeOptions）；opts 为 nil 时与 ConsumeContext 相同
+func (mng *RabbitMQ) ConsumeWithOptions(ctx context.Context, consumerTag string, opts *ConsumeOptions, handleFunc func(d amqp.Delivery) error) error {
+	if err := mng.ensureTopology(ctx); err != nil {
+		return err
+	}
+	c, err := mng.newConsumer(ctx, opts, handleFunc)
 	if err != nil {
-		return fmt.Errorf("SetChannel: %w", err)
+		return err
 	}
-	defer channel.Close()
+	return mng.consumeLoop(ctx, consumerTag, c)
+}
 
-	if err := mng.SetExchange(channel); err != nil {
-		return fmt.Errorf("SetExchange: %w", err)
+// consumeLoop 消费并在断线后恢复；首次订阅失败直接返回
+func (mng *RabbitMQ) consumeLoop(ctx context.Context, consumerTag string, c *consumer) error {
+	first := true
+	for {
+		err := mng.consumeOnce(ctx, consumerTag, c)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if errors.Is(err, ErrClosed) || (first && err != nil) {
+			return err
+		}
+		first = false
+		log.Printf("[RabbitMQ][Consume] %s interrupted: %v, resuming", consumerTag, err)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-mng.conn.Closed():
+			return ErrClosed
+		case <-time.After(mng.conn.cfg.MinBackoff):
+		}
 	}
-	if _, err := mng.DeclareQueue(channel); err != nil {
-		return fmt.Errorf("DeclareQueue: %w", err)
+}
+
+// consumeOnce 在一个 channel 上消费，channel 或连接断开时返回
+func (mng *RabbitMQ) consumeOnce(ctx context.Context, consumerTag string, c *consumer) error {
+	channel, err := mng.conn.Channel(ctx)
+	if err != nil {
+		return err
+	}
+	defer channel.Close()
+
+	if err := mng.declareAll(channel); err != nil {
+		return err
 	}
-	if err := mng.BindQueue(channel); err != nil {
-		return fmt.Errorf("BindQueue: %w", err)
+	if c.opts.Prefetch > 0 {
+		if err := channel.Qos(c.opts.Prefetch, 0, false); err != nil {
+			return fmt.Errorf("Qos: %w", err)
+		}
 	}
 
 	deliveries, err := channel.Consume(
@@ -207,14 +309,32 @@ func (mng *RabbitMQ) Consume(consumerTag string, handleFunc func(d amqp.Delivery
 		return fmt.Errorf("Consume: %w", err)
 	}
 
-	// 启动消费循环
-	for d := range deliveries {
-		if err := handleFunc(d); err == nil {
-			_ = d.Ack(false)
-		} else {
-			_ = d.Nack(false, true)
-			log.Printf("[RabbitMQ][Consume] handleFunc error: %v", err)
-		}
+	// 启动消费循环，Workers 个协程共享同一 channel 的投递
+	errCh := make(chan error, c.opts.Workers)
+	var wg sync.WaitGroup
+	for i := 0; i < c.opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case d, ok := <-deliveries:
+					if !ok {
+						errCh <- errors.New("deliveries closed")
+						return
+					}
+					c.handle(ctx, d)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	select {
+	case err = <-errCh:
+		return err
+	default:
+		return nil
 	}
-	return nil
 }
@@ -0,0 +1,297 @@
+package amqpMng
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+	"github.com/wiidz/goutil/mngs/busMng"
+	"github.com/wiidz/goutil/mngs/envelopeMng"
+)
+
+// directReplyTo RabbitMQ 的 direct reply-to 伪队列
+const directReplyTo = "amq.rabbitmq.reply-to"
+
+// Bus busMng.Bus 的 RabbitMQ 实现：交换机持久化，发布均使用 publisher confirm，
+// 拓扑登记在 Connection 上，重连后重新声明，订阅自动恢复。
+// 延迟消息使用 TTL 队列（每个交换机、每个延迟一个 bus.delay.<exchange>.<ms>），不依赖延迟插件
+type Bus struct {
+	conn *Connection
+
+	mu     sync.Mutex
+	subs   map[*busSubscription]struct{}
+	delays sync.Map // 已声明的延迟队列
+}
+
+var _ busMng.Bus = (*Bus)(nil)
+
+// NewBus 在连接上创建总线，Close 不会关闭连接
+func NewBus(c *Connection) *Bus {
+	return &Bus{conn: c, subs: map[*busSubscription]struct{}{}}
+}
+
+// DeclareExchange busMng.Bus
+func (b *Bus) DeclareExchange(ctx context.Context, name string, kind busMng.ExchangeKind) error {
+	if name == busMng.DefaultExchange {
+		return errors.New("[RabbitMQ] cannot declare the default exchange")
+	}
+	return b.conn.Declare(ctx, "exchange|"+name, func(ch *amqp.Channel) error {
+		return ch.ExchangeDeclare(name, string(kind), true, false, false, false, nil)
+	})
+}
+
+// Publish busMng.Bus
+func (b *Bus) Publish(ctx context.Context, exchange, routingKey string, msg *busMng.Message) error {
+	busMng.Prepare(msg)
+	return b.publish(ctx, exchange, routingKey, msg.Publishing())
+}
+
+func (b *Bus) publish(ctx context.Context, exchange, routingKey string, pub amqp.Publishing) error {
+	return b.conn.withConfirmChannel(ctx, func(pc *pooledChannel) error {
+		if err := pc.ch.Publish(exchange, routingKey, false, false, pub); err != nil {
+			return err
+		}
+		return confirmOne(ctx, pc.confirms)
+	})
+}
+
+// PublishDelayed busMng.Bus；消息先进入 TTL 队列，到期后带原 routing key 死信回目标交换机
+func (b *Bus) PublishDelayed(ctx context.Context, exchange, routingKey string, msg *busMng.Message, delay time.Duration) error {
+	if delay <= 0 {
+		return b.Publish(ctx, exchange, routingKey, msg)
+	}
+	busMng.Prepare(msg)
+	name, err := b.delayQueue(ctx, exchange, delay)
+	if err != nil {
+		return err
+	}
+	return b.publish(ctx, name, routingKey, msg.Publishing())
+}
+
+// delayQueue 声明 fanout 交换机与同名 TTL 队列，返回交换机名
+func (b *Bus) delayQueue(ctx context.Context, exchange string, delay time.Duration) (string, error) {
+	target := exchange
+	if target == busMng.DefaultExchange {
+		target = "default"
+	}
+	ms := delay.Milliseconds()
+	name := "bus.delay." + target + "." + strconv.FormatInt(ms, 10)
+	if _, ok := b.delays.Load(name); ok {
+		return name, nil
+	}
+	err := b.conn.Declare(ctx, "delay|"+name, func(ch *amqp.Channel) error {
+		if err := ch.ExchangeDeclare(name, string(Fanout), true, false, false, false, nil); err != nil {
+			return err
+		}
+		args := amqp.Table{
+			"x-message-ttl":          ms,
+			"x-dead-letter-exchange": exchange, // 不指定 routing key，沿用发布时的 routing key
+		}
+		if _, err := ch.QueueDeclare(name, true, false, false, false, args); err != nil {
+			return err
+		}
+		return ch.QueueBind(name, "", name, false, nil)
+	})
+	if err != nil {
+		return "", err
+	}
+	b.delays.Store(name, struct{}{})
+	return name, nil
+}
+
+// -------BEGIN------subscribe-----BEGIN--------
+
+type busSubscription struct {
+	bus      *Bus
+	mq       *RabbitMQ
+	consumer *consumer
+	queue    string
+	temp     bool
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	once   sync.Once
+}
+
+// Queue busMng.Subscription
+func (s *busSubscription) Queue() string {
+	return s.queue
+}
+
+// Close busMng.Subscription；临时队列及其死信 / 重试队列随之删除
+func (s *busSubscription) Close() error {
+	var err error
+	s.once.Do(func() {
+		s.cancel()
+		<-s.done
+		s.bus.mu.Lock()
+		delete(s.bus.subs, s)
+		s.bus.mu.Unlock()
+
+		conn := s.bus.conn
+		conn.Undeclare("sub|" + s.queue)
+		if !s.temp {
+			return
+		}
+		conn.Undeclare("retry|" + s.queue)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		var ch *amqp.Channel
+		if ch, err = conn.Channel(ctx); err != nil {
+			return
+		}
+		defer ch.Close()
+		if _, err = ch.QueueDelete(s.queue, false, false, false); err != nil {
+			return
+		}
+		err = s.consumer.undeclare(ch)
+	})
+	return err
+}
+
+// Subscribe busMng.Bus
+func (b *Bus) Subscribe(ctx context.Context, binding busMng.Binding, handler busMng.Handler) (busMng.Subscription, error) {
+	kind := binding.ExchangeKind
+	if kind == "" {
+		kind = busMng.Direct
+	}
+	sub := &busSubscription{bus: b, queue: binding.Queue, done: make(chan struct{})}
+	args := amqp.Table{}
+	if sub.queue == "" {
+		// 临时队列：非持久，连接异常退出未删除时闲置一分钟后由 broker 删除
+		sub.queue, sub.temp = "bus.gen-"+uuid.NewString(), true
+		args["x-expires"] = int32(time.Minute / time.Millisecond)
+	}
+	keys := binding.Keys
+	if len(keys) == 0 {
+		keys = []string{""}
+	}
+
+	// 交换机由总线声明（持久化），RabbitMQ 只负责队列与消费
+	mq, err := NewRabbitMQWithConn(&Config{
+		IsDurable:        binding.Durable && !sub.temp,
+		QueueName:        sub.queue,
+		QueueDeclareArgs: args,
+	}, b.conn)
+	if err != nil {
+		return nil, err
+	}
+	sub.mq = mq
+	err = b.conn.Declare(ctx, "sub|"+sub.queue, func(ch *amqp.Channel) error {
+		if binding.Exchange != busMng.DefaultExchange {
+			if err := ch.ExchangeDeclare(binding.Exchange, string(kind), true, false, false, false, nil); err != nil {
+				return err
+			}
+		}
+		if _, err := mq.DeclareQueue(ch); err != nil {
+			return err
+		}
+		if binding.Exchange == busMng.DefaultExchange {
+			return nil
+		}
+		for _, key := range keys {
+			if err := ch.QueueBind(sub.queue, key, binding.Exchange, false, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	subCtx, cancel := context.WithCancel(ctx)
+	sub.cancel = cancel
+	c, err := mq.newConsumer(subCtx, &ConsumeOptions{
+		Prefetch:    binding.Prefetch,
+		Workers:     binding.Workers,
+		MaxAttempts: binding.MaxAttempts,
+		RetryDelays: binding.RetryDelays,
+	}, func(d amqp.Delivery) error {
+		env := envelopeMng.FromDelivery(d)
+		return handler(env.Context(subCtx), env)
+	})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	sub.consumer = c
+
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+	go func() {
+		defer close(sub.done)
+		if err := mq.consumeLoop(subCtx, "", c); err != nil {
+			log.Printf("[RabbitMQ][Bus] subscription %s stopped: %v", sub.queue, err)
+		}
+	}()
+	return sub, nil
+}
+
+// -------END------subscribe----END---------
+
+// Request busMng.Bus，使用 direct reply-to，无需声明回复队列
+func (b *Bus) Request(ctx context.Context, exchange, routingKey string, msg *busMng.Message) (*busMng.Message, error) {
+	ch, err := b.conn.Channel(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer ch.Close()
+
+	// direct reply-to 要求先在同一 channel 上以 autoAck 消费，再发布请求
+	replies, err := ch.Consume(directReplyTo, "", true, false, false, false, nil)
+	if err != nil {
+		return nil, err
+	}
+	busMng.Prepare(msg)
+	msg.ReplyTo = directReplyTo
+	if err = ch.Publish(exchange, routingKey, false, false, msg.Publishing()); err != nil {
+		return nil, err
+	}
+	for {
+		select {
+		case d, ok := <-replies:
+			if !ok {
+				return nil, errors.New("[RabbitMQ] channel closed before reply")
+			}
+			if d.CorrelationId == msg.ID {
+				return envelopeMng.FromDelivery(d), nil
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Reply busMng.Bus
+func (b *Bus) Reply(ctx context.Context, request *busMng.Message, reply *busMng.Message) error {
+	if request.ReplyTo == "" {
+		return errors.New("[RabbitMQ] request has no reply-to")
+	}
+	busMng.PrepareReply(request, reply)
+	return b.publish(ctx, busMng.DefaultExchange, request.ReplyTo, reply.Publishing())
+}
+
+// Close busMng.Bus，关闭所有订阅，不关闭连接
+func (b *Bus) Close() error {
+	b.mu.Lock()
+	subs := make([]*busSubscription, 0, len(b.subs))
+	for sub := range b.subs {
+		subs = append(subs, sub)
+	}
+	b.mu.Unlock()
+
+	var errs []error
+	for _, sub := range subs {
+		if err := sub.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,334 @@
+package amqpMng
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// ErrClosed 连接已被主动关闭
+var ErrClosed = errors.New("[RabbitMQ] connection closed")
+
+// ConnConfig 自愈连接配置，零值字段使用默认值
+type ConnConfig struct {
+	MinBackoff time.Duration // 首次重连等待，默认 500ms，之后翻倍
+	MaxBackoff time.Duration // 重连等待上限，默认 30s
+	PoolSize   int           // 发布用 channel 池大小（普通与 confirm 各一个池），默认 8
+}
+
+// Connection 自愈连接：监听 NotifyClose，断线后指数退避重连，
+// 重连后重新声明已登记的拓扑（exchange / queue / binding），消费者自动恢复
+type Connection struct {
+	dsn string
+	cfg ConnConfig
+
+	mu    sync.RWMutex
+	conn  *amqp.Connection
+	ready chan struct{} // 已连接时为关闭状态，断线时替换为新的未关闭通道
+
+	plainPool   chan *pooledChannel
+	confirmPool chan *pooledChannel
+
+	topoMu     sync.Mutex
+	topologies map[string]func(ch *amqp.Channel) error
+	topoOrder  []string // 按登记顺序重新声明，保证队列先于依赖它的绑定
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// Dial 建立连接，首次连接失败直接返回错误，之后的断线由后台自动重连
+func Dial(dsn string, cfg *ConnConfig) (*Connection, error) {
+	c := &Connection{
+		dsn:        dsn,
+		ready:      make(chan struct{}),
+		topologies: map[string]func(ch *amqp.Channel) error{},
+		closed:     make(chan struct{}),
+	}
+	if cfg != nil {
+		c.cfg = *cfg
+	}
+	if c.cfg.MinBackoff <= 0 {
+		c.cfg.MinBackoff = 500 * time.Millisecond
+	}
+	if c.cfg.MaxBackoff <= 0 {
+		c.cfg.MaxBackoff = 30 * time.Second
+	}
+	if c.cfg.PoolSize <= 0 {
+		c.cfg.PoolSize = 8
+	}
+	c.plainPool = make(chan *pooledChannel, c.cfg.PoolSize)
+	c.confirmPool = make(chan *pooledChannel, c.cfg.PoolSize)
+
+	raw, err := amqp.Dial(dsn)
+	if err != nil {
+		return nil, err
+	}
+	c.connected(raw)
+	return c, nil
+}
+
+// connected 切换到新连接并启动断线监听
+func (c *Connection) connected(raw *amqp.Connection) {
+	c.mu.Lock()
+	c.conn = raw
+	close(c.ready)
+	c.mu.Unlock()
+	go c.watch(raw)
+}
+
+// watch 等待连接断开并重连
+func (c *Connection) watch(raw *amqp.Connection) {
+	reason, ok := <-raw.NotifyClose(make(chan *amqp.Error, 1))
+	select {
+	case <-c.closed:
+		return
+	default:
+	}
+	log.Printf("[RabbitMQ] connection lost: %v (graceful=%v), reconnecting", reason, !ok)
+
+	c.mu.Lock()
+	c.ready = make(chan struct{})
+	c.mu.Unlock()
+	c.drainPools()
+
+	backoff := c.cfg.MinBackoff
+	for {
+		select {
+		case <-c.closed:
+			return
+		case <-time.After(backoff):
+		}
+		next, err := amqp.Dial(c.dsn)
+		if err == nil {
+			select {
+			case <-c.closed:
+				_ = next.Close()
+				return
+			default:
+			}
+			if err = c.redeclare(next); err == nil {
+				log.Println("[RabbitMQ] reconnected")
+				c.connected(next)
+				return
+			}
+			_ = next.Close()
+		}
+		log.Printf("[RabbitMQ] reconnect failed: %v, retry in %v", err, backoff)
+		if backoff *= 2; backoff > c.cfg.MaxBackoff {
+			backoff = c.cfg.MaxBackoff
+		}
+	}
+}
+
+// Raw 当前底层连接，重连后会变化，不要长期持有
+func (c *Connection) Raw() *amqp.Connection {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.conn
+}
+
+// wait 等待连接可用
+func (c *Connection) wait(ctx context.Context) (*amqp.Connection, error) {
+	for {
+		c.mu.RLock()
+		ready, raw := c.ready, c.conn
+		c.mu.RUnlock()
+		select {
+		case <-c.closed:
+			return nil, ErrClosed
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ready:
+			if !raw.IsClosed() {
+				return raw, nil
+			}
+			// 已断开但 watch 尚未切换状态，稍后重试
+			select {
+			case <-time.After(50 * time.Millisecond):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+	}
+}
+
+// Channel 打开一个新 channel，断线期间会等待重连；调用方负责关闭
+func (c *Connection) Channel(ctx context.Context) (*amqp.Channel, error) {
+	raw, err := c.wait(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return raw.Channel()
+}
+
+// Declare 立即执行一次拓扑声明，成功后登记，之后每次重连都会重新执行；key 相同的声明只保留最后一个
+func (c *Connection) Declare(ctx context.Context, key string, fn func(ch *amqp.Channel) error) error {
+	ch, err := c.Channel(ctx)
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+	if err = fn(ch); err != nil {
+		return err
+	}
+
+	c.topoMu.Lock()
+	if _, ok := c.topologies[key]; !ok {
+		c.topoOrder = append(c.topoOrder, key)
+	}
+	c.topologies[key] = fn
+	c.topoMu.Unlock()
+	return nil
+}
+
+// Undeclare 取消登记，重连后不再声明（已声明的资源不会被删除）
+func (c *Connection) Undeclare(key string) {
+	c.topoMu.Lock()
+	defer c.topoMu.Unlock()
+	if _, ok := c.topologies[key]; !ok {
+		return
+	}
+	delete(c.topologies, key)
+	for i, k := range c.topoOrder {
+		if k == key {
+			c.topoOrder = append(c.topoOrder[:i:i], c.topoOrder[i+1:]...)
+			break
+		}
+	}
+}
+
+// redeclare 在新连接上重新声明全部拓扑；单个声明失败只记录日志（声明失败会关闭 channel，不影响连接）
+func (c *Connection) redeclare(raw *amqp.Connection) error {
+	c.topoMu.Lock()
+	defer c.topoMu.Unlock()
+	for _, key := range c.topoOrder {
+		fn := c.topologies[key]
+		ch, err := raw.Channel()
+		if err != nil {
+			return err
+		}
+		if err = fn(ch); err != nil {
+			log.Printf("[RabbitMQ] redeclare %s: %v", key, err)
+		}
+		_ = ch.Close()
+	}
+	return nil
+}
+
+// -------BEGIN------channel pool-----BEGIN--------
+
+// pooledChannel 池中的 channel；closed 用于在借出前剔除已被服务端关闭的 channel
+type pooledChannel struct {
+	ch       *amqp.Channel
+	closed   chan *amqp.Error
+	confirms chan amqp.Confirmation // 仅 confirm 模式
+}
+
+func (pc *pooledChannel) broken() bool {
+	select {
+	case <-pc.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// acquire 从池中借出可用 channel，池空时新建
+func (c *Connection) acquire(ctx context.Context, pool chan *pooledChannel, confirm bool) (*pooledChannel, error) {
+	for len(pool) > 0 {
+		select {
+		case pc := <-pool:
+			if !pc.broken() {
+				return pc, nil
+			}
+		default:
+		}
+	}
+	ch, err := c.Channel(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pc := &pooledChannel{ch: ch, closed: ch.NotifyClose(make(chan *amqp.Error, 1))}
+	if confirm {
+		if err = ch.Confirm(false); err != nil {
+			_ = ch.Close()
+			return nil, err
+		}
+		pc.confirms = ch.NotifyPublish(make(chan amqp.Confirmation, 1))
+	}
+	return pc, nil
+}
+
+// release 归还 channel；fn 出错时 channel 状态不可信，直接关闭
+func (c *Connection) release(pool chan *pooledChannel, pc *pooledChannel, err error) {
+	if err != nil || pc.broken() {
+		_ = pc.ch.Close()
+		return
+	}
+	select {
+	case pool <- pc:
+	default:
+		_ = pc.ch.Close()
+	}
+}
+
+// withChannel 借用发布 channel
+func (c *Connection) withChannel(ctx context.Context, fn func(ch *amqp.Channel) error) error {
+	pc, err := c.acquire(ctx, c.plainPool, false)
+	if err != nil {
+		return err
+	}
+	err = fn(pc.ch)
+	c.release(c.plainPool, pc, err)
+	return err
+}
+
+// withConfirmChannel 借用 confirm 模式的 channel
+func (c *Connection) withConfirmChannel(ctx context.Context, fn func(pc *pooledChannel) error) error {
+	pc, err := c.acquire(ctx, c.confirmPool, true)
+	if err != nil {
+		return err
+	}
+	err = fn(pc)
+	c.release(c.confirmPool, pc, err)
+	return err
+}
+
+// drainPools 断线后池中的 channel 已失效
+func (c *Connection) drainPools() {
+	for {
+		select {
+		case pc := <-c.plainPool:
+			_ = pc.ch.Close()
+		case pc := <-c.confirmPool:
+			_ = pc.ch.Close()
+		default:
+			return
+		}
+	}
+}
+
+// -------END------channel pool----END---------
+
+// Closed 连接被 Close 后关闭
+func (c *Connection) Closed() <-chan struct{} {
+	return c.closed
+}
+
+// Close 关闭连接并停止重连
+func (c *Connection) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		c.drainPools()
+		if raw := c.Raw(); raw != nil && !raw.IsClosed() {
+			err = raw.Close()
+		}
+	})
+	return err
+}
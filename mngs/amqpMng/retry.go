@@ -0,0 +1,320 @@
+package amqpMng
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/streadway/amqp"
+	"github.com/wiidz/goutil/mngs/envelopeMng"
+)
+
+// 重试 / 死信消息头
+const (
+	HeaderAttempts           = "x-attempts"             // 已失败次数
+	HeaderLastError          = "x-last-error"           // 最后一次错误
+	HeaderOriginalExchange   = "x-original-exchange"    // 首次投递的 exchange
+	HeaderOriginalRoutingKey = "x-original-routing-key" // 首次投递的 routing key
+)
+
+// RetryMode 延迟重投方式
+type RetryMode string
+
+const (
+	RetryAuto            RetryMode = ""                 // ExchangeType 为 XDelayedMessage 时用延迟交换机，否则用 TTL 队列
+	RetryDelayedExchange RetryMode = "delayed_exchange" // x-delayed-message 插件，exchange 为 <queue>.retry
+	RetryTTLQueue        RetryMode = "ttl_queue"        // 每个延迟一个 TTL 队列 <queue>.retry.<ms>，到期死信回原队列
+)
+
+const (
+	maxLastErrorLen      = 1024             // x-last-error 截断长度
+	defaultRepublishWait = 30 * time.Second // 重投 / 死信转发等待确认的上限
+)
+
+// ConsumeOptions 消费选项，零值字段使用默认值
+type ConsumeOptions struct {
+	Prefetch int // QoS prefetch count，0 表示不限制
+	Workers  int // 并发处理协程数，默认 1
+
+	// MaxAttempts 最多处理次数（含首次）。<=0 时不启用重试和死信，失败直接 Nack 重回队列（旧行为）；
+	// 1 表示失败即进死信
+	MaxAttempts int
+	RetryDelays []time.Duration // 第 n 次重试用 RetryDelays[n-1]，超出取最后一个；默认 1s、10s、1m
+	RetryMode   RetryMode
+
+	DeadLetterExchange string // 默认 <queue>.dlx（direct），以原队列名为 routing key
+	DeadLetterQueue    string // 默认 <queue>.dlq，绑定到 DeadLetterExchange
+}
+
+// Permanent 包装不可恢复的错误（毒消息），handler 返回后跳过剩余重试直接进死信；同 envelopeMng.Permanent
+func Permanent(err error) error {
+	return envelopeMng.Permanent(err)
+}
+
+// IsPermanent 是否为 Permanent 包装的错误
+func IsPermanent(err error) bool {
+	return envelopeMng.IsPermanent(err)
+}
+
+// Attempts 消息此前已失败的次数（首次投递为 0）
+func Attempts(d amqp.Delivery) int {
+	switch v := d.Headers[HeaderAttempts].(type) {
+	case int:
+		return v
+	case int8:
+		return int(v)
+	case int16:
+		return int(v)
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case uint8:
+		return int(v)
+	case string:
+		n, _ := strconv.Atoi(v)
+		return n
+	}
+	return 0
+}
+
+// consumer 一个消费者的运行时选项
+type consumer struct {
+	mng        *RabbitMQ
+	opts       ConsumeOptions
+	handleFunc func(d amqp.Delivery) error
+}
+
+// newConsumer 补全默认值并声明重试 / 死信拓扑
+func (mng *RabbitMQ) newConsumer(ctx context.Context, opts *ConsumeOptions, handleFunc func(d amqp.Delivery) error) (*consumer, error) {
+	c := &consumer{mng: mng, handleFunc: handleFunc}
+	if opts != nil {
+		c.opts = *opts
+	}
+	if c.opts.Workers <= 0 {
+		c.opts.Workers = 1
+	}
+	if c.opts.MaxAttempts <= 0 {
+		return c, nil
+	}
+
+	queue := mng.Config.QueueName
+	if queue == "" {
+		return nil, errors.New("[RabbitMQ] retry and dead letter need a named queue")
+	}
+	if len(c.opts.RetryDelays) == 0 {
+		c.opts.RetryDelays = []time.Duration{time.Second, 10 * time.Second, time.Minute}
+	}
+	if c.opts.RetryMode == RetryAuto {
+		c.opts.RetryMode = RetryTTLQueue
+		if mng.Config.ExchangeType == XDelayedMessage {
+			c.opts.RetryMode = RetryDelayedExchange
+		}
+	}
+	if c.opts.DeadLetterExchange == "" {
+		c.opts.DeadLetterExchange = queue + ".dlx"
+	}
+	if c.opts.DeadLetterQueue == "" {
+		c.opts.DeadLetterQueue = queue + ".dlq"
+	}
+	if err := mng.conn.Declare(ctx, "retry|"+queue, c.declare); err != nil {
+		return nil, fmt.Errorf("declare retry topology: %w", err)
+	}
+	return c, nil
+}
+
+// declare 死信交换机与队列、重试交换机或 TTL 队列
+func (c *consumer) declare(channel *amqp.Channel) error {
+	queue := c.mng.Config.QueueName
+	durable := c.mng.Config.IsDurable
+
+	if err := channel.ExchangeDeclare(c.opts.DeadLetterExchange, string(Direct), durable, false, false, false, nil); err != nil {
+		return fmt.Errorf("dead letter exchange: %w", err)
+	}
+	if _, err := channel.QueueDeclare(c.opts.DeadLetterQueue, durable, false, false, false, nil); err != nil {
+		return fmt.Errorf("dead letter queue: %w", err)
+	}
+	if err := channel.QueueBind(c.opts.DeadLetterQueue, queue, c.opts.DeadLetterExchange, false, nil); err != nil {
+		return fmt.Errorf("dead letter bind: %w", err)
+	}
+	if c.opts.MaxAttempts <= 1 {
+		return nil
+	}
+
+	switch c.opts.RetryMode {
+	case RetryDelayedExchange:
+		args := amqp.Table{"x-delayed-type": string(Direct)}
+		if err := channel.ExchangeDeclare(c.retryExchange(), string(XDelayedMessage), durable, false, false, false, args); err != nil {
+			return fmt.Errorf("retry exchange: %w", err)
+		}
+		if err := channel.QueueBind(queue, queue, c.retryExchange(), false, nil); err != nil {
+			return fmt.Errorf("retry bind: %w", err)
+		}
+	case RetryTTLQueue:
+		seen := map[time.Duration]bool{}
+		for _, delay := range c.opts.RetryDelays {
+			if seen[delay] {
+				continue
+			}
+			seen[delay] = true
+			args := amqp.Table{
+				"x-message-ttl":             delay.Milliseconds(),
+				"x-dead-letter-exchange":    "", // 默认交换机，按队列名路由回原队列
+				"x-dead-letter-routing-key": queue,
+			}
+			if _, err := channel.QueueDeclare(c.retryQueue(delay), durable, false, false, false, args); err != nil {
+				return fmt.Errorf("retry queue: %w", err)
+			}
+		}
+	default:
+		return fmt.Errorf("[RabbitMQ] unknown retry mode %q", c.opts.RetryMode)
+	}
+	return nil
+}
+
+// undeclare 删除 declare 声明的死信与重试拓扑，用于临时队列
+func (c *consumer) undeclare(channel *amqp.Channel) error {
+	if c.opts.MaxAttempts <= 0 {
+		return nil
+	}
+	if c.opts.MaxAttempts > 1 {
+		switch c.opts.RetryMode {
+		case RetryDelayedExchange:
+			if err := channel.ExchangeDelete(c.retryExchange(), false, false); err != nil {
+				return fmt.Errorf("retry exchange: %w", err)
+			}
+		case RetryTTLQueue:
+			seen := map[time.Duration]bool{}
+			for _, delay := range c.opts.RetryDelays {
+				if seen[delay] {
+					continue
+				}
+				seen[delay] = true
+				if _, err := channel.QueueDelete(c.retryQueue(delay), false, false, false); err != nil {
+					return fmt.Errorf("retry queue: %w", err)
+				}
+			}
+		}
+	}
+	if _, err := channel.QueueDelete(c.opts.DeadLetterQueue, false, false, false); err != nil {
+		return fmt.Errorf("dead letter queue: %w", err)
+	}
+	if err := channel.ExchangeDelete(c.opts.DeadLetterExchange, false, false); err != nil {
+		return fmt.Errorf("dead letter exchange: %w", err)
+	}
+	return nil
+}
+
+func (c *consumer) retryExchange() string {
+	return c.mng.Config.QueueName + ".retry"
+}
+
+func (c *consumer) retryQueue(delay time.Duration) string {
+	return c.mng.Config.QueueName + ".retry." + strconv.FormatInt(delay.Milliseconds(), 10)
+}
+
+// retryDelay 第 attempt 次失败后的等待
+func (c *consumer) retryDelay(attempt int) time.Duration {
+	if attempt > len(c.opts.RetryDelays) {
+		attempt = len(c.opts.RetryDelays)
+	}
+	return c.opts.RetryDelays[attempt-1]
+}
+
+// handle 处理一条消息：成功 Ack；失败按选项重投或进死信，转发成功后 Ack，转发失败 Nack 重回队列
+func (c *consumer) handle(ctx context.Context, d amqp.Delivery) {
+	err := c.call(d)
+	if err == nil {
+		_ = d.Ack(false)
+		return
+	}
+	log.Printf("[RabbitMQ][Consume] handleFunc error: %v", err)
+	if c.opts.MaxAttempts <= 0 {
+		_ = d.Nack(false, true)
+		return
+	}
+
+	exchange, key, pub := c.route(d, err)
+	err = c.forward(ctx, exchange, key, pub)
+	if err != nil {
+		log.Printf("[RabbitMQ][Consume] republish failed, requeue: %v", err)
+		_ = d.Nack(false, true)
+		return
+	}
+	_ = d.Ack(false)
+}
+
+// route 失败消息的去向：Permanent 或达到 MaxAttempts 时进死信交换机，否则按延迟进重试交换机或 TTL 队列
+func (c *consumer) route(d amqp.Delivery, err error) (exchange, key string, pub amqp.Publishing) {
+	queue := c.mng.Config.QueueName
+	attempt := Attempts(d) + 1
+	pub = republishing(d, attempt, err)
+	if IsPermanent(err) || attempt >= c.opts.MaxAttempts {
+		return c.opts.DeadLetterExchange, queue, pub
+	}
+	delay := c.retryDelay(attempt)
+	if c.opts.RetryMode == RetryDelayedExchange {
+		pub.Headers["x-delay"] = delay.Milliseconds()
+		return c.retryExchange(), queue, pub
+	}
+	return "", c.retryQueue(delay), pub
+}
+
+// call 执行 handleFunc，panic 视为失败
+func (c *consumer) call(d amqp.Delivery) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return c.handleFunc(d)
+}
+
+// forward 以 publisher confirm 转发，确认后原消息才会被 Ack；不受消费 ctx 取消影响
+func (c *consumer) forward(ctx context.Context, exchange, key string, pub amqp.Publishing) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), defaultRepublishWait)
+	defer cancel()
+	return c.mng.conn.withConfirmChannel(ctx, func(pc *pooledChannel) error {
+		if err := pc.ch.Publish(exchange, key, false, false, pub); err != nil {
+			return err
+		}
+		return confirmOne(ctx, pc.confirms)
+	})
+}
+
+// republishing 复制原消息并写入重试头；不复制 Expiration，避免重试途中过期
+func republishing(d amqp.Delivery, attempt int, err error) amqp.Publishing {
+	headers := amqp.Table{}
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+	delete(headers, "x-delay")
+	if _, ok := headers[HeaderOriginalExchange]; !ok {
+		headers[HeaderOriginalExchange] = d.Exchange
+		headers[HeaderOriginalRoutingKey] = d.RoutingKey
+	}
+	msg := err.Error()
+	if len(msg) > maxLastErrorLen {
+		msg = msg[:maxLastErrorLen]
+	}
+	headers[HeaderAttempts] = int32(attempt)
+	headers[HeaderLastError] = msg
+
+	return amqp.Publishing{
+		Headers:         headers,
+		ContentType:     d.ContentType,
+		ContentEncoding: d.ContentEncoding,
+		DeliveryMode:    d.DeliveryMode,
+		Priority:        d.Priority,
+		CorrelationId:   d.CorrelationId,
+		ReplyTo:         d.ReplyTo,
+		MessageId:       d.MessageId,
+		Timestamp:       d.Timestamp,
+		Type:            d.Type,
+		AppId:           d.AppId,
+		Body:            d.Body,
+	}
+}
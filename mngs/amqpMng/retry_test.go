@@ -0,0 +1,223 @@
+package amqpMng
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// ackRecorder 记录 Ack / Nack，替代真实 channel
+type ackRecorder struct {
+	acked, nacked, requeue bool
+}
+
+func (a *ackRecorder) Ack(tag uint64, multiple bool) error { a.acked = true; return nil }
+func (a *ackRecorder) Nack(tag uint64, multiple, requeue bool) error {
+	a.nacked, a.requeue = true, requeue
+	return nil
+}
+func (a *ackRecorder) Reject(tag uint64, requeue bool) error { return a.Nack(tag, false, requeue) }
+
+func testConsumer(opts ConsumeOptions) *consumer {
+	return &consumer{mng: &RabbitMQ{Config: &Config{QueueName: "orders"}}, opts: opts}
+}
+
+func TestAttempts(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  int
+	}{
+		{"missing", nil, 0},
+		{"int", 2, 2},
+		{"int8", int8(3), 3},
+		{"int16", int16(4), 4},
+		{"int32", int32(5), 5},
+		{"int64", int64(6), 6},
+		{"uint8", uint8(7), 7},
+		{"string", "8", 8},
+		{"bad string", "x", 0},
+		{"unsupported", 1.5, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := amqp.Delivery{Headers: amqp.Table{}}
+			if tt.value != nil {
+				d.Headers[HeaderAttempts] = tt.value
+			}
+			if got := Attempts(d); got != tt.want {
+				t.Errorf("Attempts() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepublishing(t *testing.T) {
+	d := amqp.Delivery{
+		Headers:       amqp.Table{"trace": "abc", "x-delay": int64(1000)},
+		Exchange:      "shop",
+		RoutingKey:    "order.created",
+		ContentType:   "application/json",
+		DeliveryMode:  amqp.Persistent,
+		CorrelationId: "c1",
+		MessageId:     "m1",
+		Expiration:    "60000",
+		Body:          []byte(`{"id":1}`),
+	}
+	pub := republishing(d, 1, errors.New(strings.Repeat("e", maxLastErrorLen+10)))
+
+	if _, ok := pub.Headers["x-delay"]; ok {
+		t.Error("x-delay not removed")
+	}
+	if pub.Headers["trace"] != "abc" {
+		t.Errorf("trace = %v, want abc", pub.Headers["trace"])
+	}
+	if pub.Headers[HeaderOriginalExchange] != "shop" || pub.Headers[HeaderOriginalRoutingKey] != "order.created" {
+		t.Errorf("original = %v / %v", pub.Headers[HeaderOriginalExchange], pub.Headers[HeaderOriginalRoutingKey])
+	}
+	if pub.Headers[HeaderAttempts] != int32(1) {
+		t.Errorf("attempts = %#v, want int32(1)", pub.Headers[HeaderAttempts])
+	}
+	if msg := pub.Headers[HeaderLastError].(string); len(msg) != maxLastErrorLen {
+		t.Errorf("last error length = %d, want %d", len(msg), maxLastErrorLen)
+	}
+	if pub.Expiration != "" {
+		t.Errorf("expiration = %q, want empty", pub.Expiration)
+	}
+	if pub.ContentType != d.ContentType || pub.DeliveryMode != d.DeliveryMode || pub.CorrelationId != d.CorrelationId ||
+		pub.MessageId != d.MessageId || string(pub.Body) != string(d.Body) {
+		t.Errorf("properties not copied: %+v", pub)
+	}
+	if _, ok := d.Headers["x-delay"]; !ok {
+		t.Error("original delivery headers modified")
+	}
+
+	// 重试后再次失败：保留首次投递的 exchange / routing key
+	retried := amqp.Delivery{Headers: pub.Headers, Exchange: "", RoutingKey: "orders.retry.1000"}
+	again := republishing(retried, Attempts(retried)+1, errors.New("boom"))
+	if again.Headers[HeaderOriginalExchange] != "shop" || again.Headers[HeaderOriginalRoutingKey] != "order.created" {
+		t.Errorf("original overwritten: %v / %v", again.Headers[HeaderOriginalExchange], again.Headers[HeaderOriginalRoutingKey])
+	}
+	if again.Headers[HeaderAttempts] != int32(2) || again.Headers[HeaderLastError] != "boom" {
+		t.Errorf("attempts = %v, last error = %v", again.Headers[HeaderAttempts], again.Headers[HeaderLastError])
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	c := testConsumer(ConsumeOptions{RetryDelays: []time.Duration{time.Second, 10 * time.Second}})
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, time.Second},
+		{2, 10 * time.Second},
+		{3, 10 * time.Second},
+		{9, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := c.retryDelay(tt.attempt); got != tt.want {
+			t.Errorf("retryDelay(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+	if got := c.retryQueue(1500 * time.Millisecond); got != "orders.retry.1500" {
+		t.Errorf("retryQueue = %q", got)
+	}
+	if got := c.retryExchange(); got != "orders.retry" {
+		t.Errorf("retryExchange = %q", got)
+	}
+}
+
+func TestRoute(t *testing.T) {
+	delays := []time.Duration{time.Second, 10 * time.Second}
+	ttl := ConsumeOptions{MaxAttempts: 3, RetryDelays: delays, RetryMode: RetryTTLQueue, DeadLetterExchange: "orders.dlx"}
+	delayed := ttl
+	delayed.RetryMode = RetryDelayedExchange
+
+	tests := []struct {
+		name         string
+		opts         ConsumeOptions
+		attempts     int
+		err          error
+		wantExchange string
+		wantKey      string
+		wantDelay    interface{}
+	}{
+		{"ttl first failure", ttl, 0, errors.New("x"), "", "orders.retry.1000", nil},
+		{"ttl second failure", ttl, 1, errors.New("x"), "", "orders.retry.10000", nil},
+		{"ttl exhausted", ttl, 2, errors.New("x"), "orders.dlx", "orders", nil},
+		{"ttl permanent", ttl, 0, Permanent(errors.New("x")), "orders.dlx", "orders", nil},
+		{"delayed first failure", delayed, 0, errors.New("x"), "orders.retry", "orders", int64(1000)},
+		{"delayed second failure", delayed, 1, errors.New("x"), "orders.retry", "orders", int64(10000)},
+		{"delayed exhausted", delayed, 2, errors.New("x"), "orders.dlx", "orders", nil},
+		{"delayed permanent", delayed, 0, Permanent(errors.New("x")), "orders.dlx", "orders", nil},
+		{"max attempts one", ConsumeOptions{MaxAttempts: 1, DeadLetterExchange: "orders.dlx"}, 0, errors.New("x"), "orders.dlx", "orders", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := amqp.Delivery{Headers: amqp.Table{HeaderAttempts: int32(tt.attempts), "x-delay": int64(5)}}
+			exchange, key, pub := testConsumer(tt.opts).route(d, tt.err)
+			if exchange != tt.wantExchange || key != tt.wantKey {
+				t.Errorf("route = %q / %q, want %q / %q", exchange, key, tt.wantExchange, tt.wantKey)
+			}
+			if got := pub.Headers["x-delay"]; got != tt.wantDelay {
+				t.Errorf("x-delay = %#v, want %#v", got, tt.wantDelay)
+			}
+			if got := pub.Headers[HeaderAttempts]; got != int32(tt.attempts+1) {
+				t.Errorf("attempts = %#v, want %d", got, tt.attempts+1)
+			}
+		})
+	}
+}
+
+func TestHandleWithoutRetry(t *testing.T) {
+	tests := []struct {
+		name        string
+		handleFunc  func(d amqp.Delivery) error
+		wantAck     bool
+		wantRequeue bool
+	}{
+		{"success", func(d amqp.Delivery) error { return nil }, true, false},
+		{"error", func(d amqp.Delivery) error { return errors.New("x") }, false, true},
+		{"panic", func(d amqp.Delivery) error { panic("boom") }, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := (&RabbitMQ{Config: &Config{}}).newConsumer(context.Background(), nil, tt.handleFunc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rec := &ackRecorder{}
+			c.handle(context.Background(), amqp.Delivery{Acknowledger: rec})
+			if rec.acked != tt.wantAck || (rec.nacked && rec.requeue) != tt.wantRequeue {
+				t.Errorf("acked = %v, nacked = %v requeue = %v", rec.acked, rec.nacked, rec.requeue)
+			}
+		})
+	}
+}
+
+func TestCallRecoversPanic(t *testing.T) {
+	c := testConsumer(ConsumeOptions{})
+	c.handleFunc = func(d amqp.Delivery) error { panic("boom") }
+	if err := c.call(amqp.Delivery{}); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("call() = %v, want panic error", err)
+	}
+}
+
+func TestNewConsumer(t *testing.T) {
+	c, err := (&RabbitMQ{Config: &Config{}}).newConsumer(context.Background(), &ConsumeOptions{Prefetch: 5}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.opts.Workers != 1 || c.opts.Prefetch != 5 {
+		t.Errorf("opts = %+v", c.opts)
+	}
+
+	_, err = (&RabbitMQ{Config: &Config{}}).newConsumer(context.Background(), &ConsumeOptions{MaxAttempts: 3}, nil)
+	if err == nil {
+		t.Error("want error for retry without a named queue")
+	}
+}
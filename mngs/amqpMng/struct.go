@@ -1,6 +1,8 @@
 package amqpMng
 
 import (
+	"sync"
+
 	"github.com/streadway/amqp"
 )
 
@@ -44,5 +46,10 @@ type Config struct {
 // 管理器
 type RabbitMQ struct {
 	Config *Config
-	Conn   *amqp.Connection
+	// Deprecated: 创建时的底层连接，重连后失效，请使用 Connection()
+	Conn *amqp.Connection
+
+	conn     *Connection
+	topoMu   sync.Mutex
+	declared bool
 }
@@ -98,7 +98,15 @@ func NewApp(ctx context.Context, loggerBuilder AppLogger, configPool *ConfigPool
 		log.Printf("✅成功: RabbitMQ 连接已初始化")
 	}
 
-	// 【4】项目级配置构建
+	// 【4】数据库迁移（ProjectConfig 实现了 MigrationProvider 时）
+	if provider, ok := projectBuilder.(MigrationProvider); ok {
+		if err = appMng.runMigrations(ctx, provider); err != nil {
+			err = errFactory.migrateFailed(err)
+			return
+		}
+	}
+
+	// 【5】项目级配置构建
 
 	// 设置 ProjectConfig（如果提供了）
 	if projectBuilder != nil {
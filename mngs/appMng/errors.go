@@ -160,6 +160,11 @@ func (f *errorFactory) rabbitmqInitFailed(err error) error {
 	return fmt.Errorf("❌appMng: init rabbitmq failed: %w", err)
 }
 
+// migrateFailed 数据库迁移失败
+func (f *errorFactory) migrateFailed(err error) error {
+	return fmt.Errorf("❌appMng: migrate failed: %w", err)
+}
+
 // projectBuildFailed 项目配置构建失败
 func (f *errorFactory) projectBuildFailed(err error) error {
 	return fmt.Errorf("❌appMng: project build failed: %w", err)
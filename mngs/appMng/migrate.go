@@ -0,0 +1,44 @@
+package appMng
+
+import (
+	"context"
+	"log"
+
+	"github.com/wiidz/goutil/mngs/migrateMng"
+)
+
+// Migrations NewApp 启动时执行的版本化迁移
+type Migrations struct {
+	Mysql    []*migrateMng.Migration
+	Postgres []*migrateMng.Migration
+	Options  []migrateMng.Option // 如 migrateMng.WithDryRun(os.Stdout)
+}
+
+// MigrationProvider 可选接口，ProjectConfig 实现后 NewApp 会在数据库连接初始化之后、项目配置构建之前执行迁移
+// 多副本同时启动时由迁移锁保证只有一个实例执行
+type MigrationProvider interface {
+	Migrations() (*Migrations, error)
+}
+
+// runMigrations 执行 MySQL 与 PostgreSQL 的迁移
+func (mng *AppMng) runMigrations(ctx context.Context, provider MigrationProvider) error {
+	migrations, err := provider.Migrations()
+	if err != nil || migrations == nil {
+		return err
+	}
+	if len(migrations.Mysql) > 0 && mng.Repos.Mysql != nil {
+		done, err := mng.Repos.Mysql.NewMigrator(migrations.Options...).Register(migrations.Mysql...).Up(ctx)
+		if err != nil {
+			return err
+		}
+		log.Printf("✅成功: MySQL 迁移已完成 (本次执行 %d 个)", len(done))
+	}
+	if len(migrations.Postgres) > 0 && mng.Repos.Postgres != nil {
+		done, err := mng.Repos.Postgres.NewMigrator(migrations.Options...).Register(migrations.Postgres...).Up(ctx)
+		if err != nil {
+			return err
+		}
+		log.Printf("✅成功: PostgreSQL 迁移已完成 (本次执行 %d 个)", len(done))
+	}
+	return nil
+}
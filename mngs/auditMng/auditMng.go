@@ -0,0 +1,230 @@
+package auditMng
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/wiidz/goutil/mngs/identityMng"
+)
+
+// Action 变更类型
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+)
+
+// Entry 一行数据的一次变更
+type Entry struct {
+	Action     Action          `json:"action"`
+	Table      string          `json:"table"`
+	PrimaryKey string          `json:"primary_key"`      // 多列主键以逗号连接
+	Actor      string          `json:"actor"`            // 操作人（登录ID）
+	RequestID  string          `json:"request_id"`       // 请求ID
+	Before     json.RawMessage `json:"before,omitempty"` // 变更前快照，新增时为空
+	After      json.RawMessage `json:"after,omitempty"`  // 变更后快照，删除时为空
+	Diff       json.RawMessage `json:"diff,omitempty"`   // 变化的列：{"col":{"old":x,"new":y}}
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// Sink 审计日志的存储，Write 在后台协程中按批调用
+type Sink interface {
+	Write(ctx context.Context, entries []*Entry) error
+}
+
+// Option 审计配置
+type Option func(*options)
+
+type options struct {
+	bufferSize    int
+	batchSize     int
+	flushInterval time.Duration
+	maxRows       int
+	ignoreTables  map[string]struct{}
+	actorFunc     func(ctx context.Context) string
+	requestIDFunc func(ctx context.Context) string
+}
+
+// WithBufferSize 缓冲通道长度，默认 4096，写满时丢弃新日志并计数
+func WithBufferSize(n int) Option {
+	return func(o *options) { o.bufferSize = n }
+}
+
+// WithBatch 每批最多写入条数（默认 100）与最长等待时间（默认 1 秒）
+func WithBatch(size int, interval time.Duration) Option {
+	return func(o *options) { o.batchSize, o.flushInterval = size, interval }
+}
+
+// WithMaxRows 单条语句最多记录的行数，超出部分不再记录快照，默认 1000
+func WithMaxRows(n int) Option {
+	return func(o *options) { o.maxRows = n }
+}
+
+// WithIgnoreTables 不记录的表，DBSink 的日志表会自动忽略
+func WithIgnoreTables(tables ...string) Option {
+	return func(o *options) {
+		for _, t := range tables {
+			o.ignoreTables[t] = struct{}{}
+		}
+	}
+}
+
+// WithActorFunc 自定义操作人，默认取 identityMng.LoginIDFromContext
+func WithActorFunc(fn func(ctx context.Context) string) Option {
+	return func(o *options) { o.actorFunc = fn }
+}
+
+// WithRequestIDFunc 自定义请求ID，默认取 ContextWithRequestID 写入的值
+func WithRequestIDFunc(fn func(ctx context.Context) string) Option {
+	return func(o *options) { o.requestIDFunc = fn }
+}
+
+// requestIDKey 请求ID在 context 中的键
+type requestIDKey struct{}
+
+// ContextWithRequestID 将请求ID写入 context
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext 读取 context 中的请求ID
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// Auditor 审计管理器，同时是 gorm 插件：db.Use(auditor)
+type Auditor struct {
+	sink Sink
+	opts options
+
+	ch      chan *Entry
+	done    chan struct{}
+	mu      sync.RWMutex // Record 持读锁投递，Close 持写锁关闭通道，避免向已关闭的通道写入
+	closed  bool
+	dropped atomic.Int64
+}
+
+// NewAuditor 创建审计管理器并启动后台写入协程
+func NewAuditor(sink Sink, opts ...Option) *Auditor {
+	o := options{
+		bufferSize:    4096,
+		batchSize:     100,
+		flushInterval: time.Second,
+		maxRows:       1000,
+		ignoreTables:  map[string]struct{}{},
+		actorFunc:     identityMng.LoginIDFromContext,
+		requestIDFunc: RequestIDFromContext,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.bufferSize <= 0 {
+		o.bufferSize = 4096
+	}
+	if o.batchSize <= 0 {
+		o.batchSize = 100
+	}
+	if o.flushInterval <= 0 {
+		o.flushInterval = time.Second
+	}
+	if t, ok := sink.(interface{ TableName() string }); ok {
+		o.ignoreTables[t.TableName()] = struct{}{}
+	}
+
+	a := &Auditor{
+		sink: sink,
+		opts: o,
+		ch:   make(chan *Entry, o.bufferSize),
+		done: make(chan struct{}),
+	}
+	go a.run()
+	return a
+}
+
+// Record 投递一条日志，不会阻塞；缓冲区已满或已 Close 时丢弃
+func (a *Auditor) Record(entry *Entry) {
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		a.dropped.Add(1)
+		return
+	}
+	select {
+	case a.ch <- entry:
+	default:
+		if a.dropped.Add(1)%1000 == 1 {
+			log.Println("【auditMng】buffer full, entries dropped:", a.dropped.Load())
+		}
+	}
+}
+
+// Dropped 因缓冲区满或已关闭被丢弃的条数
+func (a *Auditor) Dropped() int64 {
+	return a.dropped.Load()
+}
+
+// Close 停止接收并写完缓冲区中的日志
+func (a *Auditor) Close(ctx context.Context) error {
+	a.mu.Lock()
+	if !a.closed {
+		a.closed = true
+		close(a.ch)
+	}
+	a.mu.Unlock()
+	select {
+	case <-a.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run 按批写入
+func (a *Auditor) run() {
+	defer close(a.done)
+
+	ticker := time.NewTicker(a.opts.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Entry, 0, a.opts.batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := a.sink.Write(ctx, batch); err != nil {
+			log.Println("【auditMng】sink write err:", err)
+		}
+		cancel()
+		batch = make([]*Entry, 0, a.opts.batchSize)
+	}
+
+	for {
+		select {
+		case entry, ok := <-a.ch:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, entry)
+			if len(batch) >= a.opts.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
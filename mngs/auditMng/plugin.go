@@ -0,0 +1,303 @@
+package auditMng
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/wiidz/goutil/helpers/condHelper"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+	"gorm.io/plugin/dbresolver"
+)
+
+const beforeKey = "auditMng:before"
+
+type row = map[string]interface{}
+
+// Name gorm.Plugin
+func (a *Auditor) Name() string {
+	return "auditMng"
+}
+
+// Initialize gorm.Plugin，注册增删改回调；db.Exec / Raw 不经过这些回调，不会被记录
+func (a *Auditor) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Create().After("gorm:create").Register("auditMng:after_create", a.afterCreate); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register("auditMng:before_update", a.snapshotBefore); err != nil {
+		return err
+	}
+	if err := cb.Update().After("gorm:update").Register("auditMng:after_update", a.afterUpdate); err != nil {
+		return err
+	}
+	if err := cb.Delete().Before("gorm:delete").Register("auditMng:before_delete", a.snapshotBefore); err != nil {
+		return err
+	}
+	return cb.Delete().After("gorm:delete").Register("auditMng:after_delete", a.afterDelete)
+}
+
+// skip 出错、DryRun、忽略的表都不记录
+func (a *Auditor) skip(db *gorm.DB) bool {
+	if db.Error != nil || db.DryRun || db.Statement.Table == "" {
+		return true
+	}
+	_, ignored := a.opts.ignoreTables[db.Statement.Table]
+	return ignored
+}
+
+// -------BEGIN------callbacks-----BEGIN--------
+
+// afterCreate 新增的快照直接取自写入的结构体，不再回查
+func (a *Auditor) afterCreate(db *gorm.DB) {
+	if a.skip(db) || db.Statement.Schema == nil {
+		return
+	}
+	stmt := db.Statement
+	var values []reflect.Value
+	switch rv := reflect.Indirect(stmt.ReflectValue); rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len() && i < a.opts.maxRows; i++ {
+			values = append(values, reflect.Indirect(rv.Index(i)))
+		}
+	case reflect.Struct:
+		values = append(values, rv)
+	}
+	for _, v := range values {
+		after := structRow(stmt.Context, stmt.Schema, v)
+		a.record(db, ActionCreate, nil, after)
+	}
+}
+
+// snapshotBefore 修改、删除之前按相同条件查出将被影响的行
+func (a *Auditor) snapshotBefore(db *gorm.DB) {
+	if a.skip(db) {
+		return
+	}
+	rows, ok := a.fetch(db, a.whereExprs(db), db.Statement.Unscoped)
+	if ok {
+		db.InstanceSet(beforeKey, rows)
+	}
+}
+
+// afterUpdate 按主键回查修改后的行并与修改前比较
+func (a *Auditor) afterUpdate(db *gorm.DB) {
+	before := a.before(db)
+	if len(before) == 0 {
+		return
+	}
+	keys := primaryColumns(db.Statement.Schema, before[0])
+	if len(keys) == 0 {
+		return
+	}
+	// 修改可能恰好是软删除，回查时不再过滤
+	after, _ := a.fetch(db, []clause.Expression{keyCond(keys, before)}, true)
+	index := make(map[string]row, len(after))
+	for _, r := range after {
+		index[keyString(keys, r)] = r
+	}
+	for _, old := range before {
+		a.record(db, ActionUpdate, old, index[keyString(keys, old)])
+	}
+}
+
+// afterDelete 软删除同样按删除记录，after 为空
+func (a *Auditor) afterDelete(db *gorm.DB) {
+	for _, old := range a.before(db) {
+		a.record(db, ActionDelete, old, nil)
+	}
+}
+
+// -------END------callbacks----END---------
+
+func (a *Auditor) before(db *gorm.DB) []row {
+	if a.skip(db) || db.RowsAffected == 0 {
+		return nil
+	}
+	v, ok := db.InstanceGet(beforeKey)
+	if !ok {
+		return nil
+	}
+	rows, _ := v.([]row)
+	return rows
+}
+
+// whereExprs 语句上的条件；gorm 在执行阶段才会把模型主键并入条件，这里提前补上
+func (a *Auditor) whereExprs(db *gorm.DB) []clause.Expression {
+	stmt := db.Statement
+	var exprs []clause.Expression
+	if c, ok := stmt.Clauses["WHERE"]; ok {
+		if where, ok := c.Expression.(clause.Where); ok {
+			exprs = append(exprs, where.Exprs...)
+		}
+	}
+	if stmt.Schema != nil && len(stmt.Schema.PrimaryFields) > 0 && stmt.ReflectValue.IsValid() {
+		_, identities := schema.GetIdentityFieldValuesMap(stmt.Context, stmt.ReflectValue, stmt.Schema.PrimaryFields)
+		column, values := schema.ToQueryValues(stmt.Table, stmt.Schema.PrimaryFieldDBNames, identities)
+		if len(values) > 0 {
+			exprs = append(exprs, clause.IN{Column: column, Values: values})
+		}
+	}
+	return exprs
+}
+
+// fetch 在同一连接（含事务）上查询快照，无条件时不查，避免全表扫描
+func (a *Auditor) fetch(db *gorm.DB, exprs []clause.Expression, unscoped bool) ([]row, bool) {
+	if len(exprs) == 0 {
+		return nil, false
+	}
+	stmt := db.Statement
+	tx := db.Session(&gorm.Session{NewDB: true, Context: stmt.Context, SkipHooks: true}).
+		Clauses(dbresolver.Write)
+	if stmt.Schema != nil {
+		tx = tx.Model(reflect.New(stmt.Schema.ModelType).Interface())
+		if unscoped {
+			tx = tx.Unscoped()
+		}
+	}
+	var rows []row
+	err := tx.Table(stmt.Table).Clauses(clause.Where{Exprs: exprs}).Limit(a.opts.maxRows).Find(&rows).Error
+	if err != nil {
+		return nil, false
+	}
+	for _, r := range rows {
+		normalize(r)
+	}
+	return rows, true
+}
+
+// record 组装日志并投递
+func (a *Auditor) record(db *gorm.DB, action Action, before, after row) {
+	diff := diffRows(before, after)
+	if action == ActionUpdate && len(diff) == 0 {
+		return
+	}
+	ctx := db.Statement.Context
+	entry := &Entry{
+		Action:    action,
+		Table:     db.Statement.Table,
+		Actor:     a.opts.actorFunc(ctx),
+		RequestID: a.opts.requestIDFunc(ctx),
+		Before:    marshal(before),
+		After:     marshal(after),
+		Diff:      marshal(diff),
+		CreatedAt: time.Now(),
+	}
+	snapshot := after
+	if snapshot == nil {
+		snapshot = before
+	}
+	entry.PrimaryKey = keyString(primaryColumns(db.Statement.Schema, snapshot), snapshot)
+	a.Record(entry)
+}
+
+// -------BEGIN------snapshot helpers-----BEGIN--------
+
+// structRow 结构体转为 列名 => 值
+func structRow(ctx context.Context, sch *schema.Schema, rv reflect.Value) row {
+	r := make(row, len(sch.DBNames))
+	for _, name := range sch.DBNames {
+		if field := sch.FieldsByDBName[name]; field != nil {
+			r[name], _ = field.ValueOf(ctx, rv)
+		}
+	}
+	return normalize(r)
+}
+
+// normalize 统一值的形式，使快照之间可以比较并可读地序列化
+func normalize(r row) row {
+	for k, v := range r {
+		switch val := v.(type) {
+		case []byte:
+			r[k] = string(val)
+		case time.Time:
+			r[k] = val.UTC().Format(time.RFC3339Nano)
+		case *time.Time:
+			if val == nil {
+				r[k] = nil
+			} else {
+				r[k] = val.UTC().Format(time.RFC3339Nano)
+			}
+		case gorm.DeletedAt:
+			if val.Valid {
+				r[k] = val.Time.UTC().Format(time.RFC3339Nano)
+			} else {
+				r[k] = nil
+			}
+		}
+	}
+	return r
+}
+
+// diffRows 变化的列，新增时为全部列，删除时为空
+func diffRows(before, after row) map[string]map[string]interface{} {
+	if after == nil {
+		return nil
+	}
+	diff := map[string]map[string]interface{}{}
+	for k, newVal := range after {
+		oldVal, ok := before[k]
+		if ok && fmt.Sprint(oldVal) == fmt.Sprint(newVal) {
+			continue
+		}
+		diff[k] = map[string]interface{}{"old": oldVal, "new": newVal}
+	}
+	return diff
+}
+
+// primaryColumns 主键列，无模型时退回 id
+func primaryColumns(sch *schema.Schema, r row) []string {
+	if sch != nil && len(sch.PrimaryFieldDBNames) > 0 {
+		return sch.PrimaryFieldDBNames
+	}
+	if _, ok := r["id"]; ok {
+		return []string{"id"}
+	}
+	return nil
+}
+
+func keyString(keys []string, r row) string {
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprint(r[k]))
+	}
+	return strings.Join(parts, ",")
+}
+
+// keyCond pk IN (...)，联合主键为 (pk1 = ? AND pk2 = ?) OR ...
+func keyCond(keys []string, rows []row) condHelper.Cond {
+	if len(keys) == 1 {
+		values := make([]interface{}, 0, len(rows))
+		for _, r := range rows {
+			values = append(values, r[keys[0]])
+		}
+		return condHelper.In(keys[0], values)
+	}
+	conds := make([]condHelper.Cond, 0, len(rows))
+	for _, r := range rows {
+		and := make([]condHelper.Cond, 0, len(keys))
+		for _, k := range keys {
+			and = append(and, condHelper.Eq(k, r[k]))
+		}
+		conds = append(conds, condHelper.And(and...))
+	}
+	return condHelper.Or(conds...)
+}
+
+func marshal(v interface{}) json.RawMessage {
+	if rv := reflect.ValueOf(v); !rv.IsValid() || rv.IsNil() {
+		return nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+// -------END------snapshot helpers----END---------
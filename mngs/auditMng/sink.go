@@ -0,0 +1,204 @@
+package auditMng
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/wiidz/goutil/mngs/amqpMng"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// DefaultTableName DBSink 默认表名
+const DefaultTableName = "a_audit_log"
+
+// -------BEGIN------db sink-----BEGIN--------
+
+// JSON 按数据库选择列类型：mysql 为 json，postgres 为 jsonb，其他为 text
+type JSON json.RawMessage
+
+// GormDBDataType gorm 建表时的列类型
+func (JSON) GormDBDataType(db *gorm.DB, _ *schema.Field) string {
+	switch db.Dialector.Name() {
+	case "mysql":
+		return "json"
+	case "postgres":
+		return "jsonb"
+	}
+	return "text"
+}
+
+// Value driver.Valuer
+func (j JSON) Value() (driver.Value, error) {
+	if len(j) == 0 {
+		return nil, nil
+	}
+	return string(j), nil
+}
+
+// Scan sql.Scanner
+func (j *JSON) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*j = nil
+	case []byte:
+		*j = append((*j)[:0], v...)
+	case string:
+		*j = JSON(v)
+	default:
+		return fmt.Errorf("auditMng: cannot scan %T into JSON", value)
+	}
+	return nil
+}
+
+// MarshalJSON 原样输出
+func (j JSON) MarshalJSON() ([]byte, error) {
+	if len(j) == 0 {
+		return []byte("null"), nil
+	}
+	return j, nil
+}
+
+// Record 审计日志表结构
+type Record struct {
+	ID         uint64    `gorm:"primaryKey;column:id" json:"id"`
+	Action     string    `gorm:"column:action;type:varchar(16);not null" json:"action"`                       // create / update / delete
+	Table      string    `gorm:"column:table_name;type:varchar(64);not null;index:idx_table_pk" json:"table"` // 表名
+	PrimaryKey string    `gorm:"column:primary_key;type:varchar(128);index:idx_table_pk" json:"primary_key"`  // 主键
+	Actor      string    `gorm:"column:actor;type:varchar(64);index" json:"actor"`                            // 操作人
+	RequestID  string    `gorm:"column:request_id;type:varchar(64);index" json:"request_id"`                  // 请求ID
+	Before     JSON      `gorm:"column:before_data" json:"before"`                                            // 变更前
+	After      JSON      `gorm:"column:after_data" json:"after"`                                              // 变更后
+	Diff       JSON      `gorm:"column:diff" json:"diff"`                                                     // 变化的列
+	CreatedAt  time.Time `gorm:"column:created_at;index" json:"created_at"`                                   // 创建时间
+}
+
+// DBSink 写入数据库表，可与被审计的表同库（mysqlMng、psqlMng 均可）
+type DBSink struct {
+	db    *gorm.DB
+	table string
+}
+
+// NewDBSink 创建数据库存储，table 为空时使用 a_audit_log，并自动建表
+func NewDBSink(db *gorm.DB, table string) (*DBSink, error) {
+	if db == nil {
+		return nil, gorm.ErrInvalidDB
+	}
+	if table == "" {
+		table = DefaultTableName
+	}
+	sink := &DBSink{db: db.Session(&gorm.Session{NewDB: true}), table: table}
+	if err := sink.db.Table(table).AutoMigrate(&Record{}); err != nil {
+		return nil, err
+	}
+	return sink, nil
+}
+
+// TableName 日志表名，Auditor 会自动忽略该表
+func (s *DBSink) TableName() string {
+	return s.table
+}
+
+// Write Sink
+func (s *DBSink) Write(ctx context.Context, entries []*Entry) error {
+	records := make([]*Record, 0, len(entries))
+	for _, e := range entries {
+		records = append(records, &Record{
+			Action:     string(e.Action),
+			Table:      e.Table,
+			PrimaryKey: e.PrimaryKey,
+			Actor:      e.Actor,
+			RequestID:  e.RequestID,
+			Before:     JSON(e.Before),
+			After:      JSON(e.After),
+			Diff:       JSON(e.Diff),
+			CreatedAt:  e.CreatedAt,
+		})
+	}
+	return s.db.WithContext(ctx).Table(s.table).CreateInBatches(records, 100).Error
+}
+
+// -------END------db sink----END---------
+
+// FileSink 以 JSON Lines 追加写入文件
+type FileSink struct {
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+// NewFileSink 打开（或创建）日志文件
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{file: file, enc: json.NewEncoder(file)}, nil
+}
+
+// Write Sink
+func (s *FileSink) Write(_ context.Context, entries []*Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range entries {
+		if err := s.enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close 关闭文件，应在 Auditor.Close 之后调用
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+// AMQPSink 每条日志作为一条 JSON 消息发布到 amqpMng 配置的交换机
+type AMQPSink struct {
+	mq       *amqpMng.RabbitMQ
+	reliable bool
+}
+
+// NewAMQPSink reliable 为 true 时使用 Publisher Confirm
+func NewAMQPSink(mq *amqpMng.RabbitMQ, reliable bool) *AMQPSink {
+	return &AMQPSink{mq: mq, reliable: reliable}
+}
+
+// Write Sink
+func (s *AMQPSink) Write(ctx context.Context, entries []*Entry) error {
+	for _, e := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		body, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		if err = s.mq.Publish(string(body), 0, s.reliable); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MultiSink 同时写入多个存储，某个失败不影响其他
+type MultiSink []Sink
+
+// Write Sink
+func (m MultiSink) Write(ctx context.Context, entries []*Entry) error {
+	var errs []error
+	for _, sink := range m {
+		if err := sink.Write(ctx, entries); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
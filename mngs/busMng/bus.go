@@ -0,0 +1,105 @@
+package busMng
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+	"github.com/wiidz/goutil/mngs/envelopeMng"
+)
+
+// Message 总线上传递的消息，即 envelopeMng.Envelope
+type Message = envelopeMng.Envelope
+
+// ExchangeKind 交换机类型，路由语义与 RabbitMQ 一致
+type ExchangeKind string
+
+const (
+	Direct ExchangeKind = "direct" // routing key 与 binding key 完全相同
+	Topic  ExchangeKind = "topic"  // binding key 中 * 匹配一个词，# 匹配零或多个词，词以 . 分隔
+	Fanout ExchangeKind = "fanout" // 忽略 routing key，投递到所有绑定的队列
+)
+
+// DefaultExchange 默认交换机：按 routing key 直接投递到同名队列，无需声明
+const DefaultExchange = ""
+
+// Handler 消息处理函数：返回 nil 确认；返回错误时按 Binding.MaxAttempts 重投或进死信，
+// envelopeMng.Permanent 包装的错误直接进死信。ctx 中带有消息的关联ID与追踪头
+type Handler func(ctx context.Context, msg *Message) error
+
+// Binding 订阅设置
+type Binding struct {
+	Exchange     string       // 为空时直接消费 Queue（默认交换机）
+	ExchangeKind ExchangeKind // 订阅时同时声明交换机，默认 Direct
+	Queue        string       // 为空时创建临时队列，订阅关闭后删除
+	Keys         []string     // binding key，Fanout 忽略；为空时绑定 ""
+	Durable      bool         // 队列持久化
+
+	Prefetch int // 未确认消息上限，0 不限
+	Workers  int // 并发处理数，默认 1
+
+	// MaxAttempts 最多处理次数（含首次），<=0 时失败消息立即重回队列；
+	// 超出后进入死信（RabbitMQ 为 <queue>.dlq，内存实现见 MemoryBus.DeadLetters）
+	MaxAttempts int
+	RetryDelays []time.Duration // 第 n 次重试前的等待，超出取最后一个
+}
+
+// Subscription 订阅，Close 后不再接收消息
+type Subscription interface {
+	Queue() string // 实际消费的队列名（临时队列时由总线生成）
+	Close() error
+}
+
+// Bus 消息总线，由 amqpMng.Bus、rabbitMngOld.Bus 与 MemoryBus 实现
+type Bus interface {
+	// DeclareExchange 声明交换机，发布前需声明（默认交换机除外）
+	DeclareExchange(ctx context.Context, name string, kind ExchangeKind) error
+	// Publish 发布，msg 的 ID / Timestamp 为空时自动填充
+	Publish(ctx context.Context, exchange, routingKey string, msg *Message) error
+	// PublishDelayed 延迟 delay 后再路由
+	PublishDelayed(ctx context.Context, exchange, routingKey string, msg *Message, delay time.Duration) error
+	// Subscribe 按 binding 消费，直到 Subscription.Close 或 ctx 结束
+	Subscribe(ctx context.Context, binding Binding, handler Handler) (Subscription, error)
+	// Request 发布并等待回复（回复的 CorrelationID 为请求的 ID），超时由 ctx 控制
+	Request(ctx context.Context, exchange, routingKey string, msg *Message) (*Message, error)
+	// Reply 回复 Request 发来的消息
+	Reply(ctx context.Context, request *Message, reply *Message) error
+	// Close 关闭所有订阅
+	Close() error
+}
+
+// Prepare 补全消息ID与时间戳
+func Prepare(msg *Message) {
+	if msg.ID == "" {
+		msg.ID = uuid.NewString()
+	}
+	if msg.Timestamp.IsZero() {
+		msg.Timestamp = time.Now()
+	}
+	if msg.CorrelationID == "" {
+		msg.CorrelationID = msg.ID
+	}
+}
+
+// PrepareReply 补全回复消息，关联到请求
+func PrepareReply(request, reply *Message) {
+	reply.CorrelationID = request.ID
+	Prepare(reply)
+}
+
+// Sender 适配 envelopeMng.Sender，使 envelopeMng.Publisher[T] 可以经总线发布
+func Sender(bus Bus, exchange, routingKey string) envelopeMng.Sender {
+	return &sender{bus: bus, exchange: exchange, routingKey: routingKey}
+}
+
+type sender struct {
+	bus        Bus
+	exchange   string
+	routingKey string
+}
+
+// PublishMessage envelopeMng.Sender；总线发布总是等待确认，reliable 被忽略
+func (s *sender) PublishMessage(ctx context.Context, pub amqp.Publishing, _ bool) error {
+	return s.bus.Publish(ctx, s.exchange, s.routingKey, envelopeMng.FromPublishing(pub))
+}
@@ -0,0 +1,513 @@
+package busMng
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wiidz/goutil/mngs/envelopeMng"
+)
+
+// ErrClosed 总线已关闭
+var ErrClosed = errors.New("busMng: bus closed")
+
+// defaultRetryDelays 与 amqpMng 的默认重试间隔一致
+var defaultRetryDelays = []time.Duration{time.Second, 10 * time.Second, time.Minute}
+
+// MemoryBus 进程内总线，路由（direct / topic / fanout / 默认交换机）、延迟、确认与重试语义与 RabbitMQ 一致，
+// 用于测试或单机运行。未被任何队列匹配的消息会被丢弃；没有消费者的队列会一直保留消息
+type MemoryBus struct {
+	mu        sync.Mutex
+	exchanges map[string]ExchangeKind
+	bindings  map[string][]memBinding // exchange → 绑定
+	queues    map[string]*memQueue
+	dead      map[string][]*Message // queue → 死信
+	subs      map[*memSubscription]struct{}
+	closed    bool
+
+	pending atomic.Int64 // 排队中、处理中与延迟中的消息数，Flush 用
+}
+
+type memBinding struct {
+	queue string
+	key   string
+}
+
+type memQueue struct {
+	name  string
+	items []*memDelivery
+	ready chan struct{} // 有新消息时发出信号
+}
+
+type memDelivery struct {
+	msg      *Message
+	attempts int // 已失败次数
+}
+
+// NewMemoryBus 创建进程内总线
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{
+		exchanges: map[string]ExchangeKind{},
+		bindings:  map[string][]memBinding{},
+		queues:    map[string]*memQueue{},
+		dead:      map[string][]*Message{},
+		subs:      map[*memSubscription]struct{}{},
+	}
+}
+
+// DeclareExchange Bus；同名交换机类型不同时返回错误
+func (b *MemoryBus) DeclareExchange(_ context.Context, name string, kind ExchangeKind) error {
+	if name == DefaultExchange {
+		return errors.New("busMng: cannot declare the default exchange")
+	}
+	switch kind {
+	case Direct, Topic, Fanout:
+	default:
+		return fmt.Errorf("busMng: unsupported exchange kind %q", kind)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if old, ok := b.exchanges[name]; ok && old != kind {
+		return fmt.Errorf("busMng: exchange %s already declared as %s", name, old)
+	}
+	b.exchanges[name] = kind
+	return nil
+}
+
+// Publish Bus
+func (b *MemoryBus) Publish(_ context.Context, exchange, routingKey string, msg *Message) error {
+	Prepare(msg)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return ErrClosed
+	}
+	return b.route(exchange, routingKey, msg)
+}
+
+// PublishDelayed Bus；延迟期间交换机需保持声明
+func (b *MemoryBus) PublishDelayed(ctx context.Context, exchange, routingKey string, msg *Message, delay time.Duration) error {
+	if delay <= 0 {
+		return b.Publish(ctx, exchange, routingKey, msg)
+	}
+	Prepare(msg)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return ErrClosed
+	}
+	if _, ok := b.exchanges[exchange]; !ok && exchange != DefaultExchange {
+		return fmt.Errorf("busMng: exchange %s not found", exchange)
+	}
+	b.pending.Add(1)
+	time.AfterFunc(delay, func() {
+		defer b.pending.Add(-1)
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if b.closed {
+			return
+		}
+		if err := b.route(exchange, routingKey, msg); err != nil {
+			log.Println("【busMng】delayed publish:", err)
+		}
+	})
+	return nil
+}
+
+// route 按交换机类型投递到匹配的队列，调用方持有锁
+func (b *MemoryBus) route(exchange, routingKey string, msg *Message) error {
+	if exchange == DefaultExchange {
+		if q, ok := b.queues[routingKey]; ok {
+			b.enqueue(q, &memDelivery{msg: cloneMessage(msg)})
+		}
+		return nil
+	}
+	kind, ok := b.exchanges[exchange]
+	if !ok {
+		return fmt.Errorf("busMng: exchange %s not found", exchange)
+	}
+	routed := map[string]struct{}{}
+	for _, bd := range b.bindings[exchange] {
+		if _, done := routed[bd.queue]; done {
+			continue
+		}
+		if kind == Fanout || (kind == Direct && bd.key == routingKey) || (kind == Topic && topicMatch(bd.key, routingKey)) {
+			routed[bd.queue] = struct{}{}
+			if q, ok := b.queues[bd.queue]; ok {
+				b.enqueue(q, &memDelivery{msg: cloneMessage(msg)})
+			}
+		}
+	}
+	return nil
+}
+
+// enqueue 调用方持有锁
+func (b *MemoryBus) enqueue(q *memQueue, d *memDelivery) {
+	b.pending.Add(1)
+	q.items = append(q.items, d)
+	q.signal()
+}
+
+func (q *memQueue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// take 取出一条消息，队列为空时等待；ctx 结束后不再取，剩余消息留在队列中
+func (b *MemoryBus) take(ctx context.Context, q *memQueue) (*memDelivery, bool) {
+	for {
+		if ctx.Err() != nil {
+			return nil, false
+		}
+		b.mu.Lock()
+		if len(q.items) > 0 {
+			d := q.items[0]
+			q.items = q.items[1:]
+			if len(q.items) > 0 {
+				q.signal() // 唤醒其他消费者
+			}
+			b.mu.Unlock()
+			return d, true
+		}
+		b.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return nil, false
+		case <-q.ready:
+		}
+	}
+}
+
+// declareQueue 调用方持有锁
+func (b *MemoryBus) declareQueue(name string) *memQueue {
+	q, ok := b.queues[name]
+	if !ok {
+		q = &memQueue{name: name, ready: make(chan struct{}, 1)}
+		b.queues[name] = q
+	}
+	return q
+}
+
+// deleteQueue 删除队列及其绑定，调用方持有锁
+func (b *MemoryBus) deleteQueue(name string) {
+	if q, ok := b.queues[name]; ok {
+		b.pending.Add(-int64(len(q.items)))
+		delete(b.queues, name)
+	}
+	for ex, bds := range b.bindings {
+		kept := bds[:0]
+		for _, bd := range bds {
+			if bd.queue != name {
+				kept = append(kept, bd)
+			}
+		}
+		b.bindings[ex] = kept
+	}
+}
+
+// -------BEGIN------subscribe-----BEGIN--------
+
+type memSubscription struct {
+	bus     *MemoryBus
+	queue   string
+	temp    bool
+	binding Binding
+	handler Handler
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	once   sync.Once
+}
+
+// Queue Subscription
+func (s *memSubscription) Queue() string {
+	return s.queue
+}
+
+// Close Subscription；等待处理中的消息完成，临时队列随之删除
+func (s *memSubscription) Close() error {
+	s.once.Do(func() {
+		s.cancel()
+		s.wg.Wait()
+		s.bus.mu.Lock()
+		delete(s.bus.subs, s)
+		if s.temp {
+			s.bus.deleteQueue(s.queue)
+		}
+		s.bus.mu.Unlock()
+	})
+	return nil
+}
+
+// Subscribe Bus；Prefetch 在内存实现中由 Workers 限制，不单独生效
+func (b *MemoryBus) Subscribe(ctx context.Context, binding Binding, handler Handler) (Subscription, error) {
+	if binding.ExchangeKind == "" {
+		binding.ExchangeKind = Direct
+	}
+	if binding.Workers <= 0 {
+		binding.Workers = 1
+	}
+	if binding.MaxAttempts > 0 && len(binding.RetryDelays) == 0 {
+		binding.RetryDelays = defaultRetryDelays
+	}
+	if binding.Exchange != DefaultExchange {
+		if err := b.DeclareExchange(ctx, binding.Exchange, binding.ExchangeKind); err != nil {
+			return nil, err
+		}
+	}
+
+	sub := &memSubscription{bus: b, queue: binding.Queue, binding: binding, handler: handler}
+	if sub.queue == "" {
+		sub.queue, sub.temp = "bus.gen-"+uuid.NewString(), true
+	}
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil, ErrClosed
+	}
+	q := b.declareQueue(sub.queue)
+	if binding.Exchange != DefaultExchange {
+		keys := binding.Keys
+		if len(keys) == 0 {
+			keys = []string{""}
+		}
+		for _, key := range keys {
+			b.bind(binding.Exchange, sub.queue, key)
+		}
+	}
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+
+	subCtx, cancel := context.WithCancel(ctx)
+	sub.cancel = cancel
+	for i := 0; i < binding.Workers; i++ {
+		sub.wg.Add(1)
+		go func() {
+			defer sub.wg.Done()
+			for {
+				d, ok := b.take(subCtx, q)
+				if !ok {
+					return
+				}
+				sub.handle(subCtx, q, d)
+			}
+		}()
+	}
+	// ctx 结束时释放订阅
+	go func() {
+		<-subCtx.Done()
+		_ = sub.Close()
+	}()
+	return sub, nil
+}
+
+// bind 相同的绑定只保留一个，调用方持有锁
+func (b *MemoryBus) bind(exchange, queue, key string) {
+	for _, bd := range b.bindings[exchange] {
+		if bd.queue == queue && bd.key == key {
+			return
+		}
+	}
+	b.bindings[exchange] = append(b.bindings[exchange], memBinding{queue: queue, key: key})
+}
+
+// handle 处理一条消息：成功确认；失败按 MaxAttempts 重投或进死信
+func (s *memSubscription) handle(ctx context.Context, q *memQueue, d *memDelivery) {
+	err := s.call(ctx, d.msg)
+	if err == nil {
+		s.bus.pending.Add(-1)
+		return
+	}
+	log.Printf("【busMng】handler error on %s: %v", q.name, err)
+
+	b := s.bus
+	if s.binding.MaxAttempts <= 0 {
+		// 与 Nack(requeue) 相同，立即重回队列
+		b.mu.Lock()
+		q.items = append(q.items, d)
+		q.signal()
+		b.mu.Unlock()
+		return
+	}
+
+	d.attempts++
+	if envelopeMng.IsPermanent(err) || d.attempts >= s.binding.MaxAttempts {
+		msg := cloneMessage(d.msg)
+		msg.Headers["x-attempts"] = fmt.Sprint(d.attempts)
+		msg.Headers["x-last-error"] = err.Error()
+		b.mu.Lock()
+		b.dead[q.name] = append(b.dead[q.name], msg)
+		b.mu.Unlock()
+		b.pending.Add(-1)
+		return
+	}
+
+	delays := s.binding.RetryDelays
+	delay := delays[len(delays)-1]
+	if d.attempts <= len(delays) {
+		delay = delays[d.attempts-1]
+	}
+	time.AfterFunc(delay, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if cur, ok := b.queues[q.name]; ok && cur == q {
+			q.items = append(q.items, d)
+			q.signal()
+			return
+		}
+		b.pending.Add(-1) // 队列已删除
+	})
+}
+
+// call 执行 handler，panic 视为失败
+func (s *memSubscription) call(ctx context.Context, msg *Message) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return s.handler(msg.Context(ctx), msg)
+}
+
+// -------END------subscribe----END---------
+
+// Request Bus
+func (b *MemoryBus) Request(ctx context.Context, exchange, routingKey string, msg *Message) (*Message, error) {
+	Prepare(msg)
+	replyQueue := "bus.reply-" + uuid.NewString()
+	msg.ReplyTo = replyQueue
+
+	b.mu.Lock()
+	q := b.declareQueue(replyQueue)
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		b.deleteQueue(replyQueue)
+		b.mu.Unlock()
+	}()
+
+	if err := b.Publish(ctx, exchange, routingKey, msg); err != nil {
+		return nil, err
+	}
+	for {
+		d, ok := b.take(ctx, q)
+		if !ok {
+			return nil, ctx.Err()
+		}
+		b.pending.Add(-1)
+		if d.msg.CorrelationID == msg.ID {
+			return d.msg, nil
+		}
+	}
+}
+
+// Reply Bus
+func (b *MemoryBus) Reply(ctx context.Context, request *Message, reply *Message) error {
+	if request.ReplyTo == "" {
+		return errors.New("busMng: request has no reply-to")
+	}
+	PrepareReply(request, reply)
+	return b.Publish(ctx, DefaultExchange, request.ReplyTo, reply)
+}
+
+// Close Bus
+func (b *MemoryBus) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	subs := make([]*memSubscription, 0, len(b.subs))
+	for sub := range b.subs {
+		subs = append(subs, sub)
+	}
+	b.mu.Unlock()
+
+	for _, sub := range subs {
+		_ = sub.Close()
+	}
+	return nil
+}
+
+// Flush 等待所有已发布（含延迟、重试中）的消息处理完毕；有消息停留在无人消费的队列时直到 ctx 结束
+func (b *MemoryBus) Flush(ctx context.Context) error {
+	ticker := time.NewTicker(5 * time.Millisecond)
+	defer ticker.Stop()
+	for b.pending.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// DeadLetters 队列超过最大尝试次数或返回 Permanent 错误的消息，头中带 x-attempts / x-last-error
+func (b *MemoryBus) DeadLetters(queue string) []*Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]*Message(nil), b.dead[queue]...)
+}
+
+// Len 队列中等待消费的消息数
+func (b *MemoryBus) Len(queue string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if q, ok := b.queues[queue]; ok {
+		return len(q.items)
+	}
+	return 0
+}
+
+// cloneMessage 每个队列持有独立的消息，Body 共享（视为只读）
+func cloneMessage(msg *Message) *Message {
+	c := *msg
+	c.Headers = make(map[string]string, len(msg.Headers))
+	for k, v := range msg.Headers {
+		c.Headers[k] = v
+	}
+	return &c
+}
+
+// topicMatch RabbitMQ topic 匹配：* 匹配一个词，# 匹配零或多个词
+func topicMatch(pattern, key string) bool {
+	return matchWords(strings.Split(pattern, "."), strings.Split(key, "."))
+}
+
+func matchWords(pattern, words []string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case "#":
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(words); i++ {
+				if matchWords(pattern[1:], words[i:]) {
+					return true
+				}
+			}
+			return false
+		case "*":
+			if len(words) == 0 {
+				return false
+			}
+		default:
+			if len(words) == 0 || words[0] != pattern[0] {
+				return false
+			}
+		}
+		pattern, words = pattern[1:], words[1:]
+	}
+	return len(words) == 0
+}
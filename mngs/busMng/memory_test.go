@@ -0,0 +1,295 @@
+package busMng
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wiidz/goutil/mngs/envelopeMng"
+)
+
+func TestTopicMatch(t *testing.T) {
+	tests := []struct {
+		pattern, key string
+		want         bool
+	}{
+		{"order.created", "order.created", true},
+		{"order.created", "order.paid", false},
+		{"order.*", "order.created", true},
+		{"order.*", "order", false},
+		{"order.*", "order.created.v2", false},
+		{"*.created", "order.created", true},
+		{"*", "", true},
+		{"#", "", true},
+		{"#", "order.created.v2", true},
+		{"order.#", "order", true},
+		{"order.#", "order.created.v2", true},
+		{"order.#", "user.created", false},
+		{"#.created", "created", true},
+		{"#.created", "shop.order.created", true},
+		{"#.created", "order.created.v2", false},
+		{"order.#.v2", "order.v2", true},
+		{"order.#.v2", "order.created.paid.v2", true},
+		{"*.#.v2", "order.v2", true},
+		{"#.*", "", true}, // 空 key 视为一个空词，与 RabbitMQ 一致
+		{"#.*", "order", true},
+		{"", "", true},
+		{"", "order", false},
+	}
+	for _, tt := range tests {
+		if got := topicMatch(tt.pattern, tt.key); got != tt.want {
+			t.Errorf("topicMatch(%q, %q) = %v, want %v", tt.pattern, tt.key, got, tt.want)
+		}
+	}
+}
+
+// collector 按队列记录收到的消息
+type collector struct {
+	mu  sync.Mutex
+	got map[string][]string
+}
+
+func newCollector() *collector {
+	return &collector{got: map[string][]string{}}
+}
+
+func (c *collector) handler(name string) Handler {
+	return func(ctx context.Context, msg *Message) error {
+		c.mu.Lock()
+		c.got[name] = append(c.got[name], string(msg.Body))
+		c.mu.Unlock()
+		return nil
+	}
+}
+
+func (c *collector) of(name string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.got[name]
+}
+
+func flush(t *testing.T, b *MemoryBus) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := b.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+}
+
+func TestMemoryBusRouting(t *testing.T) {
+	ctx := context.Background()
+	b := NewMemoryBus()
+	defer b.Close()
+	c := newCollector()
+
+	subs := []struct {
+		name    string
+		binding Binding
+	}{
+		{"direct", Binding{Exchange: "d", Queue: "direct", Keys: []string{"a", "b"}}},
+		{"topic", Binding{Exchange: "t", ExchangeKind: Topic, Queue: "topic", Keys: []string{"order.*", "order.#"}}},
+		{"fanout1", Binding{Exchange: "f", ExchangeKind: Fanout, Queue: "fanout1", Keys: []string{"ignored"}}},
+		{"fanout2", Binding{Exchange: "f", ExchangeKind: Fanout, Queue: "fanout2"}},
+		{"default", Binding{Queue: "default"}},
+	}
+	for _, s := range subs {
+		if _, err := b.Subscribe(ctx, s.binding, c.handler(s.name)); err != nil {
+			t.Fatalf("subscribe %s: %v", s.name, err)
+		}
+	}
+
+	publish := []struct{ exchange, key, body string }{
+		{"d", "a", "d-a"},
+		{"d", "c", "d-c"},
+		{"t", "order.created", "t-created"},
+		{"t", "user.created", "t-user"},
+		{"f", "x", "f-x"},
+		{DefaultExchange, "default", "q-default"},
+		{DefaultExchange, "missing", "q-missing"},
+	}
+	for _, p := range publish {
+		if err := b.Publish(ctx, p.exchange, p.key, &Message{Body: []byte(p.body)}); err != nil {
+			t.Fatalf("publish %s/%s: %v", p.exchange, p.key, err)
+		}
+	}
+	if err := b.Publish(ctx, "nope", "", &Message{}); err == nil {
+		t.Error("publish to undeclared exchange: want error")
+	}
+	flush(t, b)
+
+	want := map[string][]string{
+		"direct":  {"d-a"},
+		"topic":   {"t-created"}, // 两个绑定都匹配也只投递一次
+		"fanout1": {"f-x"},
+		"fanout2": {"f-x"},
+		"default": {"q-default"},
+	}
+	for name, w := range want {
+		if got := c.of(name); len(got) != len(w) || (len(w) > 0 && got[0] != w[0]) {
+			t.Errorf("%s got %v, want %v", name, got, w)
+		}
+	}
+}
+
+func TestMemoryBusRetry(t *testing.T) {
+	ctx := context.Background()
+	delays := []time.Duration{time.Millisecond, 2 * time.Millisecond}
+	errBoom := errors.New("boom")
+
+	tests := []struct {
+		name         string
+		maxAttempts  int
+		failures     int   // 前几次返回错误
+		err          error // 返回的错误
+		wantCalls    int32
+		wantDead     bool
+		wantAttempts string
+	}{
+		{"succeeds after retries", 3, 2, errBoom, 3, false, ""},
+		{"exhausted", 3, 100, errBoom, 3, true, "3"},
+		{"permanent", 3, 100, envelopeMng.Permanent(errBoom), 1, true, "1"},
+		{"single attempt", 1, 100, errBoom, 1, true, "1"},
+		{"requeue without max attempts", 0, 4, errBoom, 5, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewMemoryBus()
+			defer b.Close()
+			var calls atomic.Int32
+			_, err := b.Subscribe(ctx, Binding{Queue: "jobs", MaxAttempts: tt.maxAttempts, RetryDelays: delays},
+				func(ctx context.Context, msg *Message) error {
+					if calls.Add(1) <= int32(tt.failures) {
+						return tt.err
+					}
+					return nil
+				})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = b.Publish(ctx, DefaultExchange, "jobs", &Message{Body: []byte("x")}); err != nil {
+				t.Fatal(err)
+			}
+			flush(t, b)
+
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", got, tt.wantCalls)
+			}
+			dead := b.DeadLetters("jobs")
+			if (len(dead) == 1) != tt.wantDead || len(dead) > 1 {
+				t.Fatalf("dead letters = %d, want dead %v", len(dead), tt.wantDead)
+			}
+			if tt.wantDead {
+				if dead[0].Headers["x-attempts"] != tt.wantAttempts || dead[0].Headers["x-last-error"] != "boom" {
+					t.Errorf("dead letter headers = %v", dead[0].Headers)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryBusFlush(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("delayed", func(t *testing.T) {
+		b := NewMemoryBus()
+		defer b.Close()
+		c := newCollector()
+		if _, err := b.Subscribe(ctx, Binding{Queue: "q"}, c.handler("q")); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.PublishDelayed(ctx, DefaultExchange, "q", &Message{Body: []byte("later")}, 20*time.Millisecond); err != nil {
+			t.Fatal(err)
+		}
+		flush(t, b)
+		if got := c.of("q"); len(got) != 1 {
+			t.Errorf("got %v, want one delayed message", got)
+		}
+	})
+
+	t.Run("unconsumed queue blocks", func(t *testing.T) {
+		b := NewMemoryBus()
+		defer b.Close()
+		sub, err := b.Subscribe(ctx, Binding{Queue: "idle"}, newCollector().handler("idle"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = sub.Close()
+		if err = b.Publish(ctx, DefaultExchange, "idle", &Message{}); err != nil {
+			t.Fatal(err)
+		}
+		if n := b.Len("idle"); n != 1 {
+			t.Fatalf("Len = %d, want 1", n)
+		}
+		short, cancel := context.WithTimeout(ctx, 30*time.Millisecond)
+		defer cancel()
+		if err = b.Flush(short); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Flush = %v, want deadline exceeded", err)
+		}
+	})
+
+	t.Run("temp queue close releases pending", func(t *testing.T) {
+		b := NewMemoryBus()
+		defer b.Close()
+		if err := b.DeclareExchange(ctx, "ev", Fanout); err != nil {
+			t.Fatal(err)
+		}
+		started := make(chan struct{}, 3)
+		sub, err := b.Subscribe(ctx, Binding{Exchange: "ev", ExchangeKind: Fanout, MaxAttempts: 3, RetryDelays: []time.Duration{time.Hour}},
+			func(ctx context.Context, msg *Message) error {
+				started <- struct{}{}
+				<-ctx.Done() // 订阅关闭时失败
+				return errors.New("retry later")
+			})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 3; i++ {
+			if err = b.Publish(ctx, "ev", "", &Message{}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		<-started
+		_ = sub.Close()
+		// 处理中的消息进入一小时后的重试，删除队列只释放排队中的消息
+		if got := b.pending.Load(); got != 1 {
+			t.Errorf("pending = %d, want 1 (scheduled retry)", got)
+		}
+	})
+
+	t.Run("request reply", func(t *testing.T) {
+		b := NewMemoryBus()
+		defer b.Close()
+		_, err := b.Subscribe(ctx, Binding{Queue: "rpc"}, func(ctx context.Context, msg *Message) error {
+			return b.Reply(ctx, msg, &Message{Body: append([]byte("re:"), msg.Body...)})
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		reqCtx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		reply, err := b.Request(reqCtx, DefaultExchange, "rpc", &Message{Body: []byte("ping")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(reply.Body) != "re:ping" {
+			t.Errorf("reply = %q", reply.Body)
+		}
+		flush(t, b)
+	})
+}
+
+func TestMemoryBusClosed(t *testing.T) {
+	ctx := context.Background()
+	b := NewMemoryBus()
+	_ = b.Close()
+	if err := b.Publish(ctx, DefaultExchange, "q", &Message{}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Publish = %v, want ErrClosed", err)
+	}
+	if _, err := b.Subscribe(ctx, Binding{Queue: "q"}, func(context.Context, *Message) error { return nil }); !errors.Is(err, ErrClosed) {
+		t.Errorf("Subscribe = %v, want ErrClosed", err)
+	}
+}
@@ -0,0 +1,234 @@
+package cacheMng
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// ErrNotFound loader 返回该错误表示数据不存在，结果会按 NegativeTTL 缓存，避免穿透
+var ErrNotFound = errors.New("cacheMng: not found")
+
+// Loader 缓存未命中时的回源函数
+type Loader[T any] func(ctx context.Context) (T, error)
+
+// Option 缓存行为配置
+type Option func(*options)
+
+type options struct {
+	ttl          time.Duration // 正常数据的缓存时间
+	negativeTTL  time.Duration // 不存在数据的缓存时间，0 表示不缓存
+	jitter       float64       // 过期时间随机抖动比例，避免同时过期
+	earlyRefresh float64       // 剩余时间低于 ttl*earlyRefresh 时后台提前刷新，0 表示关闭
+	loadTimeout  time.Duration // 单次回源超时，回源不受发起请求的 ctx 取消影响
+}
+
+// WithTTL 指定缓存时间（默认 10 分钟）
+func WithTTL(ttl time.Duration) Option {
+	return func(opts *options) {
+		if ttl > 0 {
+			opts.ttl = ttl
+		}
+	}
+}
+
+// WithNegativeTTL 指定不存在结果的缓存时间（默认 30 秒，0 表示不缓存）
+func WithNegativeTTL(ttl time.Duration) Option {
+	return func(opts *options) {
+		if ttl >= 0 {
+			opts.negativeTTL = ttl
+		}
+	}
+}
+
+// WithJitter 指定过期时间抖动比例，例如 0.1 表示在 ttl 基础上随机增加 0~10%（默认 0.1）
+func WithJitter(ratio float64) Option {
+	return func(opts *options) {
+		if ratio >= 0 {
+			opts.jitter = ratio
+		}
+	}
+}
+
+// WithEarlyRefresh 开启热点键提前刷新，例如 0.2 表示剩余时间不足 20% 时命中即触发后台刷新
+func WithEarlyRefresh(ratio float64) Option {
+	return func(opts *options) {
+		if ratio >= 0 && ratio < 1 {
+			opts.earlyRefresh = ratio
+		}
+	}
+}
+
+// WithLoadTimeout 指定单次回源超时（默认 30 秒）；
+// 回源由同一 key 的所有并发请求共享，因此不随某个调用方的 ctx 取消，只受该超时限制
+func WithLoadTimeout(timeout time.Duration) Option {
+	return func(opts *options) {
+		if timeout > 0 {
+			opts.loadTimeout = timeout
+		}
+	}
+}
+
+// CacheMng cache-aside 管理器，底层可以是 redisMng 或 memoryMng
+type CacheMng struct {
+	Store Store
+	opts  options
+	group singleflight.Group
+}
+
+// entry 缓存中实际保存的内容
+type entry struct {
+	Value     json.RawMessage `json:"v,omitempty"`
+	Miss      bool            `json:"m,omitempty"` // 回源确认不存在
+	RefreshAt int64           `json:"r,omitempty"` // 提前刷新时间点（毫秒时间戳）
+}
+
+// NewCacheMng 返回一个 cache-aside 管理器
+func NewCacheMng(store Store, opts ...Option) *CacheMng {
+	mng := &CacheMng{
+		Store: store,
+		opts: options{
+			ttl:         10 * time.Minute,
+			negativeTTL: 30 * time.Second,
+			jitter:      0.1,
+			loadTimeout: 30 * time.Second,
+		},
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&mng.opts)
+		}
+	}
+	return mng
+}
+
+// GetOrLoad 先读缓存，未命中时回源并写入缓存
+// 同一个 key 的并发未命中只会回源一次；opts 可覆盖管理器的默认配置
+func GetOrLoad[T any](ctx context.Context, mng *CacheMng, key string, loader Loader[T], opts ...Option) (value T, err error) {
+	cfg := mng.buildOptions(opts...)
+
+	//【1】读缓存
+	raw, exist, err := mng.Store.Get(ctx, key)
+	if err != nil {
+		log.Println("cacheMng get err", key, err)
+	}
+	if err == nil && exist {
+		var e entry
+		if json.Unmarshal([]byte(raw), &e) == nil {
+			if e.Miss {
+				return value, ErrNotFound
+			}
+			if json.Unmarshal(e.Value, &value) == nil {
+				if e.RefreshAt > 0 && time.Now().UnixMilli() >= e.RefreshAt {
+					go refresh(context.WithoutCancel(ctx), mng, key, loader, cfg)
+				}
+				return value, nil
+			}
+		}
+	}
+
+	//【2】回源，合并并发请求；回源使用独立的 ctx，避免首个调用方取消时其余等待者一起失败
+	ch := mng.group.DoChan(key, func() (interface{}, error) {
+		return load(context.WithoutCancel(ctx), mng, key, loader, cfg)
+	})
+	select {
+	case <-ctx.Done():
+		return value, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return value, res.Err
+		}
+		if res.Val == nil {
+			return value, nil // T 为接口或指针类型时的零值
+		}
+		v, ok := res.Val.(T)
+		if !ok {
+			// 同一个 key 被不同类型的 GetOrLoad 共用
+			return value, fmt.Errorf("cacheMng: key %q loaded as %T, want %T", key, res.Val, value)
+		}
+		return v, nil
+	}
+}
+
+// Invalidate 删除缓存
+func (mng *CacheMng) Invalidate(ctx context.Context, key string) error {
+	return mng.Store.Delete(ctx, key)
+}
+
+// refresh 后台提前刷新，同一个 key 同时只刷新一次
+func refresh[T any](ctx context.Context, mng *CacheMng, key string, loader Loader[T], cfg options) {
+	_, err, _ := mng.group.Do("refresh:"+key, func() (interface{}, error) {
+		return load(ctx, mng, key, loader, cfg)
+	})
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		log.Println("cacheMng refresh err", key, err)
+	}
+}
+
+// load 回源并写入缓存
+func load[T any](ctx context.Context, mng *CacheMng, key string, loader Loader[T], cfg options) (value T, err error) {
+	ctx, cancel := context.WithTimeout(ctx, cfg.loadTimeout)
+	defer cancel()
+	value, err = loader(ctx)
+	if errors.Is(err, ErrNotFound) {
+		if cfg.negativeTTL > 0 {
+			mng.save(ctx, key, entry{Miss: true}, cfg.negativeTTL)
+		}
+		return value, ErrNotFound
+	}
+	if err != nil {
+		return value, err
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value, err
+	}
+	ttl := cfg.jitteredTTL()
+	e := entry{Value: data}
+	if cfg.earlyRefresh > 0 {
+		e.RefreshAt = time.Now().Add(time.Duration(float64(ttl) * (1 - cfg.earlyRefresh))).UnixMilli()
+	}
+	mng.save(ctx, key, e, ttl)
+	return value, nil
+}
+
+// save 写入缓存，失败只记录日志，不影响本次返回
+func (mng *CacheMng) save(ctx context.Context, key string, e entry, ttl time.Duration) {
+	data, err := json.Marshal(e)
+	if err == nil {
+		err = mng.Store.Set(ctx, key, string(data), ttl)
+	}
+	if err != nil {
+		log.Println("cacheMng set err", key, err)
+	}
+}
+
+// buildOptions 合并默认配置与单次调用配置
+func (mng *CacheMng) buildOptions(opts ...Option) options {
+	cfg := mng.opts
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&cfg)
+		}
+	}
+	return cfg
+}
+
+// jitteredTTL 在 ttl 基础上增加随机抖动
+func (cfg options) jitteredTTL() time.Duration {
+	if cfg.jitter <= 0 {
+		return cfg.ttl
+	}
+	maxJitter := int64(float64(cfg.ttl) * cfg.jitter)
+	if maxJitter <= 0 {
+		return cfg.ttl
+	}
+	return cfg.ttl + time.Duration(rand.Int63n(maxJitter))
+}
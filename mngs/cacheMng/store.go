@@ -0,0 +1,77 @@
+package cacheMng
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+	"github.com/wiidz/goutil/mngs/memoryMng"
+	"github.com/wiidz/goutil/mngs/redisMng"
+)
+
+// Store 缓存存储，GetOrLoad 通过它读写 redisMng 或 memoryMng
+type Store interface {
+	Get(ctx context.Context, key string) (value string, exist bool, err error)
+	Set(ctx context.Context, key, value string, expire time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
+
+// RedisStore 基于 redisMng 的存储
+type RedisStore struct {
+	RedisMng *redisMng.RedisMng
+}
+
+// NewRedisStore 返回 redis 存储
+func NewRedisStore(redisM *redisMng.RedisMng) *RedisStore {
+	return &RedisStore{RedisMng: redisM}
+}
+
+// Get 读取
+func (s *RedisStore) Get(ctx context.Context, key string) (string, bool, error) {
+	val, err := s.RedisMng.Client.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return val, true, nil
+}
+
+// Set 写入
+func (s *RedisStore) Set(ctx context.Context, key, value string, expire time.Duration) error {
+	return s.RedisMng.Set(ctx, key, value, expire)
+}
+
+// Delete 删除
+func (s *RedisStore) Delete(ctx context.Context, key string) error {
+	return s.RedisMng.Client.Del(ctx, key).Err()
+}
+
+// MemoryStore 基于 memoryMng 的存储
+type MemoryStore struct {
+	MemoryMng *memoryMng.MemoryMng
+}
+
+// NewMemoryStore 返回内存存储
+func NewMemoryStore(memoryM *memoryMng.MemoryMng) *MemoryStore {
+	return &MemoryStore{MemoryMng: memoryM}
+}
+
+// Get 读取
+func (s *MemoryStore) Get(_ context.Context, key string) (string, bool, error) {
+	val, exist := s.MemoryMng.GetString(key)
+	return val, exist, nil
+}
+
+// Set 写入
+func (s *MemoryStore) Set(_ context.Context, key, value string, expire time.Duration) error {
+	s.MemoryMng.Set(key, value, expire)
+	return nil
+}
+
+// Delete 删除
+func (s *MemoryStore) Delete(_ context.Context, key string) error {
+	s.MemoryMng.Delete(key)
+	return nil
+}
@@ -0,0 +1,161 @@
+package cacheMng
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+	"github.com/google/uuid"
+	"github.com/wiidz/goutil/mngs/memoryMng"
+	"github.com/wiidz/goutil/mngs/redisMng"
+)
+
+// TieredStore 两级缓存：L1 为进程内 memoryMng，L2 为 redisMng
+// 写入与删除会通过 redis 频道广播失效消息，其他副本收到后删除各自的 L1
+type TieredStore struct {
+	L1 *memoryMng.MemoryMng
+	L2 *redisMng.RedisMng
+
+	channel    string        // 失效消息频道
+	l1TTL      time.Duration // L1 最长缓存时间，兜底丢失的失效消息
+	instanceID string        // 本实例标识，忽略自己发出的消息
+
+	pubsub *redis.PubSub
+	done   chan struct{}
+}
+
+// TieredOption 两级缓存配置
+type TieredOption func(*TieredStore)
+
+// WithInvalidateChannel 指定失效消息频道（默认 cache:invalidate）
+func WithInvalidateChannel(channel string) TieredOption {
+	return func(s *TieredStore) {
+		if channel != "" {
+			s.channel = channel
+		}
+	}
+}
+
+// WithL1TTL 指定 L1 最长缓存时间（默认 1 分钟）
+func WithL1TTL(ttl time.Duration) TieredOption {
+	return func(s *TieredStore) {
+		if ttl > 0 {
+			s.l1TTL = ttl
+		}
+	}
+}
+
+// invalidateMsg 失效消息
+type invalidateMsg struct {
+	Instance string   `json:"instance"`
+	Keys     []string `json:"keys"`
+}
+
+// NewTieredStore 返回两级缓存，并开始订阅失效消息
+func NewTieredStore(ctx context.Context, l1 *memoryMng.MemoryMng, l2 *redisMng.RedisMng, opts ...TieredOption) (*TieredStore, error) {
+	s := &TieredStore{
+		L1:         l1,
+		L2:         l2,
+		channel:    "cache:invalidate",
+		l1TTL:      time.Minute,
+		instanceID: uuid.NewString(),
+		done:       make(chan struct{}),
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(s)
+		}
+	}
+
+	s.pubsub = l2.Client.Subscribe(ctx, s.channel)
+	if _, err := s.pubsub.Receive(ctx); err != nil {
+		_ = s.pubsub.Close()
+		return nil, err
+	}
+	go s.listen()
+	return s, nil
+}
+
+// Get 先读 L1，未命中再读 L2 并回填 L1
+func (s *TieredStore) Get(ctx context.Context, key string) (string, bool, error) {
+	if val, exist := s.L1.GetString(key); exist {
+		return val, true, nil
+	}
+
+	pipe := s.L2.Client.Pipeline()
+	getCmd := pipe.Get(ctx, key)
+	ttlCmd := pipe.PTTL(ctx, key)
+	_, err := pipe.Exec(ctx)
+	if err == redis.Nil {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	val := getCmd.Val()
+	s.L1.Set(key, val, s.l1Expire(ttlCmd.Val()))
+	return val, true, nil
+}
+
+// Set 写入 L2 与 L1，并通知其他副本失效
+func (s *TieredStore) Set(ctx context.Context, key, value string, expire time.Duration) error {
+	if err := s.L2.Set(ctx, key, value, expire); err != nil {
+		return err
+	}
+	s.L1.Set(key, value, s.l1Expire(expire))
+	return s.publish(ctx, key)
+}
+
+// Delete 删除 L2 与 L1，并通知其他副本失效
+func (s *TieredStore) Delete(ctx context.Context, key string) error {
+	if err := s.L2.Client.Del(ctx, key).Err(); err != nil {
+		return err
+	}
+	s.L1.Delete(key)
+	return s.publish(ctx, key)
+}
+
+// Close 停止订阅
+func (s *TieredStore) Close() error {
+	err := s.pubsub.Close()
+	<-s.done
+	return err
+}
+
+// publish 广播失效消息
+func (s *TieredStore) publish(ctx context.Context, keys ...string) error {
+	data, err := json.Marshal(invalidateMsg{Instance: s.instanceID, Keys: keys})
+	if err != nil {
+		return err
+	}
+	return s.L2.Client.Publish(ctx, s.channel, data).Err()
+}
+
+// listen 处理其他副本发来的失效消息
+func (s *TieredStore) listen() {
+	defer close(s.done)
+	for msg := range s.pubsub.Channel() {
+		var m invalidateMsg
+		if err := json.Unmarshal([]byte(msg.Payload), &m); err != nil {
+			log.Println("cacheMng invalidate msg err", err)
+			continue
+		}
+		if m.Instance == s.instanceID {
+			continue
+		}
+		for _, key := range m.Keys {
+			s.L1.Delete(key)
+		}
+	}
+}
+
+// l1Expire L1 缓存时间不超过 L2 剩余时间与 l1TTL
+func (s *TieredStore) l1Expire(l2TTL time.Duration) time.Duration {
+	if l2TTL > 0 && l2TTL < s.l1TTL {
+		return l2TTL
+	}
+	return s.l1TTL
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/wiidz/goutil/mngs/repoMng"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
@@ -86,16 +87,8 @@ func (r *CalendarDayRepo) Upsert(ctx context.Context, day *CalendarDay) error {
 
 // BatchUpsert 批量插入/更新
 func (r *CalendarDayRepo) BatchUpsert(ctx context.Context, days []*CalendarDay) error {
-	if len(days) == 0 {
-		return nil
-	}
-	return r.db.WithContext(ctx).
-		Table(r.table).
-		Clauses(clause.OnConflict{
-			Columns:   []clause.Column{{Name: "date"}},
-			UpdateAll: true,
-		}).
-		Create(&days).Error
+	repo := repoMng.RepoOf[CalendarDay](r.db.Table(r.table).Session(&gorm.Session{}))
+	return repo.Upsert(ctx, days, repoMng.OnConflict("date"))
 }
 
 func (r *CalendarDayRepo) GetByDate(ctx context.Context, date string) (*CalendarDay, error) {
@@ -3,6 +3,7 @@ package captchaMng
 import (
 	"context"
 	"errors"
+	"github.com/go-redis/redis/v9"
 	cp "github.com/mojocn/base64Captcha"
 	"github.com/wiidz/goutil/helpers/mathHelper"
 	"github.com/wiidz/goutil/helpers/strHelper"
@@ -13,13 +14,22 @@ import (
 	"image/color"
 	"log"
 	"strings"
+	"sync"
 	"time"
 )
 
+// numberCaptchaTTL 数字验证码有效期
+const numberCaptchaTTL = time.Second * 300
+
 type CaptchaMng struct {
 	DataSource dataSourceStruct.DataSource
 	RedisMng   *redisMng.RedisMng
 	MemoryMng  *memoryMng.MemoryMng
+
+	NumberPolicy *NumberCaptchaPolicy // 数字验证码频率限制，为空时不限制
+
+	challengerMu sync.RWMutex
+	challengers  map[ChallengeType]Challenger // 已注册的题型，见 RegisterChallenger
 }
 
 func NewCaptchaMngRedis(redisM *redisMng.RedisMng) (*CaptchaMng, error) {
@@ -68,33 +78,55 @@ func (mng *CaptchaMng) GenerateNumberGraphCaptcha(width, height, noiseCount, len
 }
 
 // GetNumberCaptcha 获取数字验证码
+// 设置了 NumberPolicy 时，触发冷却、每日上限或锁定会返回 *LimitError
 func (mng *CaptchaMng) GetNumberCaptcha(ctx context.Context, identify string) (id, captchaStr string, err error) {
 
+	if err = mng.checkRequestLimit(ctx, identify); err != nil {
+		return
+	}
+
 	captcha := mathHelper.GetRandomInt(100000, 999999) // 默认六位
 	captchaStr = typeHelper.Int2Str(captcha)
 	id = strHelper.GetRandomString(10)
 
-	_ = mng.SetCache(ctx, identify+id, captchaStr, time.Second*300) // 300秒有效
+	_ = mng.SetCache(ctx, identify+id, captchaStr, numberCaptchaTTL) // 300秒有效
 	return
 }
 
 // VerifyNumberCaptcha 验证数字验证码
 func (mng *CaptchaMng) VerifyNumberCaptcha(ctx context.Context, identifyKey, id, captchaStr string) (err error) {
 
+	if err = mng.checkLocked(ctx, identifyKey); err != nil {
+		return
+	}
+
+	// 只对已下发的验证码计数，伪造的 id 不会消耗次数或触发锁定
 	keyName := identifyKey + id
 	captchaCache, err := mng.GetCache(ctx, keyName)
 	if err != nil {
 		return
 	}
 	if captchaCache == "" {
-		return errors.New("验证码已失效")
+		return ErrCaptchaExpired
+	}
+
+	last, err := mng.reserveAttempt(ctx, identifyKey, keyName)
+	if err != nil {
+		return
 	}
 
 	if captchaCache != captchaStr {
-		return errors.New("验证码错误")
+		if last {
+			return mng.exhaustAttempts(ctx, identifyKey, keyName)
+		}
+		return ErrCaptchaWrong
 	}
 
-	_ = mng.SetCache(ctx, keyName, "0", 0)
+	// 原子取走，并发的正确请求只有一个能通过
+	if taken, err := mng.takeCache(ctx, keyName); err != nil || taken != captchaStr {
+		return ErrCaptchaExpired
+	}
+	_ = mng.DelCache(ctx, "captcha:number:attempt:"+keyName)
 
 	return nil
 }
@@ -127,3 +159,31 @@ func (mng *CaptchaMng) GetCache(ctx context.Context, keyName string) (string, er
 	}
 	return "", errors.New("未知数据源")
 }
+
+// DelCache 删除缓存
+func (mng *CaptchaMng) DelCache(ctx context.Context, keyName string) error {
+	if mng.DataSource == dataSourceStruct.Redis {
+		_, err := mng.RedisMng.Del(ctx, keyName)
+		return err
+	} else if mng.DataSource == dataSourceStruct.Memory {
+		mng.MemoryMng.Delete(keyName)
+		return nil
+	}
+	return errors.New("未知数据源")
+}
+
+// takeCache 原子地读取并删除缓存，键不存在时返回空字符串
+func (mng *CaptchaMng) takeCache(ctx context.Context, keyName string) (string, error) {
+	if mng.DataSource == dataSourceStruct.Redis {
+		value, err := mng.RedisMng.Client.GetDel(ctx, keyName).Result()
+		if errors.Is(err, redis.Nil) {
+			return "", nil
+		}
+		return value, err
+	} else if mng.DataSource == dataSourceStruct.Memory {
+		value, _ := mng.MemoryMng.Take(keyName)
+		str, _ := value.(string)
+		return str, nil
+	}
+	return "", errors.New("未知数据源")
+}
@@ -0,0 +1,186 @@
+package captchaMng
+
+import (
+	"context"
+	"errors"
+	"image/color"
+	"strings"
+	"time"
+
+	cp "github.com/mojocn/base64Captcha"
+	"github.com/wiidz/goutil/helpers/strHelper"
+)
+
+// ChallengeType 验证码类型
+type ChallengeType string
+
+const (
+	GraphChallenge  ChallengeType = "graph"  // 图形字符
+	AudioChallenge  ChallengeType = "audio"  // 语音数字
+	MathChallenge   ChallengeType = "math"   // 算术题
+	SliderChallenge ChallengeType = "slider" // 滑块拼图
+)
+
+// challengeTTL 验证码有效期
+const challengeTTL = time.Second * 300
+
+var (
+	// ErrUnknownChallenge 未注册的验证码类型
+	ErrUnknownChallenge = errors.New("未知的验证码类型")
+	// ErrChallengeExpired 验证码不存在或已失效，与 ErrCaptchaExpired 相同
+	ErrChallengeExpired = ErrCaptchaExpired
+	// ErrChallengeWrong 验证码错误，与 ErrCaptchaWrong 相同
+	ErrChallengeWrong = ErrCaptchaWrong
+)
+
+// Challenge 下发给前端的验证码
+type Challenge struct {
+	ID      string                 `json:"id"`
+	Type    ChallengeType          `json:"type"`
+	Content string                 `json:"content"`         // base64 图片或音频（含 data URI 前缀）
+	Extra   map[string]interface{} `json:"extra,omitempty"` // 类型相关的附加数据，例如滑块的拼图块与纵坐标
+}
+
+// Challenger 验证码题型，负责出题与判题，答案的存储由 CaptchaMng 负责
+type Challenger interface {
+	Type() ChallengeType
+	// Generate 生成题目及答案
+	Generate() (challenge *Challenge, answer string, err error)
+	// Verify 判断用户输入是否与答案匹配
+	Verify(answer, input string) bool
+}
+
+// RegisterChallenger 注册或替换某种题型
+func (mng *CaptchaMng) RegisterChallenger(challenger Challenger) {
+	mng.challengerMu.Lock()
+	defer mng.challengerMu.Unlock()
+	if mng.challengers == nil {
+		mng.challengers = map[ChallengeType]Challenger{}
+	}
+	mng.challengers[challenger.Type()] = challenger
+}
+
+// NewChallenge 生成指定类型的验证码，答案保存在 redis 或内存中
+func (mng *CaptchaMng) NewChallenge(ctx context.Context, challengeType ChallengeType) (*Challenge, error) {
+	challenger, err := mng.getChallenger(challengeType)
+	if err != nil {
+		return nil, err
+	}
+
+	challenge, answer, err := challenger.Generate()
+	if err != nil {
+		return nil, err
+	}
+	challenge.ID = strHelper.GetRandomString(16)
+	challenge.Type = challengeType
+
+	if err = mng.SetCache(ctx, challengeKey(challengeType, challenge.ID), answer, challengeTTL); err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// VerifyChallenge 校验验证码，无论对错都只能校验一次
+func (mng *CaptchaMng) VerifyChallenge(ctx context.Context, challengeType ChallengeType, id, input string) error {
+	challenger, err := mng.getChallenger(challengeType)
+	if err != nil {
+		return err
+	}
+
+	// 读取与删除原子完成，并发请求只有一个能拿到答案
+	answer, err := mng.takeCache(ctx, challengeKey(challengeType, id))
+	if err != nil || answer == "" {
+		return ErrCaptchaExpired
+	}
+
+	if !challenger.Verify(answer, input) {
+		return ErrCaptchaWrong
+	}
+	return nil
+}
+
+// getChallenger 读取已注册的题型，未注册时使用默认配置
+func (mng *CaptchaMng) getChallenger(challengeType ChallengeType) (Challenger, error) {
+	mng.challengerMu.RLock()
+	challenger, ok := mng.challengers[challengeType]
+	mng.challengerMu.RUnlock()
+	if ok {
+		return challenger, nil
+	}
+
+	switch challengeType {
+	case GraphChallenge:
+		challenger = NewGraphChallenger(240, 80, 20, 4, false)
+	case AudioChallenge:
+		challenger = NewAudioChallenger(6, "zh")
+	case MathChallenge:
+		challenger = NewMathChallenger(240, 80, 20)
+	case SliderChallenge:
+		challenger = NewSliderChallenger(nil)
+	default:
+		return nil, ErrUnknownChallenge
+	}
+	mng.RegisterChallenger(challenger)
+	return challenger, nil
+}
+
+func challengeKey(challengeType ChallengeType, id string) string {
+	return "captcha:" + string(challengeType) + ":" + id
+}
+
+// -------BEGIN------基于 base64Captcha 驱动的题型-----BEGIN--------
+
+// driverChallenger 包装 base64Captcha 的驱动
+type driverChallenger struct {
+	challengeType ChallengeType
+	driver        cp.Driver
+}
+
+// NewGraphChallenger 图形字符验证码，numberOnly 为 true 时只包含数字
+func NewGraphChallenger(width, height, noiseCount, length int, numberOnly bool) Challenger {
+	source := cp.TxtSimpleCharaters
+	if numberOnly {
+		source = cp.TxtNumbers
+	}
+	return &driverChallenger{
+		challengeType: GraphChallenge,
+		driver: cp.NewDriverString(height, width, noiseCount, cp.OptionShowHollowLine,
+			length, source, &color.RGBA{254, 254, 254, 254}, []string{"Flim-Flam.ttf"}),
+	}
+}
+
+// NewAudioChallenger 语音数字验证码，language 可选 en / ja / ru / zh
+func NewAudioChallenger(length int, language string) Challenger {
+	return &driverChallenger{
+		challengeType: AudioChallenge,
+		driver:        cp.NewDriverAudio(length, language),
+	}
+}
+
+// NewMathChallenger 算术验证码，答案为计算结果
+func NewMathChallenger(width, height, noiseCount int) Challenger {
+	return &driverChallenger{
+		challengeType: MathChallenge,
+		driver: cp.NewDriverMath(height, width, noiseCount, cp.OptionShowHollowLine,
+			&color.RGBA{254, 254, 254, 254}, []string{"wqy-microhei.ttc"}),
+	}
+}
+
+func (c *driverChallenger) Type() ChallengeType {
+	return c.challengeType
+}
+
+func (c *driverChallenger) Generate() (*Challenge, string, error) {
+	_, question, answer := c.driver.GenerateIdQuestionAnswer()
+	item, err := c.driver.DrawCaptcha(question)
+	if err != nil {
+		return nil, "", err
+	}
+	return &Challenge{Content: item.EncodeB64string()}, answer, nil
+}
+
+func (c *driverChallenger) Verify(answer, input string) bool {
+	return strings.ToLower(strings.TrimSpace(input)) == strings.ToLower(answer)
+}
+
+// -------END------基于 base64Captcha 驱动的题型----END---------
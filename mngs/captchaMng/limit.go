@@ -0,0 +1,176 @@
+package captchaMng
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/wiidz/goutil/mngs/memoryMng"
+	"github.com/wiidz/goutil/structs/dataSourceStruct"
+)
+
+var (
+	// ErrCaptchaExpired 验证码不存在或已失效
+	ErrCaptchaExpired = errors.New("验证码已失效")
+	// ErrCaptchaWrong 验证码错误
+	ErrCaptchaWrong = errors.New("验证码错误")
+	// ErrCaptchaCooldown 获取过于频繁，需等待冷却
+	ErrCaptchaCooldown = errors.New("验证码获取过于频繁，请稍后再试")
+	// ErrCaptchaDailyLimit 今日获取次数已达上限
+	ErrCaptchaDailyLimit = errors.New("今日验证码获取次数已达上限")
+	// ErrCaptchaTooManyAttempts 猜错次数过多，验证码已作废
+	ErrCaptchaTooManyAttempts = errors.New("验证码错误次数过多，请重新获取")
+	// ErrCaptchaLocked 标识已被锁定
+	ErrCaptchaLocked = errors.New("验证码错误次数过多，请稍后再试")
+)
+
+// LimitError 触发频率限制时返回，可用 errors.Is 判断具体原因
+type LimitError struct {
+	Err        error         // 上面定义的 ErrCaptcha* 之一
+	RetryAfter time.Duration // 距离可再次尝试的时间，未知时为 0
+}
+
+func (e *LimitError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("%s（%d秒后可重试）", e.Err.Error(), int64(e.RetryAfter.Seconds()+0.5))
+	}
+	return e.Err.Error()
+}
+
+func (e *LimitError) Unwrap() error {
+	return e.Err
+}
+
+// NumberCaptchaPolicy 数字验证码的频率限制，字段为 0 表示不限制
+type NumberCaptchaPolicy struct {
+	ResendCooldown time.Duration // 同一标识两次获取的最小间隔
+	DailyLimit     int64         // 同一标识每天最多获取次数
+	MaxAttempts    int64         // 同一个验证码最多猜错次数，达到后验证码作废
+	LockDuration   time.Duration // 猜错达到上限后锁定该标识的时长
+}
+
+// SetNumberCaptchaPolicy 设置数字验证码的频率限制
+func (mng *CaptchaMng) SetNumberCaptchaPolicy(policy *NumberCaptchaPolicy) {
+	mng.NumberPolicy = policy
+}
+
+// checkRequestLimit 获取验证码前检查锁定、冷却与每日上限
+func (mng *CaptchaMng) checkRequestLimit(ctx context.Context, identify string) error {
+	policy := mng.NumberPolicy
+	if policy == nil {
+		return nil
+	}
+
+	if err := mng.checkLocked(ctx, identify); err != nil {
+		return err
+	}
+
+	if policy.ResendCooldown > 0 {
+		ok, err := mng.setCacheNX(ctx, "captcha:number:cooldown:"+identify, policy.ResendCooldown)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return &LimitError{Err: ErrCaptchaCooldown, RetryAfter: mng.cacheTTL(ctx, "captcha:number:cooldown:"+identify)}
+		}
+	}
+
+	if policy.DailyLimit > 0 {
+		now := time.Now()
+		tomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+		count, err := mng.incrCache(ctx, "captcha:number:daily:"+identify+":"+now.Format("20060102"), tomorrow.Sub(now)+time.Minute)
+		if err != nil {
+			return err
+		}
+		if count > policy.DailyLimit {
+			return &LimitError{Err: ErrCaptchaDailyLimit, RetryAfter: tomorrow.Sub(now)}
+		}
+	}
+	return nil
+}
+
+// reserveAttempt 比对前先占用一次尝试次数，并发猜测也无法超出上限；
+// 返回本次是否为最后一次机会，已超出上限时作废验证码并返回 *LimitError
+func (mng *CaptchaMng) reserveAttempt(ctx context.Context, identify, keyName string) (last bool, err error) {
+	policy := mng.NumberPolicy
+	if policy == nil || policy.MaxAttempts <= 0 {
+		return false, nil
+	}
+
+	count, err := mng.incrCache(ctx, "captcha:number:attempt:"+keyName, numberCaptchaTTL)
+	if err != nil {
+		return false, err
+	}
+	if count > policy.MaxAttempts {
+		return false, mng.exhaustAttempts(ctx, identify, keyName)
+	}
+	return count == policy.MaxAttempts, nil
+}
+
+// exhaustAttempts 猜错达到上限，作废验证码并按配置锁定标识
+func (mng *CaptchaMng) exhaustAttempts(ctx context.Context, identify, keyName string) error {
+	policy := mng.NumberPolicy
+	_ = mng.DelCache(ctx, keyName)
+	_ = mng.DelCache(ctx, "captcha:number:attempt:"+keyName)
+	if policy.LockDuration > 0 {
+		_ = mng.SetCache(ctx, "captcha:number:lock:"+identify, "1", policy.LockDuration)
+		return &LimitError{Err: ErrCaptchaTooManyAttempts, RetryAfter: policy.LockDuration}
+	}
+	return &LimitError{Err: ErrCaptchaTooManyAttempts}
+}
+
+// checkLocked 标识是否处于锁定期
+func (mng *CaptchaMng) checkLocked(ctx context.Context, identify string) error {
+	if mng.NumberPolicy == nil || mng.NumberPolicy.LockDuration <= 0 {
+		return nil
+	}
+	keyName := "captcha:number:lock:" + identify
+	locked, _ := mng.GetCache(ctx, keyName)
+	if locked == "" {
+		return nil
+	}
+	return &LimitError{Err: ErrCaptchaLocked, RetryAfter: mng.cacheTTL(ctx, keyName)}
+}
+
+// incrCache 计数器自增，首次创建时设置过期时间
+func (mng *CaptchaMng) incrCache(ctx context.Context, keyName string, expire time.Duration) (int64, error) {
+	if mng.DataSource == dataSourceStruct.Redis {
+		return mng.RedisMng.IncrWithExpire(ctx, keyName, expire)
+	} else if mng.DataSource == dataSourceStruct.Memory {
+		if _, loaded, err := memoryMng.GetOrSet[int64](mng.MemoryMng, keyName, 1, expire); err != nil || !loaded {
+			return 1, err
+		}
+		return memoryMng.Increment[int64](mng.MemoryMng, keyName, 1)
+	}
+	return 0, errors.New("未知数据源")
+}
+
+// setCacheNX 键不存在时写入，返回是否写入成功
+func (mng *CaptchaMng) setCacheNX(ctx context.Context, keyName string, expire time.Duration) (bool, error) {
+	if mng.DataSource == dataSourceStruct.Redis {
+		return mng.RedisMng.Client.SetNX(ctx, keyName, "1", expire).Result()
+	} else if mng.DataSource == dataSourceStruct.Memory {
+		_, loaded, err := memoryMng.GetOrSet(mng.MemoryMng, keyName, "1", expire)
+		return !loaded, err
+	}
+	return false, errors.New("未知数据源")
+}
+
+// cacheTTL 键的剩余有效期，无法获取时返回 0
+func (mng *CaptchaMng) cacheTTL(ctx context.Context, keyName string) time.Duration {
+	if mng.DataSource == dataSourceStruct.Redis {
+		ttl, err := mng.RedisMng.TTL(ctx, keyName)
+		if err != nil || ttl < 0 {
+			return 0
+		}
+		return ttl
+	} else if mng.DataSource == dataSourceStruct.Memory {
+		_, expiration, found := mng.MemoryMng.Client.GetWithExpiration(keyName)
+		if !found || expiration.IsZero() {
+			return 0
+		}
+		return time.Until(expiration)
+	}
+	return 0
+}
@@ -0,0 +1,189 @@
+package captchaMng
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"github.com/fogleman/gg"
+)
+
+// SliderConfig 滑块拼图配置
+type SliderConfig struct {
+	Width       int           // 背景宽度，默认 300
+	Height      int           // 背景高度，默认 150
+	PieceSize   int           // 拼图块边长，默认 44
+	Tolerance   float64       // 允许的横向误差像素，默认 5
+	Backgrounds []image.Image // 自定义背景图，为空时随机绘制
+}
+
+// sliderChallenger 滑块拼图：答案为缺口左上角横坐标，前端提交拖动后的横坐标
+type sliderChallenger struct {
+	config SliderConfig
+}
+
+// NewSliderChallenger 滑块拼图验证码
+func NewSliderChallenger(config *SliderConfig) Challenger {
+	cfg := SliderConfig{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.Width <= 0 {
+		cfg.Width = 300
+	}
+	if cfg.Height <= 0 {
+		cfg.Height = 150
+	}
+	if cfg.PieceSize <= 0 {
+		cfg.PieceSize = 44
+	}
+	if cfg.Tolerance <= 0 {
+		cfg.Tolerance = 5
+	}
+	return &sliderChallenger{config: cfg}
+}
+
+func (c *sliderChallenger) Type() ChallengeType {
+	return SliderChallenge
+}
+
+// Generate 返回挖去缺口的背景图（Content）与拼图块（Extra.piece），拼图块纵坐标在 Extra.y 中
+func (c *sliderChallenger) Generate() (*Challenge, string, error) {
+	cfg := c.config
+	size := cfg.PieceSize
+
+	//【1】背景
+	bg := c.background()
+
+	//【2】随机缺口位置，横向至少留出一个拼图块的拖动距离
+	minX := size + 10
+	maxX := cfg.Width - size - 10
+	if maxX <= minX {
+		maxX = minX + 1
+	}
+	x := minX + rand.Intn(maxX-minX)
+	y := 10 + rand.Intn(max(cfg.Height-size-20, 1))
+
+	//【3】抠出拼图块，并将背景上的缺口压暗
+	mask := pieceMask(size)
+	piece := image.NewRGBA(image.Rect(0, 0, size, size))
+	for py := 0; py < size; py++ {
+		for px := 0; px < size; px++ {
+			if !mask[py][px] {
+				continue
+			}
+			src := bg.RGBAAt(x+px, y+py)
+			piece.SetRGBA(px, py, src)
+			bg.SetRGBA(x+px, y+py, color.RGBA{
+				R: src.R / 3, G: src.G / 3, B: src.B / 3, A: 255,
+			})
+		}
+	}
+
+	bgB64, err := encodePNG(bg)
+	if err != nil {
+		return nil, "", err
+	}
+	pieceB64, err := encodePNG(piece)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &Challenge{
+		Content: bgB64,
+		Extra: map[string]interface{}{
+			"piece":      pieceB64,
+			"y":          y,
+			"piece_size": size,
+			"width":      cfg.Width,
+			"height":     cfg.Height,
+		},
+	}, strconv.Itoa(x), nil
+}
+
+// Verify 横坐标误差在容忍范围内即通过
+func (c *sliderChallenger) Verify(answer, input string) bool {
+	expected, err := strconv.ParseFloat(answer, 64)
+	if err != nil {
+		return false
+	}
+	actual, err := strconv.ParseFloat(strings.TrimSpace(input), 64)
+	if err != nil {
+		return false
+	}
+	return math.Abs(expected-actual) <= c.config.Tolerance
+}
+
+// background 选取自定义背景或随机绘制
+func (c *sliderChallenger) background() *image.RGBA {
+	cfg := c.config
+	rect := image.Rect(0, 0, cfg.Width, cfg.Height)
+	bg := image.NewRGBA(rect)
+
+	if len(cfg.Backgrounds) > 0 {
+		src := cfg.Backgrounds[rand.Intn(len(cfg.Backgrounds))]
+		draw.Draw(bg, rect, src, src.Bounds().Min, draw.Src)
+		return bg
+	}
+
+	dc := gg.NewContext(cfg.Width, cfg.Height)
+	grad := gg.NewLinearGradient(0, 0, float64(cfg.Width), float64(cfg.Height))
+	grad.AddColorStop(0, randomColor(255))
+	grad.AddColorStop(1, randomColor(255))
+	dc.SetFillStyle(grad)
+	dc.DrawRectangle(0, 0, float64(cfg.Width), float64(cfg.Height))
+	dc.Fill()
+	for i := 0; i < 12; i++ {
+		dc.SetColor(randomColor(160))
+		dc.DrawCircle(rand.Float64()*float64(cfg.Width), rand.Float64()*float64(cfg.Height), 8+rand.Float64()*30)
+		dc.Fill()
+	}
+	draw.Draw(bg, rect, dc.Image(), image.Point{}, draw.Src)
+	return bg
+}
+
+// pieceMask 拼图块形状：主体方块 + 上方与右侧各一个半圆凸起
+func pieceMask(size int) [][]bool {
+	radius := float64(size) / 6
+	body := int(radius)
+	bodyMax := size - int(radius)
+	topX, topY := float64(body+bodyMax)/2, float64(body)
+	rightX, rightY := float64(bodyMax), float64(body+bodyMax)/2
+
+	mask := make([][]bool, size)
+	for y := 0; y < size; y++ {
+		mask[y] = make([]bool, size)
+		for x := 0; x < size; x++ {
+			fx, fy := float64(x)+0.5, float64(y)+0.5
+			inBody := x >= 0 && x < bodyMax && y >= body && y < size
+			inTop := math.Hypot(fx-topX, fy-topY) <= radius
+			inRight := math.Hypot(fx-rightX, fy-rightY) <= radius
+			mask[y][x] = inBody || inTop || inRight
+		}
+	}
+	return mask
+}
+
+func randomColor(alpha uint8) color.RGBA {
+	return color.RGBA{
+		R: uint8(60 + rand.Intn(180)),
+		G: uint8(60 + rand.Intn(180)),
+		B: uint8(60 + rand.Intn(180)),
+		A: alpha,
+	}
+}
+
+func encodePNG(img image.Image) (string, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return "", err
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
@@ -0,0 +1,108 @@
+package envelopeMng
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/ugorji/go/codec"
+	"google.golang.org/protobuf/proto"
+)
+
+// Codec 消息体编解码，按 ContentType 注册
+type Codec interface {
+	ContentType() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+var (
+	codecMu sync.RWMutex
+	codecs  = map[string]Codec{}
+)
+
+func init() {
+	RegisterCodec(JSON)
+	RegisterCodec(Protobuf)
+	RegisterCodec(Msgpack)
+}
+
+// RegisterCodec 注册编解码器，ContentType 相同的会被覆盖
+func RegisterCodec(c Codec) {
+	codecMu.Lock()
+	codecs[c.ContentType()] = c
+	codecMu.Unlock()
+}
+
+// CodecFor 按 ContentType 查找编解码器，空 ContentType 视为 JSON
+func CodecFor(contentType string) (Codec, error) {
+	if contentType == "" {
+		return JSON, nil
+	}
+	codecMu.RLock()
+	c, ok := codecs[contentType]
+	codecMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("envelopeMng: no codec for content type %q", contentType)
+	}
+	return c, nil
+}
+
+// 内置编解码器
+var (
+	JSON     Codec = jsonCodec{}
+	Protobuf Codec = protoCodec{}
+	Msgpack  Codec = msgpackCodec{handle: &codec.MsgpackHandle{}}
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string                        { return "application/json" }
+func (jsonCodec) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+// protoCodec v 需为 proto.Message；解码时也接受指向 nil 消息指针的指针（Consumer[*pb.Foo] 的情况）
+type protoCodec struct{}
+
+func (protoCodec) ContentType() string { return "application/x-protobuf" }
+
+func (protoCodec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("envelopeMng: %T is not a proto.Message", v)
+	}
+	return proto.Marshal(m)
+}
+
+func (protoCodec) Unmarshal(data []byte, v interface{}) error {
+	if m, ok := v.(proto.Message); ok {
+		return proto.Unmarshal(data, m)
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && rv.Elem().Kind() == reflect.Ptr {
+		elem := rv.Elem()
+		if elem.IsNil() {
+			elem.Set(reflect.New(elem.Type().Elem()))
+		}
+		if m, ok := elem.Interface().(proto.Message); ok {
+			return proto.Unmarshal(data, m)
+		}
+	}
+	return fmt.Errorf("envelopeMng: %T is not a proto.Message", v)
+}
+
+type msgpackCodec struct {
+	handle *codec.MsgpackHandle
+}
+
+func (msgpackCodec) ContentType() string { return "application/msgpack" }
+
+func (c msgpackCodec) Marshal(v interface{}) (data []byte, err error) {
+	err = codec.NewEncoderBytes(&data, c.handle).Encode(v)
+	return
+}
+
+func (c msgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	return codec.NewDecoderBytes(data, c.handle).Decode(v)
+}
@@ -0,0 +1,96 @@
+package envelopeMng
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/streadway/amqp"
+)
+
+// Handler 类型化处理函数，ctx 中带有消息的关联ID与追踪头
+type Handler[T any] func(ctx context.Context, env *Envelope, payload T) error
+
+// Consumer 类型化消费者，按消息的 ContentType 选择 Codec 解码
+type Consumer[T any] struct {
+	handler Handler[T]
+}
+
+// NewConsumer 创建消费者
+func NewConsumer[T any](handler Handler[T]) *Consumer[T] {
+	return &Consumer[T]{handler: handler}
+}
+
+// Handle 可直接作为 amqpMng.RabbitMQ.Consume / rabbitMngOld.Consumer.Start 的处理函数
+func (c *Consumer[T]) Handle(d amqp.Delivery) error {
+	return c.HandleEnvelope(context.Background(), FromDelivery(d))
+}
+
+// HandleEnvelope 解码信封并调用处理函数，解码失败返回 Permanent 错误
+func (c *Consumer[T]) HandleEnvelope(ctx context.Context, env *Envelope) error {
+	payload, err := Decode[T](env)
+	if err != nil {
+		return Permanent(err) // 无法解码的消息重试无意义
+	}
+	return c.handler(env.Context(ctx), env, payload)
+}
+
+// Decode 按信封的 ContentType 解码消息体
+func Decode[T any](env *Envelope) (T, error) {
+	var payload T
+	codec, err := CodecFor(env.ContentType)
+	if err != nil {
+		return payload, err
+	}
+	if err = codec.Unmarshal(env.Body, &payload); err != nil {
+		return payload, fmt.Errorf("envelopeMng: decode %s: %w", env.Type, err)
+	}
+	return payload, nil
+}
+
+// Router 按消息类型分发到不同的处理函数，用 On 登记
+type Router struct {
+	mu       sync.RWMutex
+	handlers map[string]func(ctx context.Context, env *Envelope) error
+	fallback func(ctx context.Context, env *Envelope) error
+}
+
+// NewRouter 创建分发器
+func NewRouter() *Router {
+	return &Router{handlers: map[string]func(ctx context.Context, env *Envelope) error{}}
+}
+
+// On 登记 msgType 的处理函数（Go 不支持泛型方法，故为函数）
+func On[T any](r *Router, msgType string, handler Handler[T]) {
+	c := NewConsumer(handler)
+	r.mu.Lock()
+	r.handlers[msgType] = c.HandleEnvelope
+	r.mu.Unlock()
+}
+
+// Fallback 未登记类型的处理函数；不设置时未知类型返回 Permanent 错误（启用死信时直接进死信）
+func (r *Router) Fallback(fn func(ctx context.Context, env *Envelope) error) {
+	r.mu.Lock()
+	r.fallback = fn
+	r.mu.Unlock()
+}
+
+// Handle 可直接作为 amqpMng.RabbitMQ.Consume / rabbitMngOld.Consumer.Start 的处理函数
+func (r *Router) Handle(d amqp.Delivery) error {
+	return r.HandleEnvelope(context.Background(), FromDelivery(d))
+}
+
+// HandleEnvelope 按 env.Type 分发
+func (r *Router) HandleEnvelope(ctx context.Context, env *Envelope) error {
+	r.mu.RLock()
+	handler, ok := r.handlers[env.Type]
+	fallback := r.fallback
+	r.mu.RUnlock()
+	if ok {
+		return handler(ctx, env)
+	}
+	if fallback != nil {
+		return fallback(env.Context(ctx), env)
+	}
+	return Permanent(fmt.Errorf("envelopeMng: no handler for message type %q", env.Type))
+}
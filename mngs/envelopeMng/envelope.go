@@ -0,0 +1,152 @@
+package envelopeMng
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+)
+
+// 链路追踪头（W3C Trace Context）
+const (
+	HeaderTraceParent = "traceparent"
+	HeaderTraceState  = "tracestate"
+)
+
+// Envelope 标准消息信封，字段映射到 amqp 消息属性：
+// ID→MessageId、Type→Type、Timestamp→Timestamp、CorrelationID→CorrelationId、Headers→Headers
+type Envelope struct {
+	ID            string            `json:"id"`
+	Type          string            `json:"type"`           // 消息类型，Router 据此分发
+	Timestamp     time.Time         `json:"timestamp"`      // 产生时间
+	CorrelationID string            `json:"correlation_id"` // 关联ID，同一业务链路上的消息相同
+	ReplyTo       string            `json:"reply_to,omitempty"`
+	ContentType   string            `json:"content_type"` // 决定解码用的 Codec
+	Headers       map[string]string `json:"headers,omitempty"`
+	Body          []byte            `json:"-"`
+}
+
+// NewEnvelope 生成ID与时间戳，关联ID与追踪头取自 ctx
+func NewEnvelope(ctx context.Context, msgType string) *Envelope {
+	env := &Envelope{
+		ID:        uuid.NewString(),
+		Type:      msgType,
+		Timestamp: time.Now(),
+		Headers:   map[string]string{},
+	}
+	env.CorrelationID = CorrelationIDFromContext(ctx)
+	if env.CorrelationID == "" {
+		env.CorrelationID = env.ID
+	}
+	for k, v := range TraceHeadersFromContext(ctx) {
+		env.Headers[k] = v
+	}
+	return env
+}
+
+// Publishing 转为 amqp 消息
+func (env *Envelope) Publishing() amqp.Publishing {
+	headers := amqp.Table{}
+	for k, v := range env.Headers {
+		headers[k] = v
+	}
+	return amqp.Publishing{
+		Headers:       headers,
+		ContentType:   env.ContentType,
+		DeliveryMode:  amqp.Persistent,
+		CorrelationId: env.CorrelationID,
+		ReplyTo:       env.ReplyTo,
+		MessageId:     env.ID,
+		Timestamp:     env.Timestamp,
+		Type:          env.Type,
+		Body:          env.Body,
+	}
+}
+
+// FromDelivery 从 amqp 消息还原信封，非字符串的头（如 x-attempts）会被忽略
+func FromDelivery(d amqp.Delivery) *Envelope {
+	env := &Envelope{
+		ID:            d.MessageId,
+		Type:          d.Type,
+		Timestamp:     d.Timestamp,
+		CorrelationID: d.CorrelationId,
+		ReplyTo:       d.ReplyTo,
+		ContentType:   d.ContentType,
+		Headers:       map[string]string{},
+		Body:          d.Body,
+	}
+	for k, v := range d.Headers {
+		if s, ok := v.(string); ok {
+			env.Headers[k] = s
+		}
+	}
+	return env
+}
+
+// FromPublishing 从待发送的 amqp 消息还原信封
+func FromPublishing(pub amqp.Publishing) *Envelope {
+	return FromDelivery(amqp.Delivery{
+		Headers:       pub.Headers,
+		ContentType:   pub.ContentType,
+		CorrelationId: pub.CorrelationId,
+		ReplyTo:       pub.ReplyTo,
+		MessageId:     pub.MessageId,
+		Timestamp:     pub.Timestamp,
+		Type:          pub.Type,
+		Body:          pub.Body,
+	})
+}
+
+// Context 将信封的关联ID与追踪头写入 ctx，处理过程中再发布的消息会沿用
+func (env *Envelope) Context(ctx context.Context) context.Context {
+	if env.CorrelationID != "" {
+		ctx = ContextWithCorrelationID(ctx, env.CorrelationID)
+	}
+	trace := map[string]string{}
+	for _, k := range []string{HeaderTraceParent, HeaderTraceState} {
+		if v, ok := env.Headers[k]; ok {
+			trace[k] = v
+		}
+	}
+	if len(trace) > 0 {
+		ctx = ContextWithTraceHeaders(ctx, trace)
+	}
+	return ctx
+}
+
+// -------BEGIN------context-----BEGIN--------
+
+type correlationIDKey struct{}
+
+type traceHeadersKey struct{}
+
+// ContextWithCorrelationID 将关联ID写入 context
+func ContextWithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+// CorrelationIDFromContext 读取 context 中的关联ID
+func CorrelationIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(correlationIDKey{}).(string)
+	return id
+}
+
+// ContextWithTraceHeaders 将追踪头（traceparent / tracestate 等）写入 context
+func ContextWithTraceHeaders(ctx context.Context, headers map[string]string) context.Context {
+	return context.WithValue(ctx, traceHeadersKey{}, headers)
+}
+
+// TraceHeadersFromContext 读取 context 中的追踪头
+func TraceHeadersFromContext(ctx context.Context) map[string]string {
+	if ctx == nil {
+		return nil
+	}
+	headers, _ := ctx.Value(traceHeadersKey{}).(map[string]string)
+	return headers
+}
+
+// -------END------context----END---------
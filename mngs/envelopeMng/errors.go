@@ -0,0 +1,25 @@
+package envelopeMng
+
+import "errors"
+
+// permanentError 不可恢复的错误，重试无意义
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent 包装不可恢复的错误（毒消息）：amqpMng 启用重试时跳过剩余重试直接进死信，busMng 的内存实现同样处理
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent 是否为 Permanent 包装的错误
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
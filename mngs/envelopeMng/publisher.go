@@ -0,0 +1,79 @@
+package envelopeMng
+
+import (
+	"context"
+
+	"github.com/streadway/amqp"
+)
+
+// Sender 底层发送者，*amqpMng.RabbitMQ 已实现，rabbitMngOld 通过 Producer.Sender(routingKey) 适配
+type Sender interface {
+	PublishMessage(ctx context.Context, pub amqp.Publishing, reliable bool) error
+}
+
+// PublishOption 单次发布的附加设置
+type PublishOption func(env *Envelope)
+
+// WithHeader 附加消息头
+func WithHeader(key, value string) PublishOption {
+	return func(env *Envelope) { env.Headers[key] = value }
+}
+
+// WithCorrelationID 指定关联ID（默认取 ctx 中的值，没有时使用消息ID）
+func WithCorrelationID(id string) PublishOption {
+	return func(env *Envelope) { env.CorrelationID = id }
+}
+
+// WithReplyTo 指定回复队列
+func WithReplyTo(queue string) PublishOption {
+	return func(env *Envelope) { env.ReplyTo = queue }
+}
+
+// Publisher 类型化发布者，同一 Publisher 发布的消息 Type 相同
+type Publisher[T any] struct {
+	sender   Sender
+	msgType  string
+	codec    Codec
+	reliable bool
+}
+
+// NewPublisher 创建发布者，codec 为 nil 时使用 JSON，默认使用 publisher confirm
+func NewPublisher[T any](sender Sender, msgType string, codec Codec) *Publisher[T] {
+	if codec == nil {
+		codec = JSON
+	}
+	return &Publisher[T]{sender: sender, msgType: msgType, codec: codec, reliable: true}
+}
+
+// Reliable 是否等待 broker 确认
+func (p *Publisher[T]) Reliable(reliable bool) *Publisher[T] {
+	p.reliable = reliable
+	return p
+}
+
+// Envelope 编码 payload 并生成信封，不发送
+func (p *Publisher[T]) Envelope(ctx context.Context, payload T, opts ...PublishOption) (*Envelope, error) {
+	body, err := p.codec.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	env := NewEnvelope(ctx, p.msgType)
+	env.ContentType = p.codec.ContentType()
+	env.Body = body
+	for _, opt := range opts {
+		opt(env)
+	}
+	return env, nil
+}
+
+// Publish 编码并发布，返回已发送的信封
+func (p *Publisher[T]) Publish(ctx context.Context, payload T, opts ...PublishOption) (*Envelope, error) {
+	env, err := p.Envelope(ctx, payload, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err = p.sender.PublishMessage(ctx, env.Publishing(), p.reliable); err != nil {
+		return nil, err
+	}
+	return env, nil
+}
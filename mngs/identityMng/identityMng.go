@@ -119,13 +119,33 @@ func (mng *IdentityMng) LogoutByLoginID(_ context.Context, loginID string) error
 // 为兼容旧签名，可保留一个 Logout 代理到当前会话
 func (mng *IdentityMng) Logout(_ context.Context) error { return mng.LogoutCurrent(nil) }
 
-// CurrentLoginID 获取当前登录ID
-func (mng *IdentityMng) CurrentLoginID(_ context.Context) string {
+// CurrentLoginID 获取当前登录ID，优先取 ctx 中由 ContextWithLoginID 写入的值
+func (mng *IdentityMng) CurrentLoginID(ctx context.Context) string {
+	if id := LoginIDFromContext(ctx); id != "" {
+		return id
+	}
 	id, _ := stputil.GetLoginID("")
 	mng.dbg("current login id=%s", id)
 	return id
 }
 
+// loginIDKey 登录ID在 context 中的键
+type loginIDKey struct{}
+
+// ContextWithLoginID 将登录ID写入 context，通常在鉴权中间件校验 token 后调用
+func ContextWithLoginID(ctx context.Context, loginID string) context.Context {
+	return context.WithValue(ctx, loginIDKey{}, loginID)
+}
+
+// LoginIDFromContext 读取 context 中的登录ID，没有时返回空字符串
+func LoginIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(loginIDKey{}).(string)
+	return id
+}
+
 func (mng *IdentityMng) dbg(format string, args ...interface{}) {
 	if mng == nil || !mng.debug {
 		return
@@ -1,28 +1,86 @@
 package memoryMng
 
 import (
-	"github.com/patrickmn/go-cache"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/patrickmn/go-cache"
 )
 
-var cacheMng = cache.New(cache.DefaultExpiration, 0)
+var (
+	defaultMng     *MemoryMng
+	defaultMngOnce sync.Once
+)
 
 type MemoryMng struct {
 	Client *cache.Cache
+
+	opts    options
+	bounded bool // 是否设置了容量限制
+
+	mu      sync.Mutex
+	policy  evictionPolicy
+	sizes   map[string]int64       // 键 -> 估算字节数
+	bytes   int64                  // 当前估算字节数
+	pending map[string]EvictReason // 正在由本管理器删除的键及原因
+
+	takeMu sync.Mutex // 串行化 Take，保证同一个值只被取走一次
+
+	hits      int64
+	misses    int64
+	evictions int64
 }
 
-// NewCacheMng 获取系统缓存管理器
+// Stats 缓存统计
+type Stats struct {
+	Hits      int64 `json:"hits"`      // 命中次数
+	Misses    int64 `json:"misses"`    // 未命中次数
+	Evictions int64 `json:"evictions"` // 过期清理与容量淘汰次数
+	Entries   int   `json:"entries"`   // 当前键数量（含尚未清理的过期键）
+	Bytes     int64 `json:"bytes"`     // 当前估算字节数（仅限制容量时统计）
+}
+
+// NewCacheMng 获取系统缓存管理器（进程内共享同一个实例）
 func NewCacheMng() *MemoryMng {
-	var memoryMng = MemoryMng{
-		Client: cacheMng,
+	defaultMngOnce.Do(func() {
+		defaultMng = NewMemoryMng()
+	})
+	return defaultMng
+}
+
+// NewMemoryMng 创建一个独立的缓存管理器，键空间与其他实例隔离
+func NewMemoryMng(opts ...Option) *MemoryMng {
+	cfg := options{
+		janitorInterval: time.Minute,
+		policy:          LRU,
+		sizer:           defaultSizer,
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&cfg)
+		}
+	}
+
+	mng := &MemoryMng{
+		Client:  cache.New(cfg.defaultExpiration, cfg.janitorInterval),
+		opts:    cfg,
+		bounded: cfg.maxEntries > 0 || cfg.maxBytes > 0,
+		pending: make(map[string]EvictReason),
 	}
-	return &memoryMng
+	if mng.bounded {
+		mng.policy = newEvictionPolicy(cfg.policy)
+		mng.sizes = make(map[string]int64)
+	}
+	mng.Client.OnEvicted(mng.handleEvicted)
+	return mng
 }
 
 // Get 提取
 func (mng *MemoryMng) Get(keyName string) (data interface{}, isExist bool) {
 
 	data, isExist = mng.Client.Get(keyName)
+	mng.record(keyName, isExist)
 	return
 }
 
@@ -30,7 +88,7 @@ func (mng *MemoryMng) Get(keyName string) (data interface{}, isExist bool) {
 func (mng *MemoryMng) GetString(keyName string) (data string, isExist bool) {
 
 	var temp interface{}
-	temp, isExist = mng.Client.Get(keyName)
+	temp, isExist = mng.Get(keyName)
 	var ok bool
 	if data, ok = temp.(string); !ok {
 		return "", false
@@ -42,7 +100,7 @@ func (mng *MemoryMng) GetString(keyName string) (data string, isExist bool) {
 func (mng *MemoryMng) GetStringSlice(keyName string) (data []string, isExist bool) {
 
 	var temp interface{}
-	temp, isExist = mng.Client.Get(keyName)
+	temp, isExist = mng.Get(keyName)
 	var ok bool
 	if data, ok = temp.([]string); !ok {
 		return []string{}, false
@@ -54,9 +112,151 @@ func (mng *MemoryMng) GetStringSlice(keyName string) (data []string, isExist boo
 func (mng *MemoryMng) Set(keyName string, data interface{}, expire time.Duration) {
 
 	mng.Client.Set(keyName, data, expire)
+	mng.track(keyName, data)
+}
+
+// track 记录写入键的大小与访问顺序，超出容量时淘汰
+func (mng *MemoryMng) track(keyName string, data interface{}) {
+	if !mng.bounded {
+		return
+	}
+
+	//【1】记录大小与访问顺序，并挑出需要淘汰的键
+	mng.mu.Lock()
+	size := int64(len(keyName)) + mng.opts.sizer(data)
+	mng.bytes += size - mng.sizes[keyName]
+	mng.sizes[keyName] = size
+	mng.policy.add(keyName)
+	victims := mng.collectVictims(keyName)
+	mng.mu.Unlock()
+
+	//【2】淘汰
+	for _, key := range victims {
+		mng.remove(key, EvictCapacity)
+	}
 }
 
 // Delete 删除一个键
 func (mng *MemoryMng) Delete(keyName string) {
-	mng.Client.Delete(keyName)
+	mng.remove(keyName, EvictDeleted)
+}
+
+// Take 读取并删除一个键；并发的 Take 只有一个能取到值，适合一次性凭证
+func (mng *MemoryMng) Take(keyName string) (data interface{}, isExist bool) {
+	mng.takeMu.Lock()
+	defer mng.takeMu.Unlock()
+
+	data, isExist = mng.Client.Get(keyName)
+	mng.record(keyName, isExist)
+	if isExist {
+		mng.remove(keyName, EvictDeleted)
+	}
+	return
+}
+
+// Flush 清空全部键
+func (mng *MemoryMng) Flush() {
+	mng.Client.Flush()
+	if !mng.bounded {
+		return
+	}
+	mng.mu.Lock()
+	mng.policy = newEvictionPolicy(mng.opts.policy)
+	mng.sizes = make(map[string]int64)
+	mng.bytes = 0
+	mng.mu.Unlock()
+}
+
+// Stats 返回统计数据
+func (mng *MemoryMng) Stats() Stats {
+	mng.mu.Lock()
+	bytes := mng.bytes
+	mng.mu.Unlock()
+	return Stats{
+		Hits:      atomic.LoadInt64(&mng.hits),
+		Misses:    atomic.LoadInt64(&mng.misses),
+		Evictions: atomic.LoadInt64(&mng.evictions),
+		Entries:   mng.Client.ItemCount(),
+		Bytes:     bytes,
+	}
+}
+
+// record 记录命中情况，并更新访问顺序
+func (mng *MemoryMng) record(keyName string, hit bool) {
+	if !hit {
+		atomic.AddInt64(&mng.misses, 1)
+		return
+	}
+	atomic.AddInt64(&mng.hits, 1)
+	if mng.bounded {
+		mng.mu.Lock()
+		mng.policy.touch(keyName)
+		mng.mu.Unlock()
+	}
+}
+
+// collectVictims 超出容量时按策略选出需要淘汰的键（调用方持有锁），不会淘汰刚写入的键
+func (mng *MemoryMng) collectVictims(justSet string) (victims []string) {
+	for mng.overLimit() {
+		key, ok := mng.policy.victim()
+		if !ok || key == justSet {
+			break
+		}
+		mng.untrack(key)
+		victims = append(victims, key)
+	}
+	return
+}
+
+// overLimit 判断是否超出容量（调用方持有锁）
+func (mng *MemoryMng) overLimit() bool {
+	if mng.opts.maxEntries > 0 && len(mng.sizes) > mng.opts.maxEntries {
+		return true
+	}
+	return mng.opts.maxBytes > 0 && mng.bytes > mng.opts.maxBytes
+}
+
+// untrack 移除键的容量记录（调用方持有锁）
+func (mng *MemoryMng) untrack(key string) {
+	if !mng.bounded {
+		return
+	}
+	mng.policy.remove(key)
+	mng.bytes -= mng.sizes[key]
+	delete(mng.sizes, key)
+}
+
+// remove 删除键，并标记原因，供 handleEvicted 区分
+func (mng *MemoryMng) remove(key string, reason EvictReason) {
+	mng.mu.Lock()
+	mng.pending[key] = reason
+	mng.untrack(key)
+	mng.mu.Unlock()
+
+	mng.Client.Delete(key)
+
+	mng.mu.Lock()
+	delete(mng.pending, key)
+	mng.mu.Unlock()
+}
+
+// handleEvicted go-cache 的移除回调，覆盖主动删除、容量淘汰与过期清理
+func (mng *MemoryMng) handleEvicted(key string, value interface{}) {
+	mng.mu.Lock()
+	reason, ok := mng.pending[key]
+	if !ok {
+		reason = EvictExpired
+		// 清理期间同名键可能已被重新写入，此时保留容量记录
+		if _, _, found := mng.Client.GetWithExpiration(key); !found {
+			mng.untrack(key)
+		}
+	}
+	mng.mu.Unlock()
+
+	if reason != EvictDeleted {
+		atomic.AddInt64(&mng.evictions, 1)
+	}
+	if mng.opts.onEvicted != nil {
+		mng.opts.onEvicted(key, value, reason)
+	}
 }
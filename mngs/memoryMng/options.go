@@ -0,0 +1,106 @@
+package memoryMng
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// EvictReason 键被移除的原因
+type EvictReason int8
+
+const (
+	EvictExpired  EvictReason = 1 // 过期后被清理
+	EvictCapacity EvictReason = 2 // 超出容量被淘汰
+	EvictDeleted  EvictReason = 3 // 主动删除
+)
+
+// Option 内存缓存配置
+type Option func(*options)
+
+type options struct {
+	defaultExpiration time.Duration // Set 传 0 时的过期时间，0 表示永不过期
+	janitorInterval   time.Duration // 过期清理间隔，0 表示不清理
+	maxEntries        int           // 最大键数量，0 表示不限制
+	maxBytes          int64         // 最大估算字节数，0 表示不限制
+	policy            Policy
+	sizer             func(value interface{}) int64
+	onEvicted         func(key string, value interface{}, reason EvictReason)
+}
+
+// WithDefaultExpiration 指定默认过期时间
+func WithDefaultExpiration(expire time.Duration) Option {
+	return func(opts *options) {
+		opts.defaultExpiration = expire
+	}
+}
+
+// WithJanitorInterval 指定过期键的清理间隔（默认 1 分钟）
+func WithJanitorInterval(interval time.Duration) Option {
+	return func(opts *options) {
+		opts.janitorInterval = interval
+	}
+}
+
+// WithMaxEntries 限制最大键数量
+func WithMaxEntries(n int) Option {
+	return func(opts *options) {
+		if n > 0 {
+			opts.maxEntries = n
+		}
+	}
+}
+
+// WithMaxBytes 限制最大估算字节数，估算方式见 WithSizer
+func WithMaxBytes(n int64) Option {
+	return func(opts *options) {
+		if n > 0 {
+			opts.maxBytes = n
+		}
+	}
+}
+
+// WithPolicy 指定淘汰策略（默认 LRU）
+func WithPolicy(policy Policy) Option {
+	return func(opts *options) {
+		if policy == LRU || policy == LFU {
+			opts.policy = policy
+		}
+	}
+}
+
+// WithSizer 指定值大小的估算函数（默认按字符串长度或 json 编码长度估算）
+func WithSizer(sizer func(value interface{}) int64) Option {
+	return func(opts *options) {
+		if sizer != nil {
+			opts.sizer = sizer
+		}
+	}
+}
+
+// WithOnEvicted 指定键被移除时的回调
+func WithOnEvicted(fn func(key string, value interface{}, reason EvictReason)) Option {
+	return func(opts *options) {
+		opts.onEvicted = fn
+	}
+}
+
+// defaultSizer 默认大小估算
+func defaultSizer(value interface{}) int64 {
+	switch val := value.(type) {
+	case string:
+		return int64(len(val))
+	case []byte:
+		return int64(len(val))
+	case []string:
+		var n int64
+		for _, s := range val {
+			n += int64(len(s))
+		}
+		return n
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return 0
+	}
+	return int64(len(data))
+}
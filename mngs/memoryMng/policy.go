@@ -0,0 +1,153 @@
+package memoryMng
+
+import "container/list"
+
+// Policy 淘汰策略
+type Policy int8
+
+const (
+	LRU Policy = 1 // 淘汰最久未访问的键
+	LFU Policy = 2 // 淘汰访问次数最少的键，次数相同时淘汰最久未访问的
+)
+
+// evictionPolicy 记录访问顺序，选出下一个被淘汰的键（调用方负责加锁）
+type evictionPolicy interface {
+	add(key string)
+	touch(key string)
+	remove(key string)
+	victim() (key string, ok bool)
+}
+
+func newEvictionPolicy(policy Policy) evictionPolicy {
+	if policy == LFU {
+		return newLfuPolicy()
+	}
+	return newLruPolicy()
+}
+
+// lruPolicy 双向链表，表头为最近访问
+type lruPolicy struct {
+	ll    *list.List
+	index map[string]*list.Element
+}
+
+func newLruPolicy() *lruPolicy {
+	return &lruPolicy{ll: list.New(), index: make(map[string]*list.Element)}
+}
+
+func (p *lruPolicy) add(key string) {
+	if el, ok := p.index[key]; ok {
+		p.ll.MoveToFront(el)
+		return
+	}
+	p.index[key] = p.ll.PushFront(key)
+}
+
+func (p *lruPolicy) touch(key string) {
+	if el, ok := p.index[key]; ok {
+		p.ll.MoveToFront(el)
+	}
+}
+
+func (p *lruPolicy) remove(key string) {
+	if el, ok := p.index[key]; ok {
+		p.ll.Remove(el)
+		delete(p.index, key)
+	}
+}
+
+func (p *lruPolicy) victim() (string, bool) {
+	el := p.ll.Back()
+	if el == nil {
+		return "", false
+	}
+	return el.Value.(string), true
+}
+
+// lfuPolicy 按访问次数分桶，O(1) 选出淘汰键
+type lfuPolicy struct {
+	buckets map[int]*list.List // 访问次数 -> 键链表，表头为最近访问
+	index   map[string]*lfuEntry
+	minFreq int
+}
+
+type lfuEntry struct {
+	freq int
+	el   *list.Element
+}
+
+func newLfuPolicy() *lfuPolicy {
+	return &lfuPolicy{buckets: make(map[int]*list.List), index: make(map[string]*lfuEntry)}
+}
+
+func (p *lfuPolicy) add(key string) {
+	if _, ok := p.index[key]; ok {
+		p.touch(key)
+		return
+	}
+	p.index[key] = &lfuEntry{freq: 1, el: p.bucket(1).PushFront(key)}
+	p.minFreq = 1
+}
+
+func (p *lfuPolicy) touch(key string) {
+	entry, ok := p.index[key]
+	if !ok {
+		return
+	}
+	old := p.buckets[entry.freq]
+	old.Remove(entry.el)
+	if old.Len() == 0 {
+		delete(p.buckets, entry.freq)
+		if p.minFreq == entry.freq {
+			p.minFreq++
+		}
+	}
+	entry.freq++
+	entry.el = p.bucket(entry.freq).PushFront(key)
+}
+
+func (p *lfuPolicy) remove(key string) {
+	entry, ok := p.index[key]
+	if !ok {
+		return
+	}
+	bucket := p.buckets[entry.freq]
+	bucket.Remove(entry.el)
+	if bucket.Len() == 0 {
+		delete(p.buckets, entry.freq)
+	}
+	delete(p.index, key)
+	if entry.freq == p.minFreq {
+		p.resetMinFreq()
+	}
+}
+
+func (p *lfuPolicy) victim() (string, bool) {
+	bucket, ok := p.buckets[p.minFreq]
+	if !ok || bucket.Len() == 0 {
+		return "", false
+	}
+	return bucket.Back().Value.(string), true
+}
+
+func (p *lfuPolicy) bucket(freq int) *list.List {
+	bucket, ok := p.buckets[freq]
+	if !ok {
+		bucket = list.New()
+		p.buckets[freq] = bucket
+	}
+	return bucket
+}
+
+// resetMinFreq 删除键后重新计算最小访问次数
+func (p *lfuPolicy) resetMinFreq() {
+	if _, ok := p.buckets[p.minFreq]; ok {
+		return
+	}
+	p.minFreq = 0
+	for freq := range p.buckets {
+		if p.minFreq == 0 || freq < p.minFreq {
+			p.minFreq = freq
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package memoryMng
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+var (
+	// ErrNotFound 键不存在或已过期
+	ErrNotFound = errors.New("memoryMng: key not found")
+	// ErrTypeMismatch 键存在但值的类型与期望不符
+	ErrTypeMismatch = errors.New("memoryMng: type mismatch")
+	// ErrUnderflow 无符号数自减结果小于 0
+	ErrUnderflow = errors.New("memoryMng: unsigned underflow")
+)
+
+// Number 支持自增自减的数值类型
+type Number interface {
+	int | int8 | int16 | int32 | int64 |
+		uint | uint8 | uint16 | uint32 | uint64 | uintptr |
+		float32 | float64
+}
+
+// Get 按类型提取，区分键不存在（ErrNotFound）与类型不符（ErrTypeMismatch）
+func Get[T any](mng *MemoryMng, keyName string) (data T, err error) {
+	temp, isExist := mng.Get(keyName)
+	if !isExist {
+		return data, ErrNotFound
+	}
+	data, ok := temp.(T)
+	if !ok {
+		return data, fmt.Errorf("%w: %s is %T, not %T", ErrTypeMismatch, keyName, temp, data)
+	}
+	return data, nil
+}
+
+// GetOrSet 键存在时返回已有值（loaded 为 true），否则写入 value 并返回
+func GetOrSet[T any](mng *MemoryMng, keyName string, value T, expire time.Duration) (actual T, loaded bool, err error) {
+	for i := 0; i < 2; i++ {
+		if mng.Client.Add(keyName, value, expire) == nil {
+			mng.track(keyName, value)
+			atomic.AddInt64(&mng.misses, 1)
+			return value, false, nil
+		}
+		actual, err = Get[T](mng, keyName)
+		if !errors.Is(err, ErrNotFound) {
+			return actual, err == nil, err
+		}
+		// 读取前恰好过期或被删除，重试写入
+	}
+	return actual, false, err
+}
+
+// Increment 数值自增，键不存在时以 delta 为初始值创建（永不过期）
+func Increment[N Number](mng *MemoryMng, keyName string, delta N) (N, error) {
+	return incrementOrDecrement(mng, keyName, delta, false)
+}
+
+// Decrement 数值自减，键不存在时以 0-delta 为初始值创建（永不过期）；
+// 无符号类型结果小于 0 时不修改并返回 ErrUnderflow
+func Decrement[N Number](mng *MemoryMng, keyName string, delta N) (N, error) {
+	return incrementOrDecrement(mng, keyName, delta, true)
+}
+
+// Keys 返回前缀匹配且未过期的键，按字典序排列；prefix 为空时返回全部
+func (mng *MemoryMng) Keys(prefix string) []string {
+	items := mng.Client.Items()
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Items 返回未过期键值的快照，修改返回的 map 不影响缓存
+func (mng *MemoryMng) Items() map[string]interface{} {
+	items := mng.Client.Items()
+	res := make(map[string]interface{}, len(items))
+	for key, item := range items {
+		res[key] = item.Object
+	}
+	return res
+}
+
+// incrementOrDecrement 自增或自减的公共实现
+func incrementOrDecrement[N Number](mng *MemoryMng, keyName string, delta N, decrease bool) (N, error) {
+	// 无符号类型的 0-1 为最大值，据此判断是否会下溢
+	unsigned := N(0)-1 > 0
+	for i := 0; i < 2; i++ {
+		if _, found := mng.Client.Get(keyName); !found {
+			initial := delta
+			if decrease {
+				if unsigned && delta > 0 {
+					return 0, ErrUnderflow
+				}
+				initial = 0 - delta
+			}
+			if mng.Client.Add(keyName, initial, cache.NoExpiration) == nil {
+				mng.track(keyName, initial)
+				return initial, nil
+			}
+			continue
+		}
+
+		res, err := applyDelta(mng.Client, keyName, delta, decrease)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %v", ErrTypeMismatch, err)
+		}
+		// 回绕后 res+delta 会再次溢出而小于 res，加回 delta 撤销本次自减
+		if decrease && unsigned && res+delta < res {
+			_, _ = applyDelta(mng.Client, keyName, delta, false)
+			return 0, ErrUnderflow
+		}
+		return res, nil
+	}
+	return 0, ErrNotFound
+}
+
+// applyDelta 按具体类型调用 go-cache 的自增自减方法
+func applyDelta[N Number](c *cache.Cache, keyName string, delta N, decrease bool) (res N, err error) {
+	var out interface{}
+	switch d := any(delta).(type) {
+	case int:
+		out, err = pick(decrease, c.DecrementInt, c.IncrementInt)(keyName, d)
+	case int8:
+		out, err = pick(decrease, c.DecrementInt8, c.IncrementInt8)(keyName, d)
+	case int16:
+		out, err = pick(decrease, c.DecrementInt16, c.IncrementInt16)(keyName, d)
+	case int32:
+		out, err = pick(decrease, c.DecrementInt32, c.IncrementInt32)(keyName, d)
+	case int64:
+		out, err = pick(decrease, c.DecrementInt64, c.IncrementInt64)(keyName, d)
+	case uint:
+		out, err = pick(decrease, c.DecrementUint, c.IncrementUint)(keyName, d)
+	case uint8:
+		out, err = pick(decrease, c.DecrementUint8, c.IncrementUint8)(keyName, d)
+	case uint16:
+		out, err = pick(decrease, c.DecrementUint16, c.IncrementUint16)(keyName, d)
+	case uint32:
+		out, err = pick(decrease, c.DecrementUint32, c.IncrementUint32)(keyName, d)
+	case uint64:
+		out, err = pick(decrease, c.DecrementUint64, c.IncrementUint64)(keyName, d)
+	case uintptr:
+		out, err = pick(decrease, c.DecrementUintptr, c.IncrementUintptr)(keyName, d)
+	case float32:
+		out, err = pick(decrease, c.DecrementFloat32, c.IncrementFloat32)(keyName, d)
+	case float64:
+		out, err = pick(decrease, c.DecrementFloat64, c.IncrementFloat64)(keyName, d)
+	}
+	if err != nil {
+		return 0, err
+	}
+	res, _ = out.(N)
+	return res, nil
+}
+
+func pick[V any](decrease bool, dec, inc func(string, V) (V, error)) func(string, V) (V, error) {
+	if decrease {
+		return dec
+	}
+	return inc
+}
@@ -0,0 +1,108 @@
+package migrateMng
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"time"
+)
+
+// lock 获取跨实例的迁移锁，多个副本同时启动时只有一个会执行迁移
+// postgres 使用 pg_advisory_lock，mysql 使用 GET_LOCK，其他数据库不加锁
+// 锁是会话级的，因此单独占用一个连接直到迁移结束
+func (m *Migrator) lock(ctx context.Context) (unlock func(), err error) {
+	dialect := m.db.Dialector.Name()
+	if dialect != "postgres" && dialect != "mysql" {
+		return func() {}, nil
+	}
+	sqlDB, err := m.db.DB()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if dialect == "postgres" {
+		err = m.lockPostgres(ctx, conn)
+	} else {
+		err = m.lockMysql(ctx, conn)
+	}
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return func() {
+		// 迁移的 ctx 可能已取消，释放锁使用独立的 ctx
+		releaseCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		var releaseErr error
+		if dialect == "postgres" {
+			_, releaseErr = conn.ExecContext(releaseCtx, "SELECT pg_advisory_unlock($1)", m.lockKey())
+		} else {
+			_, releaseErr = conn.ExecContext(releaseCtx, "SELECT RELEASE_LOCK(?)", m.lockName())
+		}
+		if releaseErr != nil {
+			// 锁可能仍由该会话持有，丢弃连接而不是放回连接池，会话结束时数据库自动释放锁
+			log.Println("【migrateMng】release lock err:", releaseErr)
+			_ = conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+		}
+		_ = conn.Close()
+	}, nil
+}
+
+// lockPostgres 轮询 pg_try_advisory_lock，便于响应 ctx 取消与超时
+func (m *Migrator) lockPostgres(ctx context.Context, conn *sql.Conn) error {
+	deadline := time.Now().Add(m.lockTimeout)
+	for {
+		var ok bool
+		if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", m.lockKey()).Scan(&ok); err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return ErrLockTimeout
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// lockMysql GET_LOCK 自带等待，超时返回 0
+func (m *Migrator) lockMysql(ctx context.Context, conn *sql.Conn) error {
+	var ok sql.NullInt64
+	seconds := int(m.lockTimeout.Seconds())
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", m.lockName(), seconds).Scan(&ok); err != nil {
+		return err
+	}
+	if !ok.Valid || ok.Int64 != 1 {
+		return ErrLockTimeout
+	}
+	return nil
+}
+
+// lockName mysql 锁名，最长 64 个字符
+func (m *Migrator) lockName() string {
+	name := fmt.Sprintf("migrate:%s", m.table)
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
+
+// lockKey postgres 锁键，由表名哈希得到
+func (m *Migrator) lockKey() int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte("migrate:" + m.table))
+	return int64(h.Sum64())
+}
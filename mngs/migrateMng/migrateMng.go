@@ -0,0 +1,327 @@
+package migrateMng
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// DefaultTableName 迁移记录表
+const DefaultTableName = "schema_migrations"
+
+var (
+	ErrDuplicateVersion = errors.New("migrateMng: duplicate migration version")
+	ErrNoDown           = errors.New("migrateMng: migration has no down step")
+	ErrLockTimeout      = errors.New("migrateMng: timed out waiting for migration lock")
+)
+
+// Migration 一个版本的迁移，Up/Down 为 Go 函数或 SQL 二选一，函数优先
+type Migration struct {
+	Version int64  // 版本号，按升序执行，建议用 20060102150405 形式的时间戳
+	Name    string // 名称，仅用于展示
+
+	Up      func(ctx context.Context, tx *gorm.DB) error
+	Down    func(ctx context.Context, tx *gorm.DB) error
+	UpSQL   string // 可包含多条语句，以分号分隔
+	DownSQL string
+
+	// NoTx 不在事务中执行，用于 CREATE INDEX CONCURRENTLY 等不能放进事务的语句
+	NoTx bool
+}
+
+// checksum SQL 迁移的校验和，Go 函数迁移为空
+func (m *Migration) checksum() string {
+	if m.Up != nil || m.UpSQL == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(m.UpSQL))
+	return hex.EncodeToString(sum[:])
+}
+
+func (m *Migration) String() string {
+	return fmt.Sprintf("%d_%s", m.Version, m.Name)
+}
+
+// Record 迁移记录表结构
+type Record struct {
+	Version     int64     `gorm:"primaryKey;autoIncrement:false;column:version" json:"version"`
+	Name        string    `gorm:"column:name;type:varchar(255)" json:"name"`
+	Checksum    string    `gorm:"column:checksum;type:varchar(64)" json:"checksum"`
+	AppliedAt   time.Time `gorm:"column:applied_at" json:"applied_at"`
+	ExecutionMs int64     `gorm:"column:execution_ms" json:"execution_ms"`
+}
+
+// Status 某个迁移的状态
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	Changed   bool // 已执行的 SQL 迁移在之后被修改过
+	Missing   bool // 库中有记录但代码中已不存在
+}
+
+// Option 迁移配置
+type Option func(*Migrator)
+
+// WithTable 自定义记录表名，默认 schema_migrations
+func WithTable(table string) Option {
+	return func(m *Migrator) { m.table = table }
+}
+
+// WithLockTimeout 等待其他实例释放迁移锁的最长时间，默认 5 分钟
+func WithLockTimeout(d time.Duration) Option {
+	return func(m *Migrator) { m.lockTimeout = d }
+}
+
+// WithDryRun 只把将要执行的 SQL 写到 w，不修改数据库
+// Go 函数迁移在 DryRun 会话中执行，其中的查询不会返回数据
+func WithDryRun(w io.Writer) Option {
+	return func(m *Migrator) { m.dryRun = w }
+}
+
+// Migrator 迁移执行器，适用于 mysqlMng 与 psqlMng 的连接
+type Migrator struct {
+	db          *gorm.DB
+	table       string
+	lockTimeout time.Duration
+	dryRun      io.Writer
+
+	migrations []*Migration
+}
+
+// NewMigrator 创建迁移执行器
+func NewMigrator(db *gorm.DB, opts ...Option) *Migrator {
+	m := &Migrator{
+		db:          db,
+		table:       DefaultTableName,
+		lockTimeout: 5 * time.Minute,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Register 注册迁移，可多次调用
+func (m *Migrator) Register(migrations ...*Migration) *Migrator {
+	m.migrations = append(m.migrations, migrations...)
+	return m
+}
+
+// sorted 按版本排序并检查重复
+func (m *Migrator) sorted() ([]*Migration, error) {
+	list := append([]*Migration{}, m.migrations...)
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	for i := 1; i < len(list); i++ {
+		if list[i].Version == list[i-1].Version {
+			return nil, fmt.Errorf("%w: %d", ErrDuplicateVersion, list[i].Version)
+		}
+	}
+	return list, nil
+}
+
+// conn 迁移使用的会话，读写都走主库
+func (m *Migrator) conn(ctx context.Context) *gorm.DB {
+	return m.db.WithContext(ctx).Clauses(dbresolver.Write)
+}
+
+// ensureTable 创建记录表
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	return m.db.WithContext(ctx).Table(m.table).AutoMigrate(&Record{})
+}
+
+// applied 已执行的迁移，表不存在时为空
+func (m *Migrator) applied(ctx context.Context) (map[int64]*Record, error) {
+	records := map[int64]*Record{}
+	if !m.db.WithContext(ctx).Migrator().HasTable(m.table) {
+		return records, nil
+	}
+	var list []*Record
+	if err := m.conn(ctx).Table(m.table).Order("version").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range list {
+		records[r.Version] = r
+	}
+	return records, nil
+}
+
+// Status 所有迁移的执行情况
+func (m *Migrator) Status(ctx context.Context) ([]*Status, error) {
+	list, err := m.sorted()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*Status, 0, len(list))
+	for _, mig := range list {
+		s := &Status{Version: mig.Version, Name: mig.Name}
+		if r, ok := applied[mig.Version]; ok {
+			s.Applied, s.AppliedAt = true, r.AppliedAt
+			s.Changed = r.Checksum != "" && r.Checksum != mig.checksum()
+			delete(applied, mig.Version)
+		}
+		result = append(result, s)
+	}
+	for _, r := range applied {
+		result = append(result, &Status{Version: r.Version, Name: r.Name, Applied: true, AppliedAt: r.AppliedAt, Missing: true})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+	return result, nil
+}
+
+// Up 执行全部未执行的迁移，返回本次执行的迁移
+func (m *Migrator) Up(ctx context.Context) ([]*Migration, error) {
+	return m.UpTo(ctx, 0)
+}
+
+// UpTo 执行到 version（含）为止，version 为 0 表示全部
+func (m *Migrator) UpTo(ctx context.Context, version int64) (done []*Migration, err error) {
+	list, err := m.sorted()
+	if err != nil {
+		return nil, err
+	}
+	err = m.locked(ctx, func() error {
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+		for _, mig := range list {
+			if version > 0 && mig.Version > version {
+				break
+			}
+			if r, ok := applied[mig.Version]; ok {
+				if r.Checksum != "" && r.Checksum != mig.checksum() {
+					log.Printf("【migrateMng】migration %s changed after it was applied", mig)
+				}
+				continue
+			}
+			if err = m.run(ctx, mig, true); err != nil {
+				return fmt.Errorf("migrateMng: up %s: %w", mig, err)
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down 回滚最近执行的 steps 个迁移
+func (m *Migrator) Down(ctx context.Context, steps int) (done []*Migration, err error) {
+	list, err := m.sorted()
+	if err != nil {
+		return nil, err
+	}
+	err = m.locked(ctx, func() error {
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+		for i := len(list) - 1; i >= 0 && len(done) < steps; i-- {
+			mig := list[i]
+			if _, ok := applied[mig.Version]; !ok {
+				continue
+			}
+			if mig.Down == nil && mig.DownSQL == "" {
+				return fmt.Errorf("%w: %s", ErrNoDown, mig)
+			}
+			if err = m.run(ctx, mig, false); err != nil {
+				return fmt.Errorf("migrateMng: down %s: %w", mig, err)
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// locked 持有迁移锁执行 fn；DryRun 不加锁也不建表
+func (m *Migrator) locked(ctx context.Context, fn func() error) error {
+	if m.db == nil {
+		return gorm.ErrInvalidDB
+	}
+	if m.dryRun != nil {
+		return fn()
+	}
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err = m.ensureTable(ctx); err != nil {
+		return err
+	}
+	return fn()
+}
+
+// run 执行一个迁移并更新记录，事务模式下二者在同一事务中
+func (m *Migrator) run(ctx context.Context, mig *Migration, up bool) error {
+	if m.dryRun != nil {
+		return m.dryRunOne(ctx, mig, up)
+	}
+	start := time.Now()
+	exec := func(tx *gorm.DB) error {
+		if err := m.exec(ctx, tx, mig, up); err != nil {
+			return err
+		}
+		if !up {
+			return tx.Table(m.table).Where("version = ?", mig.Version).Delete(&Record{}).Error
+		}
+		return tx.Table(m.table).Create(&Record{
+			Version:     mig.Version,
+			Name:        mig.Name,
+			Checksum:    mig.checksum(),
+			AppliedAt:   time.Now(),
+			ExecutionMs: time.Since(start).Milliseconds(),
+		}).Error
+	}
+	if mig.NoTx {
+		err := exec(m.conn(ctx))
+		if err == nil {
+			log.Printf("【migrateMng】%s %s (%v)", direction(up), mig, time.Since(start))
+		}
+		return err
+	}
+	err := m.conn(ctx).Transaction(exec)
+	if err == nil {
+		log.Printf("【migrateMng】%s %s (%v)", direction(up), mig, time.Since(start))
+	}
+	return err
+}
+
+// exec 执行迁移本身
+func (m *Migrator) exec(ctx context.Context, tx *gorm.DB, mig *Migration, up bool) error {
+	fn, script := mig.Up, mig.UpSQL
+	if !up {
+		fn, script = mig.Down, mig.DownSQL
+	}
+	if fn != nil {
+		return fn(ctx, tx)
+	}
+	for _, stmt := range SplitStatements(script) {
+		if err := tx.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func direction(up bool) string {
+	if up {
+		return "up"
+	}
+	return "down"
+}
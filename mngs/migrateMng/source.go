@@ -0,0 +1,196 @@
+package migrateMng
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// fileRe 迁移文件名：<版本>_<名称>.up.sql / <版本>_<名称>.down.sql
+var fileRe = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// FromFS 从目录（通常是 embed.FS）读取 SQL 迁移
+//
+//	//go:embed migrations/*.sql
+//	var migrations embed.FS
+//	list, err := migrateMng.FromFS(migrations, "migrations")
+func FromFS(fsys fs.FS, dir string) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int64]*Migration{}
+	var list []*Migration
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := fileRe.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migrateMng: %s: %w", entry.Name(), err)
+		}
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = mig
+			list = append(list, mig)
+		} else if mig.Name != match[2] {
+			return nil, fmt.Errorf("%w: %d (%s, %s)", ErrDuplicateVersion, version, mig.Name, match[2])
+		}
+		if match[3] == "up" {
+			mig.UpSQL = string(content)
+		} else {
+			mig.DownSQL = string(content)
+		}
+		// 首行写 -- migrate:notx 表示不在事务中执行
+		if strings.HasPrefix(strings.TrimSpace(string(content)), "-- migrate:notx") {
+			mig.NoTx = true
+		}
+	}
+	return list, nil
+}
+
+// SplitStatements 按分号拆分多条语句，忽略引号、注释与 postgres $$ 块中的分号
+func SplitStatements(script string) []string {
+	var (
+		list  []string
+		buf   strings.Builder
+		quote byte   // 当前所在的引号 ' " `
+		tag   string // 当前所在的 $tag$ 块
+	)
+	flush := func() {
+		if s := strings.TrimSpace(buf.String()); s != "" && !onlyComments(s) {
+			list = append(list, s)
+		}
+		buf.Reset()
+	}
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		switch {
+		case tag != "":
+			if strings.HasPrefix(script[i:], tag) {
+				buf.WriteString(tag)
+				i += len(tag) - 1
+				tag = ""
+				continue
+			}
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '-' && strings.HasPrefix(script[i:], "--"):
+			end := strings.IndexByte(script[i:], '\n')
+			if end < 0 {
+				end = len(script) - i
+			}
+			buf.WriteString(script[i : i+end])
+			i += end - 1
+			continue
+		case c == '/' && strings.HasPrefix(script[i:], "/*"):
+			end := strings.Index(script[i:], "*/")
+			if end < 0 {
+				end = len(script) - i
+			} else {
+				end += 2
+			}
+			buf.WriteString(script[i : i+end])
+			i += end - 1
+			continue
+		case c == '$':
+			if end := strings.IndexByte(script[i+1:], '$'); end >= 0 && validTag(script[i+1:i+1+end]) {
+				tag = script[i : i+end+2]
+				buf.WriteString(tag)
+				i += end + 1
+				continue
+			}
+		case c == ';':
+			flush()
+			continue
+		}
+		buf.WriteByte(c)
+	}
+	flush()
+	return list
+}
+
+func validTag(s string) bool {
+	for _, r := range s {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// onlyComments 只有注释的片段不作为语句执行
+func onlyComments(s string) bool {
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "--") {
+			return false
+		}
+	}
+	return true
+}
+
+// -------BEGIN------dry run-----BEGIN--------
+
+// dryRunOne 输出迁移将执行的 SQL
+func (m *Migrator) dryRunOne(ctx context.Context, mig *Migration, up bool) error {
+	fmt.Fprintf(m.dryRun, "-- %s %s\n", direction(up), mig)
+	fn, script := mig.Up, mig.UpSQL
+	if !up {
+		fn, script = mig.Down, mig.DownSQL
+	}
+	if fn == nil {
+		for _, stmt := range SplitStatements(script) {
+			fmt.Fprintf(m.dryRun, "%s;\n", stmt)
+		}
+		return nil
+	}
+	capture := &captureLogger{}
+	tx := m.db.Session(&gorm.Session{DryRun: true, Logger: capture, Context: ctx})
+	if err := fn(ctx, tx); err != nil {
+		return err
+	}
+	for _, stmt := range capture.sqls {
+		fmt.Fprintf(m.dryRun, "%s;\n", stmt)
+	}
+	return nil
+}
+
+// captureLogger 收集 DryRun 会话生成的 SQL
+type captureLogger struct {
+	sqls []string
+}
+
+func (l *captureLogger) LogMode(logger.LogLevel) logger.Interface      { return l }
+func (l *captureLogger) Info(context.Context, string, ...interface{})  {}
+func (l *captureLogger) Warn(context.Context, string, ...interface{})  {}
+func (l *captureLogger) Error(context.Context, string, ...interface{}) {}
+func (l *captureLogger) Trace(_ context.Context, _ time.Time, fc func() (string, int64), _ error) {
+	if sql, _ := fc(); sql != "" {
+		l.sqls = append(l.sqls, sql)
+	}
+}
+
+// -------END------dry run----END---------
@@ -0,0 +1,124 @@
+package mysqlMng
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/wiidz/goutil/helpers/condHelper"
+	"github.com/wiidz/goutil/helpers/cursorHelper"
+	"gorm.io/gorm"
+)
+
+// WhereInterface 可选接口，Read/Update/Delete 的参数实现后会在 map 条件之外追加类型化条件
+type WhereInterface interface {
+	GetWhere() condHelper.Cond
+}
+
+// CursorInterface 可选接口，Read 的参数实现后可使用游标分页，排序键取自 GetOrder
+type CursorInterface interface {
+	CursorPage(order string, size int) (*cursorHelper.Page, error)
+	SetPageInfo(info *cursorHelper.PageInfo)
+}
+
+// MapCond 将旧的 map 条件转换为 condHelper 条件，键按字母序排列保证 SQL 稳定
+// 支持的写法：
+//
+//	"status": 1                              => status = 1
+//	"id": []interface{}{">", 10}             => 比较，可选 = != <> > >= < <= like "not like"
+//	"id": []interface{}{"in", ids}           => in / not in，ids 可以是任意切片
+//	"created_at": []interface{}{"between", a, b}
+//	"deleted_at": IsNull / IsNotNull
+func MapCond(condition map[string]interface{}, isOr bool) (condHelper.Cond, error) {
+	keys := make([]string, 0, len(condition))
+	for k := range condition {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	conds := make([]condHelper.Cond, 0, len(keys))
+	for _, k := range keys {
+		cond, err := mapItemCond(k, condition[k])
+		if err != nil {
+			return nil, err
+		}
+		conds = append(conds, cond)
+	}
+
+	if isOr {
+		return condHelper.Or(conds...), nil
+	}
+	return condHelper.And(conds...), nil
+}
+
+func mapItemCond(column string, value interface{}) (condHelper.Cond, error) {
+	switch v := value.(type) {
+	case NullType:
+		if v == IsNotNull {
+			return condHelper.IsNotNull(column), nil
+		}
+		return condHelper.IsNull(column), nil
+	case []interface{}:
+		if len(v) < 2 {
+			return nil, fmt.Errorf("%s 条件格式错误", column)
+		}
+		op, _ := v[0].(string)
+		switch strings.ToLower(op) {
+		case "between":
+			if len(v) < 3 {
+				return nil, fmt.Errorf("%s between 需要两个值", column)
+			}
+			return condHelper.Between(column, v[1], v[2]), nil
+		case "in":
+			return condHelper.In(column, v[1]), nil
+		case "not in":
+			return condHelper.NotIn(column, v[1]), nil
+		default:
+			return condHelper.Compare(column, op, v[1]), nil
+		}
+	default:
+		return condHelper.Eq(column, v), nil
+	}
+}
+
+// WhereBuild 复合condition成为cons、vals的结构，条件之间为 AND
+// 列名会被校验并加上引号，只允许 column 或 table.column
+func WhereBuild(condition map[string]interface{}) (whereSQL string, vals []interface{}, err error) {
+	cond, err := MapCond(condition, false)
+	if err != nil {
+		return "", nil, err
+	}
+	return condHelper.ToSQL(cond, nil, "mysql")
+}
+
+// WhereOrBuild 复合condition成为cons、vals的结构，条件之间为 OR
+func WhereOrBuild(condition map[string]interface{}) (whereSQL string, vals []interface{}, err error) {
+	cond, err := MapCond(condition, true)
+	if err != nil {
+		return "", nil, err
+	}
+	return condHelper.ToSQL(cond, nil, "mysql")
+}
+
+// applyCondition 拼接 map 条件与可选的类型化条件，返回是否存在条件
+func applyCondition(conn *gorm.DB, params interface{}, condition map[string]interface{}) (*gorm.DB, bool) {
+	hasCondition := false
+	if len(condition) > 0 {
+		cons, vals, err := WhereBuild(condition)
+		if err != nil {
+			_ = conn.AddError(err)
+			return conn, true
+		}
+		conn = conn.Where(cons, vals...)
+		hasCondition = true
+	}
+	if w, ok := params.(WhereInterface); ok && !condHelper.IsEmpty(w.GetWhere()) {
+		conn = conn.Scopes(condHelper.Scope(w.GetWhere(), nil))
+		hasCondition = true
+	}
+	return conn, hasCondition
+}
+
+// errEmptyCondition 修改、删除时禁止无条件执行
+var errEmptyCondition = errors.New("条件不允许为空")
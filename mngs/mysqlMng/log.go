@@ -1,6 +1,7 @@
 package mysqlMng
 
 import (
+	"context"
 	"errors"
 	"github.com/wiidz/goutil/helpers/typeHelper"
 	"gorm.io/gorm"
@@ -99,7 +100,9 @@ func (x *LogInsertCreate) TableName() string {
  *			[list] dbStruct.List 查询结构体
  * @return: [err] error 错误
  */
-func (mng *MysqlMng) LogRead(list ReadInterface, userID, authID int) {
+//
+// Deprecated: 只记录条件字符串且会截断，改用 auditMng.Auditor 插件（mng.Use）记录行级前后快照
+func (mng *MysqlMng) LogReadCtx(ctx context.Context, list ReadInterface, userID, authID int) {
 
 	//【1】初始化参数
 	offset := list.GetOffset()
@@ -109,11 +112,17 @@ func (mng *MysqlMng) LogRead(list ReadInterface, userID, authID int) {
 	preloads := list.GetPreloads()
 	rows := list.GetRows()
 
-	thisConn := mng.Conn
+	thisConn := mng.GetCtxConn(ctx)
 
 	//【2】拼接
+	var cons string
+	var vals []interface{}
 	if len(condition) > 0 {
-		cons, vals, _ := WhereBuild(condition)
+		var err error
+		if cons, vals, err = WhereBuild(condition); err != nil {
+			list.SetError(err)
+			return
+		}
 		thisConn = thisConn.Where(cons, vals...)
 	}
 	if len(preloads) > 0 {
@@ -140,10 +149,8 @@ func (mng *MysqlMng) LogRead(list ReadInterface, userID, authID int) {
 			rowsAffected = thisConn.RowsAffected
 
 			// count
-			//mysql.NewCommonConn()
-			thisConn = mng.Conn
+			thisConn = mng.GetCtxConn(ctx)
 			if len(condition) > 0 {
-				cons, vals, _ := WhereBuild(condition)
 				thisConn = thisConn.Where(cons, vals...)
 			}
 			err = thisConn.Model(rows).Count(&count).Error
@@ -153,9 +160,8 @@ func (mng *MysqlMng) LogRead(list ReadInterface, userID, authID int) {
 
 	// 【4】记录操作
 	go func() {
-		mng.NewCommonConn()
 		jsonCondition, _ := typeHelper.JsonEncode(condition)
-		mng.Conn.Create(&LogReadCreate{
+		mng.GetConn().Create(&LogReadCreate{
 			UserID:       userID,
 			AuthID:       authID,
 			Kind:         int8(1),
@@ -182,15 +188,14 @@ func (mng *MysqlMng) LogRead(list ReadInterface, userID, authID int) {
  * 			[data] interface{} 数据
  * 			[statusCode] 状态码
  */
-func (mng *MysqlMng) LogCreateOne(insert InsertInterface, userID, authID int) error {
+//
+// Deprecated: 只记录条件字符串且会截断，改用 auditMng.Auditor 插件（mng.Use）记录行级前后快照
+func (mng *MysqlMng) LogCreateOneCtx(ctx context.Context, insert InsertInterface, userID, authID int) error {
 
 	//【1】初始化参数
 	row := insert.GetRow()
 
-	thisConn := mng.Conn
-	if mng.TransConn != nil {
-		thisConn = mng.TransConn
-	}
+	thisConn := mng.GetCtxConn(ctx)
 	thisConn = thisConn.Create(row)
 
 	//【2】提取结果
@@ -205,7 +210,6 @@ func (mng *MysqlMng) LogCreateOne(insert InsertInterface, userID, authID int) er
 
 	//【5】记录操作
 	go func() {
-		mng.NewCommonConn()
 		jsonValue, _ := typeHelper.JsonEncode(row)
 		data := LogInsertCreate{
 			UserID:       userID,
@@ -214,7 +218,7 @@ func (mng *MysqlMng) LogCreateOne(insert InsertInterface, userID, authID int) er
 			Data:         jsonValue,
 			RowsAffected: int(rowsAffected),
 		}
-		mng.Conn.Create(&data)
+		mng.GetConn().Create(&data)
 	}()
 
 	//【5】返回
@@ -229,16 +233,15 @@ func (mng *MysqlMng) LogCreateOne(insert InsertInterface, userID, authID int) er
  *			[list] dbStruct.List 查询结构体
  * @return: [err] error 错误
  */
-func (mng *MysqlMng) LogUpdate(update UpdateInterface, userID, authID int) error {
+//
+// Deprecated: 只记录条件字符串且会截断，改用 auditMng.Auditor 插件（mng.Use）记录行级前后快照
+func (mng *MysqlMng) LogUpdateCtx(ctx context.Context, update UpdateInterface, userID, authID int) error {
 
 	//【1】初始化参数
 	condition := update.GetCondition()
 	value := update.GetValue()
 	tableName := update.GetTableName()
-	thisConn := mng.Conn
-	if mng.TransConn != nil {
-		thisConn = mng.TransConn
-	}
+	thisConn := mng.GetCtxConn(ctx)
 
 	//【2】拼接
 	if len(condition) == 0 {
@@ -250,17 +253,20 @@ func (mng *MysqlMng) LogUpdate(update UpdateInterface, userID, authID int) error
 	//【3】查原始数据 - 暂时不实现
 
 	//【4】修改
+	cons, vals, err := WhereBuild(condition)
+	if err != nil {
+		update.SetError(err)
+		return err
+	}
 	value["updated_at"] = time.Now()
-	cons, vals, _ := WhereBuild(condition)
 	thisConn = thisConn.Table(tableName).Where(cons, vals...).Updates(value)
 
 	//【4】提取结果
-	err := thisConn.Error
+	err = thisConn.Error
 	rowsAffected := thisConn.RowsAffected
 
 	//【5】记录操作
 	go func() {
-		mng.NewCommonConn()
 		jsonCondition, _ := typeHelper.JsonEncode(condition)
 		jsonValue, _ := typeHelper.JsonEncode(value)
 		data := LogUpdateCreate{
@@ -271,7 +277,7 @@ func (mng *MysqlMng) LogUpdate(update UpdateInterface, userID, authID int) error
 			Data:         jsonValue,
 			RowsAffected: int(rowsAffected),
 		}
-		mng.Conn.Create(&data)
+		mng.GetConn().Create(&data)
 	}()
 
 	//【5】返回
@@ -288,14 +294,13 @@ func (mng *MysqlMng) LogUpdate(update UpdateInterface, userID, authID int) error
  *          [newsID]  int 新闻的ID
  * @return: [err] error 错误信息
  */
-func (mng *MysqlMng) LogDelete(params DeleteInterface, userID, authID int) error {
+//
+// Deprecated: 只记录条件字符串且会截断，改用 auditMng.Auditor 插件（mng.Use）记录行级前后快照
+func (mng *MysqlMng) LogDeleteCtx(ctx context.Context, params DeleteInterface, userID, authID int) error {
 
 	//【1】初始化参数
 	condition := params.GetCondition()
-	thisConn := mng.Conn
-	if mng.TransConn != nil {
-		thisConn = mng.TransConn
-	}
+	thisConn := mng.GetCtxConn(ctx)
 
 	row := params.GetRow()
 
@@ -304,11 +309,14 @@ func (mng *MysqlMng) LogDelete(params DeleteInterface, userID, authID int) error
 		return errors.New("条件不允许为空")
 	}
 
-	cons, vals, _ := WhereBuild(condition)
+	cons, vals, err := WhereBuild(condition)
+	if err != nil {
+		return err
+	}
 	thisConn = thisConn.Where(cons, vals...).Delete(row)
 
 	//【3】提取结果
-	err := thisConn.Error
+	err = thisConn.Error
 	rowsAffected := thisConn.RowsAffected
 	if err == nil {
 		params.SetRowsAffected(rowsAffected)
@@ -316,7 +324,6 @@ func (mng *MysqlMng) LogDelete(params DeleteInterface, userID, authID int) error
 
 	//【5】记录操作
 	go func() {
-		mng.NewCommonConn()
 		jsonCondition, _ := typeHelper.JsonEncode(condition)
 		data := LogDeleteCreate{
 			UserID:       userID,
@@ -325,9 +332,41 @@ func (mng *MysqlMng) LogDelete(params DeleteInterface, userID, authID int) error
 			Condition:    jsonCondition,
 			RowsAffected: int(rowsAffected),
 		}
-		mng.Conn.Create(&data)
+		mng.GetConn().Create(&data)
 	}()
 
 	//【5】返回
 	return err
 }
+
+// -------BEGIN------不带 ctx 的旧签名-----BEGIN--------
+
+// LogRead 同 LogReadCtx，使用 context.Background()
+//
+// Deprecated: 改用 auditMng.Auditor 插件（mng.Use）
+func (mng *MysqlMng) LogRead(list ReadInterface, userID, authID int) {
+	mng.LogReadCtx(context.Background(), list, userID, authID)
+}
+
+// LogCreateOne 同 LogCreateOneCtx
+//
+// Deprecated: 改用 auditMng.Auditor 插件（mng.Use）
+func (mng *MysqlMng) LogCreateOne(insert InsertInterface, userID, authID int) error {
+	return mng.LogCreateOneCtx(context.Background(), insert, userID, authID)
+}
+
+// LogUpdate 同 LogUpdateCtx
+//
+// Deprecated: 改用 auditMng.Auditor 插件（mng.Use）
+func (mng *MysqlMng) LogUpdate(update UpdateInterface, userID, authID int) error {
+	return mng.LogUpdateCtx(context.Background(), update, userID, authID)
+}
+
+// LogDelete 同 LogDeleteCtx
+//
+// Deprecated: 改用 auditMng.Auditor 插件（mng.Use）
+func (mng *MysqlMng) LogDelete(params DeleteInterface, userID, authID int) error {
+	return mng.LogDeleteCtx(context.Background(), params, userID, authID)
+}
+
+// -------END------不带 ctx 的旧签名----END---------
@@ -1,13 +1,14 @@
 package mysqlMng
 
 import (
+	"database/sql"
 	"errors"
-	"fmt"
 	"log"
 	"net/url"
 	"strconv"
 	"time"
 
+	"github.com/wiidz/goutil/mngs/migrateMng"
 	"github.com/wiidz/goutil/structs/configStruct"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
@@ -21,14 +22,20 @@ type MysqlMng struct {
 	Conn      *gorm.DB                    // 普通会话
 	TransConn *gorm.DB                    // 事务会话
 	Slaves    []*configStruct.MysqlConfig // 从库配置项
+	Replica   *ReplicaConfig              // 从库健康检查配置，nil 使用默认值
+
+	replicas *replicaSet
 }
 
-// NewMysqlMng 获取一个mysql实例
-func NewMysqlMng(master *configStruct.MysqlConfig, slaves []*configStruct.MysqlConfig) (mysqlMng *MysqlMng, err error) {
+// NewMysqlMng 获取一个mysql实例，有从库时会启动健康检查，可选传入 replicaConfig 调整阈值
+func NewMysqlMng(master *configStruct.MysqlConfig, slaves []*configStruct.MysqlConfig, replicaConfig ...*ReplicaConfig) (mysqlMng *MysqlMng, err error) {
 	mysqlMng = &MysqlMng{
 		config: master,
 		Slaves: slaves,
 	}
+	if len(replicaConfig) > 0 {
+		mysqlMng.Replica = replicaConfig[0]
+	}
 
 	err = mysqlMng.Init()
 	return
@@ -64,11 +71,20 @@ func (mng *MysqlMng) Init() (err error) {
 	sqlDB.SetConnMaxLifetime(time.Second * time.Duration(mng.config.MaxLifeTime)) //设置连接空闲超时
 
 	//【4】构建从库
-	if mng.Slaves != nil && len(mng.Slaves) > 0 {
+	if len(mng.Slaves) > 0 {
 		log.Println("【mysql-slaves】", len(mng.Slaves))
+		// 从库连接自行创建，便于健康检查直接探测，并由 replicaSet 作为负载策略跳过不健康的从库
+		mng.replicas = newReplicaSet(mng.Replica.withDefaults(), sqlDB)
 		dialectors := []gorm.Dialector{}
 		for _, v := range mng.Slaves {
-			dialectors = append(dialectors, mysql.Open(getDsn(v)))
+			dsn := getDsn(v)
+			var replicaDB *sql.DB
+			if replicaDB, err = sql.Open("mysql", dsn); err != nil {
+				log.Println("【mysql-slaves-init】", err)
+				return
+			}
+			mng.replicas.add(v.Host+":"+v.Port, replicaDB)
+			dialectors = append(dialectors, mysql.New(mysql.Config{DSN: dsn, Conn: replicaDB}))
 		}
 
 		// 配置主从（读写分离）
@@ -77,6 +93,7 @@ func (mng *MysqlMng) Init() (err error) {
 				// 指定源（主库）和 replicas（从库）
 				dbresolver.Config{
 					Replicas: dialectors,
+					Policy:   mng.replicas,
 				},
 				// 指定作用于哪些表，可选
 				// &User{},
@@ -86,11 +103,33 @@ func (mng *MysqlMng) Init() (err error) {
 				SetMaxOpenConns(mng.Slaves[0].MaxOpenConns),
 		)
 		log.Println("【mysql-slaves-init】", err)
+		if err != nil {
+			return
+		}
+		if err = mng.registerReadYourWrites(); err != nil {
+			return
+		}
+		go mng.replicas.run()
 	}
 
 	return
 }
 
+// Close 停止从库健康检查并关闭主从连接
+func (mng *MysqlMng) Close() error {
+	var errs []error
+	if mng.replicas != nil {
+		mng.replicas.close()
+		for _, r := range mng.replicas.list {
+			errs = append(errs, r.db.Close())
+		}
+	}
+	if sqlDB, err := mng.db.DB(); err == nil {
+		errs = append(errs, sqlDB.Close())
+	}
+	return errors.Join(errs...)
+}
+
 // NewCommonConn 获取一个新的会话
 func (mng *MysqlMng) NewCommonConn() {
 	mng.Conn = mng.db.Session(&gorm.Session{
@@ -105,6 +144,16 @@ func (mng *MysqlMng) SetLogger(logger logger.Interface) {
 	mng.db.Logger = logger
 }
 
+// Use 注册 gorm 插件（如 auditMng.Auditor），对之后获取的会话生效
+func (mng *MysqlMng) Use(plugin gorm.Plugin) error {
+	return mng.db.Use(plugin)
+}
+
+// NewMigrator 基于主库创建版本化迁移执行器
+func (mng *MysqlMng) NewMigrator(opts ...migrateMng.Option) *migrateMng.Migrator {
+	return migrateMng.NewMigrator(mng.db, opts...)
+}
+
 // GetConn 获取一个新的会话
 func (mng *MysqlMng) GetConn() *gorm.DB {
 	return mng.db.Session(&gorm.Session{
@@ -127,6 +176,8 @@ func (mng *MysqlMng) GetDBTransConn(dbName string) *gorm.DB {
 }
 
 // NewTransConn 开启一个事务会话
+//
+// Deprecated: TransConn 为所有请求共享，并发开启事务会互相覆盖，请使用 WithTx
 func (mng *MysqlMng) NewTransConn() {
 	mng.TransConn = mng.db.Session(&gorm.Session{
 		//WithConditions: true,
@@ -135,11 +186,15 @@ func (mng *MysqlMng) NewTransConn() {
 }
 
 // Rollback 回滚事务
+//
+// Deprecated: 请使用 WithTx
 func (mng *MysqlMng) Rollback() {
 	mng.TransConn.Rollback()
 }
 
 // Commit 提交事务
+//
+// Deprecated: 请使用 WithTx
 func (mng *MysqlMng) Commit() {
 	mng.TransConn.Commit()
 }
@@ -149,178 +204,6 @@ func IsNotFound(err error) bool {
 	return errors.Is(err, gorm.ErrRecordNotFound)
 }
 
-// WhereBuild 复合condition成为cons、vals的结构
-func WhereBuild(condition map[string]interface{}) (whereSQL string, vals []interface{}, err error) {
-
-	for k, v := range condition {
-		if whereSQL != "" {
-			whereSQL += " AND "
-		}
-		switch v.(type) {
-		case []interface{}:
-			switch v.([]interface{})[0] {
-			case "=":
-				//whereSQL += fmt.Sprint("`"+k+"`", "=?")
-				whereSQL += fmt.Sprint(k, "=?")
-			case ">":
-				//whereSQL += fmt.Sprint("`"+k+"`", ">?")
-				whereSQL += fmt.Sprint(k, ">?")
-			case ">=":
-				//whereSQL += fmt.Sprint("`"+k+"`", ">=?")
-				whereSQL += fmt.Sprint(k, ">=?")
-			case "<":
-				//whereSQL += fmt.Sprint("`"+k+"`", "<?")
-				whereSQL += fmt.Sprint(k, "<?")
-			case "<=":
-				//whereSQL += fmt.Sprint("`"+k+"`", "<=?")
-				whereSQL += fmt.Sprint(k, "<=?")
-			case "!=":
-				//whereSQL += fmt.Sprint("`"+k+"`", "!=?")
-				whereSQL += fmt.Sprint(k, "!=?")
-			case "<>":
-				//whereSQL += fmt.Sprint("`"+k+"`", "!=?")
-				whereSQL += fmt.Sprint(k, "!=?")
-			case "like":
-				//whereSQL += fmt.Sprint("`"+k+"`", " like ?")
-				whereSQL += fmt.Sprint(k, " like ?")
-			}
-
-			if v.([]interface{})[0] == "between" {
-				whereSQL += fmt.Sprint(k, " between ? AND ?")
-				vals = append(vals, v.([]interface{})[1], v.([]interface{})[2])
-			} else if v.([]interface{})[0] == "in" || v.([]interface{})[0] == "not in" {
-				whereSQL += fmt.Sprint(k, " "+v.([]interface{})[0].(string)+" (")
-				if intSlice, ok := v.([]interface{})[1].([]int); ok {
-					for k := 0; k < len(intSlice); k++ {
-						whereSQL += "?,"
-						vals = append(vals, intSlice[k])
-					}
-					whereSQL = whereSQL[0:len(whereSQL)-1] + ")"
-				} else if intSlice, ok := v.([]interface{})[1].(*[]int); ok {
-					for k := 0; k < len(*intSlice); k++ {
-						whereSQL += "?,"
-						vals = append(vals, (*intSlice)[k])
-					}
-					whereSQL = whereSQL[0:len(whereSQL)-1] + ")"
-				} else if int8Slice, ok := v.([]interface{})[1].([]int8); ok {
-					for k := 0; k < len(int8Slice); k++ {
-						whereSQL += "?,"
-						vals = append(vals, (int8Slice)[k])
-					}
-					whereSQL = whereSQL[0:len(whereSQL)-1] + ")"
-				} else if uint64Slice, ok := v.([]interface{})[1].([]uint64); ok {
-					for k := 0; k < len(uint64Slice); k++ {
-						whereSQL += "?,"
-						vals = append(vals, (uint64Slice)[k])
-					}
-					whereSQL = whereSQL[0:len(whereSQL)-1] + ")"
-				} else {
-					err = errors.New("无法匹配的类型")
-				}
-			} else {
-				vals = append(vals, v.([]interface{})[1])
-			}
-
-		default:
-			switch v := v.(type) {
-			case NullType:
-				if v == IsNotNull {
-					//whereSQL += fmt.Sprint("`"+k+"`", " IS NOT NULL")
-					whereSQL += fmt.Sprint(k, " IS NOT NULL")
-				} else {
-					//whereSQL += fmt.Sprint("`"+k+"`", " IS NULL")
-					whereSQL += fmt.Sprint(k, " IS NULL")
-				}
-			default:
-				//whereSQL += fmt.Sprint("`"+k+"`", "=?")
-				whereSQL += fmt.Sprint(k, "=?")
-				vals = append(vals, v)
-			}
-		}
-	}
-	return
-}
-
-// WhereOrBuild 复合condition成为cons、vals的结构
-func WhereOrBuild(condition map[string]interface{}) (whereSQL string, vals []interface{}, err error) {
-
-	for k, v := range condition {
-		if whereSQL != "" {
-			whereSQL += " OR "
-		}
-		switch v.(type) {
-		case []interface{}:
-			switch v.([]interface{})[0] {
-			case "=":
-				//whereSQL += fmt.Sprint("`"+k+"`", "=?")
-				whereSQL += fmt.Sprint(k, "=?")
-			case ">":
-				//whereSQL += fmt.Sprint("`"+k+"`", ">?")
-				whereSQL += fmt.Sprint(k, ">?")
-			case ">=":
-				//whereSQL += fmt.Sprint("`"+k+"`", ">=?")
-				whereSQL += fmt.Sprint(k, ">=?")
-			case "<":
-				//whereSQL += fmt.Sprint("`"+k+"`", "<?")
-				whereSQL += fmt.Sprint(k, "<?")
-			case "<=":
-				//whereSQL += fmt.Sprint("`"+k+"`", "<=?")
-				whereSQL += fmt.Sprint(k, "<=?")
-			case "!=":
-				//whereSQL += fmt.Sprint("`"+k+"`", "!=?")
-				whereSQL += fmt.Sprint(k, "!=?")
-			case "<>":
-				//whereSQL += fmt.Sprint("`"+k+"`", "!=?")
-				whereSQL += fmt.Sprint(k, "!=?")
-			case "like":
-				//whereSQL += fmt.Sprint("`"+k+"`", " like ?")
-				whereSQL += fmt.Sprint(k, " like ?")
-			}
-
-			if v.([]interface{})[0] == "between" {
-				//whereSQL += fmt.Sprint("`"+k+"`", " between ? AND ?")
-				whereSQL += fmt.Sprint(k, " between ? AND ?")
-				vals = append(vals, v.([]interface{})[1], v.([]interface{})[2])
-			} else if v.([]interface{})[0] == "in" {
-				//whereSQL += fmt.Sprint("`"+k+"`", " IN (")
-				whereSQL += fmt.Sprint(k, " IN (")
-				if intSlice, ok := v.([]interface{})[1].([]int); ok {
-					for k := 0; k < len(intSlice); k++ {
-						whereSQL += "?,"
-						vals = append(vals, intSlice[k])
-					}
-					whereSQL = whereSQL[0:len(whereSQL)-1] + ")"
-				} else if intSlice, ok := v.([]interface{})[1].(*[]int); ok {
-					for k := 0; k < len((*intSlice)); k++ {
-						whereSQL += "?,"
-						vals = append(vals, (*intSlice)[k])
-					}
-					whereSQL = whereSQL[0:len(whereSQL)-1] + ")"
-				}
-			} else {
-				vals = append(vals, v.([]interface{})[1])
-			}
-
-		default:
-			switch v := v.(type) {
-			case NullType:
-				if v == IsNotNull {
-					//whereSQL += fmt.Sprint("`"+k+"`", " IS NOT NULL")
-					whereSQL += fmt.Sprint(k, " IS NOT NULL")
-				} else {
-					//whereSQL += fmt.Sprint("`"+k+"`", " IS NULL")
-					whereSQL += fmt.Sprint(k, " IS NULL")
-				}
-			default:
-				//whereSQL += fmt.Sprint("`"+k+"`", "=?")
-				whereSQL += fmt.Sprint(k, "=?")
-				vals = append(vals, v)
-			}
-		}
-	}
-	return
-}
-
 // IsExist 查询是否存在记录
 func IsExist(conn *gorm.DB, condition map[string]interface{}, tableName string) (err error) {
 	cons, vals, err := WhereBuild(condition)
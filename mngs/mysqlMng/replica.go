@@ -0,0 +1,392 @@
+package mysqlMng
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ReplicaConfig 从库健康检查与读写分离配置，零值字段使用默认值
+type ReplicaConfig struct {
+	Interval         time.Duration // 检查间隔，默认 5 秒
+	Timeout          time.Duration // 单次检查超时，默认 2 秒
+	MaxLag           time.Duration // 允许的最大复制延迟，默认 10 秒，超过即摘除
+	FailThreshold    int           // 连续失败多少次摘除，默认 2
+	RecoverThreshold int           // 摘除后连续成功多少次恢复，默认 2
+
+	// HeartbeatTable 非空时用心跳表测量延迟：主库定期写入当前时间，从库读取差值
+	// 为空时使用 SHOW REPLICA STATUS（低版本回退到 SHOW SLAVE STATUS），需要 REPLICATION CLIENT 权限
+	HeartbeatTable string
+
+	// PinDuration ReadYourWrites 模式下，写入后该 ctx 的读请求走主库的时长，默认 5 秒
+	PinDuration time.Duration
+}
+
+func (c *ReplicaConfig) withDefaults() ReplicaConfig {
+	cfg := ReplicaConfig{}
+	if c != nil {
+		cfg = *c
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 5 * time.Second
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 2 * time.Second
+	}
+	if cfg.MaxLag <= 0 {
+		cfg.MaxLag = 10 * time.Second
+	}
+	if cfg.FailThreshold <= 0 {
+		cfg.FailThreshold = 2
+	}
+	if cfg.RecoverThreshold <= 0 {
+		cfg.RecoverThreshold = 2
+	}
+	if cfg.PinDuration <= 0 {
+		cfg.PinDuration = 5 * time.Second
+	}
+	return cfg
+}
+
+// ReplicaStat 从库状态
+type ReplicaStat struct {
+	Name      string        `json:"name"`    // host:port
+	Healthy   bool          `json:"healthy"` // 是否参与读负载
+	Lag       time.Duration `json:"lag"`     // 最近一次测得的复制延迟
+	LastErr   string        `json:"last_err"`
+	LastCheck time.Time     `json:"last_check"`
+	Failures  int           `json:"failures"`  // 当前连续失败次数
+	Ejections int64         `json:"ejections"` // 累计被摘除次数
+}
+
+// replica 一个从库及其健康状态
+type replica struct {
+	name string
+	db   *sql.DB
+
+	mu        sync.RWMutex
+	healthy   bool
+	lag       time.Duration
+	lastErr   error
+	lastCheck time.Time
+	fails     int
+	oks       int
+	ejections int64
+}
+
+func (r *replica) isHealthy() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.healthy
+}
+
+// report 记录一次检查结果，按阈值摘除或恢复
+func (r *replica) report(cfg ReplicaConfig, lag time.Duration, err error) {
+	if err == nil && lag > cfg.MaxLag {
+		err = fmt.Errorf("replication lag %v exceeds %v", lag, cfg.MaxLag)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lag, r.lastErr, r.lastCheck = lag, err, time.Now()
+	if err != nil {
+		r.oks = 0
+		r.fails++
+		if r.healthy && r.fails >= cfg.FailThreshold {
+			r.healthy = false
+			r.ejections++
+			log.Printf("【mysql-replica】%s ejected: %v", r.name, err)
+		}
+		return
+	}
+	r.fails = 0
+	r.oks++
+	if !r.healthy && r.oks >= cfg.RecoverThreshold {
+		r.healthy = true
+		log.Printf("【mysql-replica】%s readmitted", r.name)
+	}
+}
+
+func (r *replica) stat() ReplicaStat {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s := ReplicaStat{
+		Name:      r.name,
+		Healthy:   r.healthy,
+		Lag:       r.lag,
+		LastCheck: r.lastCheck,
+		Failures:  r.fails,
+		Ejections: r.ejections,
+	}
+	if r.lastErr != nil {
+		s.LastErr = r.lastErr.Error()
+	}
+	return s
+}
+
+// replicaSet 从库集合，同时作为 dbresolver 的负载策略
+type replicaSet struct {
+	cfg     ReplicaConfig
+	primary *sql.DB
+	list    []*replica
+	byPool  map[gorm.ConnPool]*replica
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func newReplicaSet(cfg ReplicaConfig, primary *sql.DB) *replicaSet {
+	return &replicaSet{
+		cfg:     cfg,
+		primary: primary,
+		byPool:  map[gorm.ConnPool]*replica{},
+		stop:    make(chan struct{}),
+	}
+}
+
+func (s *replicaSet) add(name string, db *sql.DB) {
+	r := &replica{name: name, db: db, healthy: true}
+	s.list = append(s.list, r)
+	s.byPool[db] = r
+}
+
+// Resolve dbresolver.Policy：在健康的从库中随机选择，全部不可用时回落到主库
+func (s *replicaSet) Resolve(pools []gorm.ConnPool) gorm.ConnPool {
+	healthy := make([]gorm.ConnPool, 0, len(pools))
+	for _, pool := range pools {
+		if r, ok := s.byPool[pool]; !ok || r.isHealthy() {
+			healthy = append(healthy, pool)
+		}
+	}
+	if len(healthy) == 0 {
+		return s.primary
+	}
+	return healthy[rand.Intn(len(healthy))]
+}
+
+// run 周期性检查所有从库
+func (s *replicaSet) run() {
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		s.checkAll()
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *replicaSet) close() {
+	s.stopOnce.Do(func() { close(s.stop) })
+}
+
+func (s *replicaSet) checkAll() {
+	if s.cfg.HeartbeatTable != "" {
+		if err := s.beat(); err != nil {
+			log.Println("【mysql-replica】heartbeat write err:", err)
+		}
+	}
+	var wg sync.WaitGroup
+	for _, r := range s.list {
+		wg.Add(1)
+		go func(r *replica) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+			defer cancel()
+			lag, err := s.check(ctx, r)
+			r.report(s.cfg, lag, err)
+		}(r)
+	}
+	wg.Wait()
+}
+
+// check 连通性与复制延迟
+func (s *replicaSet) check(ctx context.Context, r *replica) (time.Duration, error) {
+	if err := r.db.PingContext(ctx); err != nil {
+		return 0, err
+	}
+	if s.cfg.HeartbeatTable != "" {
+		return s.heartbeatLag(ctx, r)
+	}
+	return statusLag(ctx, r.db)
+}
+
+// beat 主库写入心跳
+func (s *replicaSet) beat() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+	defer cancel()
+	table := quoteIdent(s.cfg.HeartbeatTable)
+	if _, err := s.primary.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS "+table+" (id TINYINT PRIMARY KEY, ts DATETIME(6) NOT NULL)"); err != nil {
+		return err
+	}
+	_, err := s.primary.ExecContext(ctx, "REPLACE INTO "+table+" (id, ts) VALUES (1, UTC_TIMESTAMP(6))")
+	return err
+}
+
+// heartbeatLag 从库上的心跳时间与当前时间之差
+func (s *replicaSet) heartbeatLag(ctx context.Context, r *replica) (time.Duration, error) {
+	var micro sql.NullInt64
+	query := "SELECT TIMESTAMPDIFF(MICROSECOND, ts, UTC_TIMESTAMP(6)) FROM " + quoteIdent(s.cfg.HeartbeatTable) + " WHERE id = 1"
+	if err := r.db.QueryRowContext(ctx, query).Scan(&micro); err != nil {
+		return 0, err
+	}
+	if !micro.Valid {
+		return 0, errors.New("heartbeat missing")
+	}
+	return time.Duration(micro.Int64) * time.Microsecond, nil
+}
+
+// statusLag 读取 Seconds_Behind_Source，为 NULL 表示复制线程未运行
+func statusLag(ctx context.Context, db *sql.DB) (time.Duration, error) {
+	rows, err := db.QueryContext(ctx, "SHOW REPLICA STATUS")
+	if err != nil {
+		// MySQL 8.0.22 以下
+		if rows, err = db.QueryContext(ctx, "SHOW SLAVE STATUS"); err != nil {
+			return 0, err
+		}
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return 0, err
+		}
+		return 0, errors.New("not a replica")
+	}
+	values := make([]sql.RawBytes, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err = rows.Scan(dest...); err != nil {
+		return 0, err
+	}
+	for i, column := range columns {
+		if column != "Seconds_Behind_Source" && column != "Seconds_Behind_Master" {
+			continue
+		}
+		if values[i] == nil {
+			return 0, errors.New("replication is not running")
+		}
+		seconds, err := strconv.ParseInt(string(values[i]), 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(seconds) * time.Second, nil
+	}
+	return 0, errors.New("replication lag unavailable")
+}
+
+func quoteIdent(name string) string {
+	parts := strings.Split(name, ".")
+	for i, p := range parts {
+		parts[i] = "`" + strings.ReplaceAll(p, "`", "``") + "`"
+	}
+	return strings.Join(parts, ".")
+}
+
+// Stats 各从库的健康状态，没有从库时为空
+func (mng *MysqlMng) Stats() []ReplicaStat {
+	if mng.replicas == nil {
+		return nil
+	}
+	stats := make([]ReplicaStat, 0, len(mng.replicas.list))
+	for _, r := range mng.replicas.list {
+		stats = append(stats, r.stat())
+	}
+	return stats
+}
+
+// -------BEGIN------read your writes-----BEGIN--------
+
+// rywKey ReadYourWrites 状态在 context 中的键
+type rywKey struct {
+	mng *MysqlMng
+}
+
+// rywState 最近一次写入后读主库的截止时间（UnixNano）
+type rywState struct {
+	until atomic.Int64
+}
+
+// ReadYourWrites 开启读己之写：之后用该 ctx 写入时，在 PinDuration 内同一 ctx 的读请求都走主库
+// 通常在请求入口处调用一次
+func (mng *MysqlMng) ReadYourWrites(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(rywKey{mng}).(*rywState); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, rywKey{mng}, &rywState{})
+}
+
+// PinPrimary 立即把 ctx 固定到主库 d 时长，ctx 需先经过 ReadYourWrites
+func (mng *MysqlMng) PinPrimary(ctx context.Context, d time.Duration) {
+	if state, ok := ctx.Value(rywKey{mng}).(*rywState); ok {
+		state.until.Store(time.Now().Add(d).UnixNano())
+	}
+}
+
+func (mng *MysqlMng) pinned(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	state, ok := ctx.Value(rywKey{mng}).(*rywState)
+	return ok && time.Now().UnixNano() < state.until.Load()
+}
+
+// registerReadYourWrites 写后标记、读前改道，需在 dbresolver 之后注册
+func (mng *MysqlMng) registerReadYourWrites() error {
+	cb := mng.db.Callback()
+	mark := func(db *gorm.DB) {
+		if db.Error == nil && !db.DryRun {
+			mng.PinPrimary(db.Statement.Context, mng.replicas.cfg.PinDuration)
+		}
+	}
+	markRaw := func(db *gorm.DB) {
+		if sqlText := strings.TrimSpace(db.Statement.SQL.String()); len(sqlText) < 6 || !strings.EqualFold(sqlText[:6], "select") {
+			mark(db)
+		}
+	}
+	route := func(db *gorm.DB) {
+		if _, isTx := db.Statement.ConnPool.(gorm.TxCommitter); !isTx && mng.pinned(db.Statement.Context) {
+			db.Statement.ConnPool = mng.replicas.primary
+		}
+	}
+
+	if err := cb.Create().After("gorm:create").Register("mysqlMng:ryw_mark", mark); err != nil {
+		return err
+	}
+	if err := cb.Update().After("gorm:update").Register("mysqlMng:ryw_mark", mark); err != nil {
+		return err
+	}
+	if err := cb.Delete().After("gorm:delete").Register("mysqlMng:ryw_mark", mark); err != nil {
+		return err
+	}
+	if err := cb.Raw().After("gorm:raw").Register("mysqlMng:ryw_mark", markRaw); err != nil {
+		return err
+	}
+	if err := cb.Query().After("gorm:db_resolver").Before("gorm:query").Register("mysqlMng:ryw_route", route); err != nil {
+		return err
+	}
+	if err := cb.Row().After("gorm:db_resolver").Before("gorm:row").Register("mysqlMng:ryw_route", route); err != nil {
+		return err
+	}
+	return cb.Raw().After("gorm:db_resolver").Before("gorm:raw").Register("mysqlMng:ryw_route", route)
+}
+
+// -------END------read your writes----END---------
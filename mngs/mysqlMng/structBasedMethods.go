@@ -1,7 +1,10 @@
 package mysqlMng
 
 import (
+	"context"
 	"errors"
+
+	"github.com/wiidz/goutil/helpers/cursorHelper"
 	"gorm.io/gorm"
 )
 
@@ -9,11 +12,11 @@ import (
  * @func  : 通用方法 获取列表
  * @author: Wiidz
  * @date  : 2020-10-14
- * @params: [mysql] *mysqlMng.MysqlMng 数据库连接
+ * @params: [ctx] context.Context 携带 WithTx 开启的事务时在事务中查询（UpdateCtx/CreateOneCtx/DeleteCtx 同理）
  *			[list] dbStruct.List 查询结构体
  * @return: [err] error 错误
  */
-func (mng *MysqlMng) Read(list ReadInterface, isSingle, doCount bool) (err error) {
+func (mng *MysqlMng) ReadCtx(ctx context.Context, list ReadInterface, isSingle, doCount bool) (err error) {
 
 	//【1】初始化参数
 	offset := list.GetOffset()
@@ -27,18 +30,37 @@ func (mng *MysqlMng) Read(list ReadInterface, isSingle, doCount bool) (err error
 		model = list.GetRow()
 	}
 
-	thisConn := mng.GetConn()
+	thisConn := mng.GetCtxConn(ctx)
 
 	//【2】拼接
-	if len(condition) > 0 {
-		cons, vals, _ := WhereBuild(condition)
-		thisConn = thisConn.Where(cons, vals...)
-	}
+	thisConn, _ = applyCondition(thisConn, list, condition)
 	if len(preloads) > 0 {
 		for _, v := range preloads {
 			thisConn = thisConn.Preload(v)
 		}
 	}
+
+	//【3】游标分页，doCount 为 false 时不统计总数
+	if cursorList, ok := list.(CursorInterface); ok && !isSingle {
+		var page *cursorHelper.Page
+		if page, err = cursorList.CursorPage(order, list.GetPageSize()); err != nil {
+			list.SetError(err)
+			return
+		}
+		if page != nil {
+			page.SkipCount = page.SkipCount || !doCount
+			var info *cursorHelper.PageInfo
+			if info, err = cursorHelper.Paginate(thisConn, *page, model); err == nil {
+				cursorList.SetPageInfo(info)
+				if info.Total >= 0 {
+					list.SetCount(info.Total)
+				}
+			}
+			list.SetError(err)
+			return
+		}
+	}
+
 	if order != "" {
 		thisConn = thisConn.Order(order)
 	}
@@ -58,10 +80,7 @@ func (mng *MysqlMng) Read(list ReadInterface, isSingle, doCount bool) (err error
 	if doCount {
 		var count int64
 		thisConn = thisConn.Session(&gorm.Session{NewDB: true})
-		if len(condition) > 0 {
-			cons, vals, _ := WhereBuild(condition)
-			thisConn = thisConn.Where(cons, vals...)
-		}
+		thisConn, _ = applyCondition(thisConn, list, condition)
 		err = thisConn.Model(model).Count(&count).Error
 		list.SetCount(count)
 	}
@@ -80,7 +99,7 @@ func (mng *MysqlMng) Read(list ReadInterface, isSingle, doCount bool) (err error
  *			[list] dbStruct.List 查询结构体
  * @return: [err] error 错误
  */
-func (mng *MysqlMng) Count(list ReadInterface) (count int64, err error) {
+func (mng *MysqlMng) CountCtx(ctx context.Context, list ReadInterface) (count int64, err error) {
 
 	//【1】初始化参数
 	condition := list.GetCondition()
@@ -89,13 +108,10 @@ func (mng *MysqlMng) Count(list ReadInterface) (count int64, err error) {
 
 	var model = list.GetRow()
 
-	thisConn := mng.GetConn()
+	thisConn := mng.GetCtxConn(ctx)
 
 	//【2】拼接
-	if len(condition) > 0 {
-		cons, vals, _ := WhereBuild(condition)
-		thisConn = thisConn.Where(cons, vals...)
-	}
+	thisConn, _ = applyCondition(thisConn, list, condition)
 	if len(preloads) > 0 {
 		for _, v := range preloads {
 			thisConn = thisConn.Preload(v)
@@ -107,10 +123,7 @@ func (mng *MysqlMng) Count(list ReadInterface) (count int64, err error) {
 
 	//【4】查count
 	thisConn = thisConn.Session(&gorm.Session{NewDB: true})
-	if len(condition) > 0 {
-		cons, vals, _ := WhereBuild(condition)
-		thisConn = thisConn.Where(cons, vals...)
-	}
+	thisConn, _ = applyCondition(thisConn, list, condition)
 	err = thisConn.Model(model).Count(&count).Error
 	list.SetCount(count)
 
@@ -119,15 +132,12 @@ func (mng *MysqlMng) Count(list ReadInterface) (count int64, err error) {
 	return
 }
 
-func (mng *MysqlMng) SumFloat64(model DBStructInterface, sumField string, condition map[string]interface{}) (sum float64, err error) {
+func (mng *MysqlMng) SumFloat64Ctx(ctx context.Context, model DBStructInterface, sumField string, condition map[string]interface{}) (sum float64, err error) {
 
-	conn := mng.GetConn()
+	conn := mng.GetCtxConn(ctx)
 
 	//【2】处理条件
-	if len(condition) > 0 {
-		cons, vals, _ := WhereBuild(condition)
-		conn = conn.Where(cons, vals...)
-	}
+	conn, _ = applyCondition(conn, model, condition)
 
 	var row SumData
 	err = conn.Model(model).Select("sum(" + sumField + ") as sum_float64").Scan(&row).Error
@@ -145,29 +155,29 @@ func (mng *MysqlMng) SumFloat64(model DBStructInterface, sumField string, condit
  *			[list] dbStruct.List 查询结构体
  * @return: [err] error 错误
  */
-func (mng *MysqlMng) Update(update UpdateInterface) error {
+func (mng *MysqlMng) UpdateCtx(ctx context.Context, update UpdateInterface) error {
 
 	//【1】初始化参数
 	condition := update.GetCondition()
 	value := update.GetValue()
 	//tableName := update.GetTableName()
-	thisConn := mng.GetConn()
+	thisConn := mng.GetCtxConn(ctx)
 	model := update.GetRow()
 	if model == nil {
 		return errors.New("")
 	}
 
 	//【2】拼接
-	if len(condition) == 0 {
-		return errors.New("条件不允许为空")
+	thisConn, hasCondition := applyCondition(thisConn, update, condition)
+	if !hasCondition {
+		return errEmptyCondition
 	}
 	if len(value) == 0 {
 		return errors.New("值不允许为空")
 	}
 
 	//【3】修改
-	cons, vals, _ := WhereBuild(condition)
-	thisConn = thisConn.Model(model).Where(cons, vals...).Updates(value)
+	thisConn = thisConn.Model(model).Updates(value)
 
 	//【4】提取结果
 	err := thisConn.Error
@@ -190,11 +200,11 @@ func (mng *MysqlMng) Update(update UpdateInterface) error {
  * 			[data] interface{} 数据
  * 			[statusCode] 状态码
  */
-func (mng *MysqlMng) CreateOne(insert InsertInterface) {
+func (mng *MysqlMng) CreateOneCtx(ctx context.Context, insert InsertInterface) {
 
 	//【1】初始化参数
 	row := insert.GetRow()
-	thisConn := mng.GetConn()
+	thisConn := mng.GetCtxConn(ctx)
 	thisConn = thisConn.Create(row)
 
 	//【2】提取结果
@@ -217,20 +227,19 @@ func (mng *MysqlMng) CreateOne(insert InsertInterface) {
  *          [newsID]  int 新闻的ID
  * @return: [err] error 错误信息
  */
-func (mng *MysqlMng) Delete(params DeleteInterface) error {
+func (mng *MysqlMng) DeleteCtx(ctx context.Context, params DeleteInterface) error {
 
 	//【1】初始化参数
 	condition := params.GetCondition()
 	row := params.GetRow()
-	thisConn := mng.GetConn()
+	thisConn := mng.GetCtxConn(ctx)
 
 	//【2】拼接
-	if len(condition) == 0 {
-		return errors.New("条件不允许为空")
+	thisConn, hasCondition := applyCondition(thisConn, params, condition)
+	if !hasCondition {
+		return errEmptyCondition
 	}
-
-	cons, vals, _ := WhereBuild(condition)
-	thisConn = thisConn.Where(cons, vals...).Delete(row)
+	thisConn = thisConn.Delete(row)
 
 	//【2】提取结果
 	err := thisConn.Error
@@ -252,10 +261,10 @@ func (mng *MysqlMng) Delete(params DeleteInterface) error {
  * 			[data] interface{} 数据
  * 			[statusCode] 状态码
  */
-func (mng *MysqlMng) SimpleGetListWithLog(read ReadInterface, userID, authID int) (msg string, data interface{}, statusCode int) {
+func (mng *MysqlMng) SimpleGetListWithLogCtx(ctx context.Context, read ReadInterface, userID, authID int) (msg string, data interface{}, statusCode int) {
 
 	//【3】查询
-	mng.LogRead(read, userID, authID)
+	mng.LogReadCtx(ctx, read, userID, authID)
 	if read.GetError() != nil {
 		return read.GetError().Error(), nil, 400
 	}
@@ -267,11 +276,11 @@ func (mng *MysqlMng) SimpleGetListWithLog(read ReadInterface, userID, authID int
 	}, 200
 }
 
-// SimpleGetDetailWithLog 简单获取记录
-func (mng *MysqlMng) SimpleGetDetailWithLog(params ReadInterface, userID, authID int) (msg string, data interface{}, statusCode int) {
+// SimpleGetDetailWithLogCtx 简单获取记录
+func (mng *MysqlMng) SimpleGetDetailWithLogCtx(ctx context.Context, params ReadInterface, userID, authID int) (msg string, data interface{}, statusCode int) {
 
 	//【2】查询
-	mng.LogRead(params, userID, authID)
+	mng.LogReadCtx(ctx, params, userID, authID)
 	if params.GetError() != nil {
 		return params.GetError().Error(), nil, 400
 	}
@@ -280,11 +289,11 @@ func (mng *MysqlMng) SimpleGetDetailWithLog(params ReadInterface, userID, authID
 	return "ok", params.GetRows(), 200
 }
 
-// SimpleUpdate 简单修改
-func (mng *MysqlMng) SimpleUpdate(params UpdateInterface) (msg string, data interface{}, statusCode int) {
+// SimpleUpdateCtx 简单修改
+func (mng *MysqlMng) SimpleUpdateCtx(ctx context.Context, params UpdateInterface) (msg string, data interface{}, statusCode int) {
 
 	//【1】修改
-	err := mng.Update(params)
+	err := mng.UpdateCtx(ctx, params)
 	if err != nil {
 		return err.Error(), nil, 400
 	}
@@ -293,11 +302,11 @@ func (mng *MysqlMng) SimpleUpdate(params UpdateInterface) (msg string, data inte
 	return "ok", params.GetRowsAffected(), 201
 }
 
-// SimpleUpdateMany 简单修改多条
-func (mng *MysqlMng) SimpleUpdateMany(params UpdateInterface) (msg string, data interface{}, statusCode int) {
+// SimpleUpdateManyCtx 简单修改多条
+func (mng *MysqlMng) SimpleUpdateManyCtx(ctx context.Context, params UpdateInterface) (msg string, data interface{}, statusCode int) {
 
 	//【2】修改
-	err := mng.Update(params)
+	err := mng.UpdateCtx(ctx, params)
 	if err != nil {
 		return err.Error(), nil, 400
 	}
@@ -305,11 +314,11 @@ func (mng *MysqlMng) SimpleUpdateMany(params UpdateInterface) (msg string, data
 	return "ok", params.GetRowsAffected(), 201
 }
 
-// SimpleCreateOne 简单插入
-func (mng *MysqlMng) SimpleCreateOne(params InsertInterface) (msg string, data interface{}, statusCode int) {
+// SimpleCreateOneCtx 简单插入
+func (mng *MysqlMng) SimpleCreateOneCtx(ctx context.Context, params InsertInterface) (msg string, data interface{}, statusCode int) {
 
 	//【1】写入数据库
-	mng.CreateOne(params)
+	mng.CreateOneCtx(ctx, params)
 	if err := params.GetError(); err != nil {
 		return err.Error(), nil, 400
 	}
@@ -318,11 +327,11 @@ func (mng *MysqlMng) SimpleCreateOne(params InsertInterface) (msg string, data i
 	return "ok", params.GetNewID(), 201
 }
 
-// SimpleDelete 简单删除
-func (mng *MysqlMng) SimpleDelete(params DeleteInterface) (msg string, data interface{}, statusCode int) {
+// SimpleDeleteCtx 简单删除
+func (mng *MysqlMng) SimpleDeleteCtx(ctx context.Context, params DeleteInterface) (msg string, data interface{}, statusCode int) {
 
 	//【2】写入数据库
-	_ = mng.Delete(params)
+	_ = mng.DeleteCtx(ctx, params)
 	if err := params.GetError(); err != nil {
 		return err.Error(), nil, 400
 	}
@@ -331,11 +340,11 @@ func (mng *MysqlMng) SimpleDelete(params DeleteInterface) (msg string, data inte
 	return "ok", params.GetRowsAffected(), 200
 }
 
-// SimpleGetList 简单获取列表
-func (mng *MysqlMng) SimpleGetList(read ReadInterface, isSingle, doCount bool) (msg string, data interface{}, statusCode int) {
+// SimpleGetListCtx 简单获取列表
+func (mng *MysqlMng) SimpleGetListCtx(ctx context.Context, read ReadInterface, isSingle, doCount bool) (msg string, data interface{}, statusCode int) {
 
 	//【1】查询
-	mng.Read(read, isSingle, doCount)
+	mng.ReadCtx(ctx, read, isSingle, doCount)
 	if read.GetError() != nil {
 		return read.GetError().Error(), nil, 400
 	}
@@ -354,11 +363,11 @@ func (mng *MysqlMng) SimpleGetList(read ReadInterface, isSingle, doCount bool) (
 	return "ok", data, 200
 }
 
-// SimpleGetDetail 简单获取详情
-func (mng *MysqlMng) SimpleGetDetail(params ReadInterface) (msg string, data interface{}, statusCode int) {
+// SimpleGetDetailCtx 简单获取详情
+func (mng *MysqlMng) SimpleGetDetailCtx(ctx context.Context, params ReadInterface) (msg string, data interface{}, statusCode int) {
 
 	//【1】查询
-	mng.Read(params, true, false)
+	mng.ReadCtx(ctx, params, true, false)
 	if params.GetError() != nil {
 		return params.GetError().Error(), nil, 400
 	}
@@ -367,10 +376,10 @@ func (mng *MysqlMng) SimpleGetDetail(params ReadInterface) (msg string, data int
 	return "ok", params.GetRow(), 200
 }
 
-// SimpleCount 简单获取数量
-func (mng *MysqlMng) SimpleCount(params ReadInterface) (msg string, data interface{}, statusCode int) {
+// SimpleCountCtx 简单获取数量
+func (mng *MysqlMng) SimpleCountCtx(ctx context.Context, params ReadInterface) (msg string, data interface{}, statusCode int) {
 	//【1】查询
-	mng.Count(params)
+	mng.CountCtx(ctx, params)
 	if params.GetError() != nil {
 		return params.GetError().Error(), nil, 400
 	}
@@ -426,3 +435,112 @@ func getFieldName(targetField string) (string, error) {
 	}
 	return fieldName, err
 }
+
+// -------BEGIN------不带 ctx 的旧签名-----BEGIN--------
+
+// Read 同 ReadCtx，使用 context.Background()，不会加入 WithTx 开启的事务
+//
+// Deprecated: 请使用 ReadCtx
+func (mng *MysqlMng) Read(list ReadInterface, isSingle, doCount bool) (err error) {
+	return mng.ReadCtx(context.Background(), list, isSingle, doCount)
+}
+
+// Count 同 CountCtx
+//
+// Deprecated: 请使用 CountCtx
+func (mng *MysqlMng) Count(list ReadInterface) (count int64, err error) {
+	return mng.CountCtx(context.Background(), list)
+}
+
+// SumFloat64 同 SumFloat64Ctx
+//
+// Deprecated: 请使用 SumFloat64Ctx
+func (mng *MysqlMng) SumFloat64(model DBStructInterface, sumField string, condition map[string]interface{}) (sum float64, err error) {
+	return mng.SumFloat64Ctx(context.Background(), model, sumField, condition)
+}
+
+// Update 同 UpdateCtx
+//
+// Deprecated: 请使用 UpdateCtx
+func (mng *MysqlMng) Update(update UpdateInterface) error {
+	return mng.UpdateCtx(context.Background(), update)
+}
+
+// CreateOne 同 CreateOneCtx
+//
+// Deprecated: 请使用 CreateOneCtx
+func (mng *MysqlMng) CreateOne(insert InsertInterface) {
+	mng.CreateOneCtx(context.Background(), insert)
+}
+
+// Delete 同 DeleteCtx
+//
+// Deprecated: 请使用 DeleteCtx
+func (mng *MysqlMng) Delete(params DeleteInterface) error {
+	return mng.DeleteCtx(context.Background(), params)
+}
+
+// SimpleGetListWithLog 同 SimpleGetListWithLogCtx
+//
+// Deprecated: 请使用 SimpleGetListWithLogCtx
+func (mng *MysqlMng) SimpleGetListWithLog(read ReadInterface, userID, authID int) (msg string, data interface{}, statusCode int) {
+	return mng.SimpleGetListWithLogCtx(context.Background(), read, userID, authID)
+}
+
+// SimpleGetDetailWithLog 同 SimpleGetDetailWithLogCtx
+//
+// Deprecated: 请使用 SimpleGetDetailWithLogCtx
+func (mng *MysqlMng) SimpleGetDetailWithLog(params ReadInterface, userID, authID int) (msg string, data interface{}, statusCode int) {
+	return mng.SimpleGetDetailWithLogCtx(context.Background(), params, userID, authID)
+}
+
+// SimpleUpdate 同 SimpleUpdateCtx
+//
+// Deprecated: 请使用 SimpleUpdateCtx
+func (mng *MysqlMng) SimpleUpdate(params UpdateInterface) (msg string, data interface{}, statusCode int) {
+	return mng.SimpleUpdateCtx(context.Background(), params)
+}
+
+// SimpleUpdateMany 同 SimpleUpdateManyCtx
+//
+// Deprecated: 请使用 SimpleUpdateManyCtx
+func (mng *MysqlMng) SimpleUpdateMany(params UpdateInterface) (msg string, data interface{}, statusCode int) {
+	return mng.SimpleUpdateManyCtx(context.Background(), params)
+}
+
+// SimpleCreateOne 同 SimpleCreateOneCtx
+//
+// Deprecated: 请使用 SimpleCreateOneCtx
+func (mng *MysqlMng) SimpleCreateOne(params InsertInterface) (msg string, data interface{}, statusCode int) {
+	return mng.SimpleCreateOneCtx(context.Background(), params)
+}
+
+// SimpleDelete 同 SimpleDeleteCtx
+//
+// Deprecated: 请使用 SimpleDeleteCtx
+func (mng *MysqlMng) SimpleDelete(params DeleteInterface) (msg string, data interface{}, statusCode int) {
+	return mng.SimpleDeleteCtx(context.Background(), params)
+}
+
+// SimpleGetList 同 SimpleGetListCtx
+//
+// Deprecated: 请使用 SimpleGetListCtx
+func (mng *MysqlMng) SimpleGetList(read ReadInterface, isSingle, doCount bool) (msg string, data interface{}, statusCode int) {
+	return mng.SimpleGetListCtx(context.Background(), read, isSingle, doCount)
+}
+
+// SimpleGetDetail 同 SimpleGetDetailCtx
+//
+// Deprecated: 请使用 SimpleGetDetailCtx
+func (mng *MysqlMng) SimpleGetDetail(params ReadInterface) (msg string, data interface{}, statusCode int) {
+	return mng.SimpleGetDetailCtx(context.Background(), params)
+}
+
+// SimpleCount 同 SimpleCountCtx
+//
+// Deprecated: 请使用 SimpleCountCtx
+func (mng *MysqlMng) SimpleCount(params ReadInterface) (msg string, data interface{}, statusCode int) {
+	return mng.SimpleCountCtx(context.Background(), params)
+}
+
+// -------END------不带 ctx 的旧签名----END---------
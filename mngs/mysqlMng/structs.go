@@ -1,5 +1,10 @@
 package mysqlMng
 
+import (
+	"github.com/wiidz/goutil/helpers/condHelper"
+	"github.com/wiidz/goutil/helpers/cursorHelper"
+)
+
 // NullType 用于判断是否是null值
 type NullType byte
 
@@ -59,13 +64,16 @@ type ReadInterface interface {
 type Read struct {
 	BaseStruct
 	Condition map[string]interface{}
-	PageNow   int    `json:"page_now" belong:"etc" default:"1"`
-	PageSize  int    `json:"page_size" belong:"etc" default:"10"`
-	Order     string `json:"order" belong:"etc" default:"ids asc"`
+	Where     condHelper.Cond // 类型化条件，与 Condition 同时存在时取交集
+	PageNow   int             `json:"page_now" belong:"etc" default:"1"`
+	PageSize  int             `json:"page_size" belong:"etc" default:"10"`
+	Order     string          `json:"order" belong:"etc" default:"ids asc"`
 	Single    bool
 	Preloads  []string
 	Rows      interface{}
 	Count     int64
+
+	cursorHelper.Request // 游标分页，设置 UseCursor 或 Cursor 后生效
 }
 
 func (read *Read) GetOrder() string {
@@ -80,6 +88,9 @@ func (read *Read) GetCount() int64 {
 func (read *Read) GetCondition() map[string]interface{} {
 	return read.Condition
 }
+func (read *Read) GetWhere() condHelper.Cond {
+	return read.Where
+}
 func (read *Read) GetOffset() int {
 	var offset int
 	if read.PageNow > 1 {
@@ -213,6 +224,7 @@ type Update struct {
 	BaseStruct
 	TableName string
 	Condition map[string]interface{}
+	Where     condHelper.Cond // 类型化条件
 	Value     map[string]interface{}
 	RawMap    map[string]interface{}
 }
@@ -226,6 +238,9 @@ func (update *Update) GetTableName() string {
 func (update *Update) GetCondition() map[string]interface{} {
 	return update.Condition
 }
+func (update *Update) GetWhere() condHelper.Cond {
+	return update.Where
+}
 func (update *Update) GetValue() map[string]interface{} {
 	return update.Value
 }
@@ -270,6 +285,7 @@ type DeleteInterface interface {
 type Delete struct {
 	BaseStruct
 	Condition map[string]interface{}
+	Where     condHelper.Cond // 类型化条件
 	Row       interface{}
 	RawMap    map[string]interface{}
 }
@@ -280,6 +296,9 @@ func (delete *Delete) GetRawMap() map[string]interface{} {
 func (delete *Delete) GetCondition() map[string]interface{} {
 	return delete.Condition
 }
+func (delete *Delete) GetWhere() condHelper.Cond {
+	return delete.Where
+}
 func (delete *Delete) GetRow() interface{} {
 	return delete.Row
 }
@@ -0,0 +1,91 @@
+package mysqlMng
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// txKey 事务在 context 中的键，按管理器区分，避免多个库的事务互相串用
+type txKey struct {
+	mng *MysqlMng
+}
+
+// txState context 中携带的事务
+type txState struct {
+	db    *gorm.DB
+	depth int // 嵌套层数，0 为最外层事务
+}
+
+// WithTx 在事务中执行 fn，事务随 ctx 传递
+// fn 返回错误或 panic 时回滚，否则提交；ctx 中已有事务时使用保存点嵌套，内层失败只回滚到保存点
+// 同一个事务只占用一个连接，不要在 fn 内并发使用同一个 ctx 读写数据库
+func (mng *MysqlMng) WithTx(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if parent, ok := ctx.Value(txKey{mng}).(*txState); ok {
+		return mng.withSavePoint(ctx, parent, fn)
+	}
+
+	tx := mng.GetConn().WithContext(ctx).Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	txCtx := context.WithValue(ctx, txKey{mng}, &txState{db: tx})
+
+	panicked := true
+	defer func() {
+		if panicked || err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit().Error
+	}()
+
+	err = fn(txCtx)
+	panicked = false
+	return
+}
+
+// withSavePoint 嵌套事务，通过保存点实现局部回滚
+func (mng *MysqlMng) withSavePoint(ctx context.Context, parent *txState, fn func(ctx context.Context) error) (err error) {
+	depth := parent.depth + 1
+	name := fmt.Sprintf("sp_%d", depth)
+	if err = parent.db.SavePoint(name).Error; err != nil {
+		return err
+	}
+	txCtx := context.WithValue(ctx, txKey{mng}, &txState{db: parent.db, depth: depth})
+
+	panicked := true
+	defer func() {
+		if panicked || err != nil {
+			parent.db.RollbackTo(name)
+		}
+	}()
+
+	err = fn(txCtx)
+	panicked = false
+	return
+}
+
+// TxFromContext 取出 ctx 中属于当前管理器的事务
+func (mng *MysqlMng) TxFromContext(ctx context.Context) (*gorm.DB, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	state, ok := ctx.Value(txKey{mng}).(*txState)
+	if !ok {
+		return nil, false
+	}
+	return state.db.WithContext(ctx), true
+}
+
+// GetCtxConn ctx 中有事务时返回事务会话，否则返回一个新的普通会话
+func (mng *MysqlMng) GetCtxConn(ctx context.Context) *gorm.DB {
+	if tx, ok := mng.TxFromContext(ctx); ok {
+		return tx
+	}
+	if ctx == nil {
+		return mng.GetConn()
+	}
+	return mng.GetConn().WithContext(ctx)
+}
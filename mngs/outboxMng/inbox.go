@@ -0,0 +1,86 @@
+package outboxMng
+
+import (
+	"context"
+	"time"
+
+	"github.com/streadway/amqp"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultInboxTable 收件箱默认表名
+const DefaultInboxTable = "a_inbox"
+
+// InboxRecord 收件箱表结构，(consumer, message_id) 唯一
+type InboxRecord struct {
+	ID        uint64    `gorm:"primaryKey;column:id" json:"id"`
+	Consumer  string    `gorm:"column:consumer;type:varchar(128);not null;uniqueIndex:uk_consumer_message" json:"consumer"`
+	MessageID string    `gorm:"column:message_id;type:varchar(64);not null;uniqueIndex:uk_consumer_message" json:"message_id"`
+	CreatedAt time.Time `gorm:"column:created_at;index" json:"created_at"`
+}
+
+// Inbox 收件箱：消费端按消息ID去重，去重记录与业务写库在同一事务中提交
+type Inbox struct {
+	db    *gorm.DB
+	table string
+}
+
+// NewInbox 创建收件箱，table 为空时使用 a_inbox，并自动建表
+func NewInbox(db *gorm.DB, table string) (*Inbox, error) {
+	if db == nil {
+		return nil, gorm.ErrInvalidDB
+	}
+	if table == "" {
+		table = DefaultInboxTable
+	}
+	in := &Inbox{db: db.Session(&gorm.Session{NewDB: true}), table: table}
+	if err := in.db.Table(table).AutoMigrate(&InboxRecord{}); err != nil {
+		return nil, err
+	}
+	return in, nil
+}
+
+// TableName 收件箱表名
+func (in *Inbox) TableName() string {
+	return in.table
+}
+
+// Process 在事务中登记消息并执行 fn；同一 consumer 已处理过该消息时跳过 fn，duplicate 为 true。
+// fn 返回错误时登记一并回滚，消息重投后会再次处理
+func (in *Inbox) Process(ctx context.Context, consumer, messageID string, fn func(tx *gorm.DB) error) (duplicate bool, err error) {
+	err = in.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Table(in.table).
+			Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&InboxRecord{Consumer: consumer, MessageID: messageID, CreatedAt: time.Now()})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			duplicate = true
+			return nil
+		}
+		return fn(tx)
+	})
+	return
+}
+
+// Handler 包装为 amqpMng 的消费函数，按 Delivery.MessageId 去重；
+// 没有 MessageId 的消息无法去重，直接在事务中执行 fn
+func (in *Inbox) Handler(consumer string, fn func(tx *gorm.DB, d amqp.Delivery) error) func(d amqp.Delivery) error {
+	return func(d amqp.Delivery) error {
+		if d.MessageId == "" {
+			return in.db.Transaction(func(tx *gorm.DB) error { return fn(tx, d) })
+		}
+		_, err := in.Process(context.Background(), consumer, d.MessageId, func(tx *gorm.DB) error {
+			return fn(tx, d)
+		})
+		return err
+	}
+}
+
+// Purge 删除 before 之前的去重记录，保留时间应长于消息可能重投的时间
+func (in *Inbox) Purge(ctx context.Context, before time.Time) (int64, error) {
+	res := in.db.WithContext(ctx).Table(in.table).Where("created_at < ?", before).Delete(&InboxRecord{})
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,141 @@
+package outboxMng
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DefaultOutboxTable 发件箱默认表名
+const DefaultOutboxTable = "a_outbox"
+
+// Status 发件箱消息状态
+type Status int8
+
+const (
+	StatusPending Status = 0 // 待发送
+	StatusSent    Status = 1 // 已发送（broker 已确认）
+	StatusFailed  Status = 2 // 超过最大尝试次数，不再发送
+)
+
+// Headers 消息头，以 JSON 文本存储
+type Headers map[string]interface{}
+
+// Value driver.Valuer
+func (h Headers) Value() (driver.Value, error) {
+	if len(h) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(h)
+	return string(b), err
+}
+
+// Scan sql.Scanner
+func (h *Headers) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*h = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, h)
+	case string:
+		return json.Unmarshal([]byte(v), h)
+	}
+	return fmt.Errorf("outboxMng: cannot scan %T into Headers", value)
+}
+
+// Message 发件箱表结构
+type Message struct {
+	ID            uint64     `gorm:"primaryKey;column:id" json:"id"`
+	MessageID     string     `gorm:"column:message_id;type:varchar(64);not null;uniqueIndex" json:"message_id"` // 投递时作为 amqp MessageId，供收件箱去重
+	Topic         string     `gorm:"column:topic;type:varchar(128);not null;index" json:"topic"`                // Relay 按 topic 选择发布者
+	ContentType   string     `gorm:"column:content_type;type:varchar(64)" json:"content_type"`
+	Headers       Headers    `gorm:"column:headers;type:text" json:"headers"`
+	Body          string     `gorm:"column:body;type:text" json:"body"`
+	Status        Status     `gorm:"column:status;not null;default:0;index:idx_status_next" json:"status"`
+	Attempts      int        `gorm:"column:attempts;not null;default:0" json:"attempts"`                  // 发送失败次数
+	LastError     string     `gorm:"column:last_error;type:varchar(1024)" json:"last_error"`              // 最后一次发送错误
+	NextAttemptAt time.Time  `gorm:"column:next_attempt_at;index:idx_status_next" json:"next_attempt_at"` // 下次可发送时间
+	CreatedAt     time.Time  `gorm:"column:created_at" json:"created_at"`
+	SentAt        *time.Time `gorm:"column:sent_at;index" json:"sent_at"`
+}
+
+// NewMessage 创建消息，payload 为 string / []byte 时原样作为 body，否则编码为 JSON
+func NewMessage(topic string, payload interface{}) (*Message, error) {
+	msg := &Message{Topic: topic}
+	switch v := payload.(type) {
+	case string:
+		msg.Body, msg.ContentType = v, "text/plain"
+	case []byte:
+		msg.Body, msg.ContentType = string(v), "application/octet-stream"
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		msg.Body, msg.ContentType = string(b), "application/json"
+	}
+	return msg, nil
+}
+
+// Outbox 发件箱：业务写库与事件写入同一事务，由 Relay 异步发布
+type Outbox struct {
+	db    *gorm.DB
+	table string
+}
+
+// NewOutbox 创建发件箱，table 为空时使用 a_outbox，并自动建表
+func NewOutbox(db *gorm.DB, table string) (*Outbox, error) {
+	if db == nil {
+		return nil, gorm.ErrInvalidDB
+	}
+	if table == "" {
+		table = DefaultOutboxTable
+	}
+	o := &Outbox{db: db.Session(&gorm.Session{NewDB: true}), table: table}
+	if err := o.db.Table(table).AutoMigrate(&Message{}); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// TableName 发件箱表名
+func (o *Outbox) TableName() string {
+	return o.table
+}
+
+// Add 在 tx 中写入消息，tx 应为业务写库所在的事务（如 mysqlMng.TxFromContext 取出的会话），
+// 事务提交后消息才对 Relay 可见，回滚则一并撤销
+func (o *Outbox) Add(tx *gorm.DB, msgs ...*Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	now := time.Now()
+	for _, msg := range msgs {
+		if msg.MessageID == "" {
+			msg.MessageID = uuid.NewString()
+		}
+		msg.Status = StatusPending
+		msg.NextAttemptAt = now
+		if msg.CreatedAt.IsZero() {
+			msg.CreatedAt = now
+		}
+	}
+	return tx.Table(o.table).Create(msgs).Error
+}
+
+// Publish 创建消息并在 tx 中写入
+func (o *Outbox) Publish(tx *gorm.DB, topic string, payload interface{}) (*Message, error) {
+	msg, err := NewMessage(topic, payload)
+	if err != nil {
+		return nil, err
+	}
+	if err = o.Add(tx, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
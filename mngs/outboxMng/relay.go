@@ -0,0 +1,317 @@
+package outboxMng
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/streadway/amqp"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Publisher Relay 使用的发布者，*amqpMng.RabbitMQ 已实现
+type Publisher interface {
+	PublishMessage(ctx context.Context, pub amqp.Publishing, reliable bool) error
+}
+
+// RelayOption Relay 配置
+type RelayOption func(*relayOptions)
+
+type relayOptions struct {
+	batchSize      int
+	pollInterval   time.Duration
+	publishTimeout time.Duration
+	maxAttempts    int
+	minBackoff     time.Duration
+	maxBackoff     time.Duration
+	retention      time.Duration
+	claimLease     time.Duration
+}
+
+// WithBatchSize 每轮最多发送条数，默认 100
+func WithBatchSize(n int) RelayOption {
+	return func(o *relayOptions) { o.batchSize = n }
+}
+
+// WithPollInterval 轮询间隔，默认 1 秒；Wake 可立即触发一轮
+func WithPollInterval(d time.Duration) RelayOption {
+	return func(o *relayOptions) { o.pollInterval = d }
+}
+
+// WithPublishTimeout 单条消息等待 broker 确认的上限，默认 10 秒
+func WithPublishTimeout(d time.Duration) RelayOption {
+	return func(o *relayOptions) { o.publishTimeout = d }
+}
+
+// WithClaimLease 认领一批消息后的租约，超时未回写的消息会被其他实例重新认领，默认 5 分钟；
+// 不足一次 publishTimeout 时按 publishTimeout 的两倍
+func WithClaimLease(d time.Duration) RelayOption {
+	return func(o *relayOptions) { o.claimLease = d }
+}
+
+// WithMaxAttempts 最多发送次数，超出后标记为 StatusFailed，默认 10；<=0 不限
+func WithMaxAttempts(n int) RelayOption {
+	return func(o *relayOptions) { o.maxAttempts = n }
+}
+
+// WithBackoff 发送失败后的重试间隔，从 min 开始翻倍，最多 max，默认 1 秒 ~ 5 分钟
+func WithBackoff(min, max time.Duration) RelayOption {
+	return func(o *relayOptions) { o.minBackoff, o.maxBackoff = min, max }
+}
+
+// WithRetention 已发送消息的保留时间，超出后删除，默认 7 天；<=0 不删除
+func WithRetention(d time.Duration) RelayOption {
+	return func(o *relayOptions) { o.retention = d }
+}
+
+// Relay 发件箱中继：轮询待发送消息，以 publisher confirm 发布，确认后标记为已发送。
+// 多个实例可同时运行，通过 SELECT ... FOR UPDATE SKIP LOCKED 认领消息（mysql 8+ / postgres），认领后即提交，发布时不持有锁。
+// 投递语义为至少一次：确认后、标记前进程退出，或租约到期后被其他实例重新认领，都会重复发送，消费端用 Inbox 去重
+type Relay struct {
+	outbox *Outbox
+	opts   relayOptions
+
+	mu         sync.RWMutex
+	publishers map[string]Publisher
+
+	wake chan struct{}
+}
+
+// NewRelay 创建中继，用 Route 登记发布者后调用 Run
+func (o *Outbox) NewRelay(opts ...RelayOption) *Relay {
+	ro := relayOptions{
+		batchSize:      100,
+		pollInterval:   time.Second,
+		publishTimeout: 10 * time.Second,
+		maxAttempts:    10,
+		minBackoff:     time.Second,
+		maxBackoff:     5 * time.Minute,
+		retention:      7 * 24 * time.Hour,
+		claimLease:     5 * time.Minute,
+	}
+	for _, opt := range opts {
+		opt(&ro)
+	}
+	if ro.batchSize <= 0 {
+		ro.batchSize = 100
+	}
+	if ro.pollInterval <= 0 {
+		ro.pollInterval = time.Second
+	}
+	if ro.publishTimeout <= 0 {
+		ro.publishTimeout = 10 * time.Second
+	}
+	if ro.claimLease < 2*ro.publishTimeout {
+		ro.claimLease = 2 * ro.publishTimeout
+	}
+	if ro.minBackoff <= 0 {
+		ro.minBackoff = time.Second
+	}
+	if ro.maxBackoff < ro.minBackoff {
+		ro.maxBackoff = ro.minBackoff
+	}
+	return &Relay{
+		outbox:     o,
+		opts:       ro,
+		publishers: map[string]Publisher{},
+		wake:       make(chan struct{}, 1),
+	}
+}
+
+// Route topic 的消息交给 p 发布；topic 为空表示默认发布者
+func (r *Relay) Route(topic string, p Publisher) *Relay {
+	r.mu.Lock()
+	r.publishers[topic] = p
+	r.mu.Unlock()
+	return r
+}
+
+func (r *Relay) publisher(topic string) (Publisher, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if p, ok := r.publishers[topic]; ok {
+		return p, true
+	}
+	p, ok := r.publishers[""]
+	return p, ok
+}
+
+// Wake 立即触发一轮发送，可在事务提交后调用以降低延迟
+func (r *Relay) Wake() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run 循环发送直到 ctx 结束
+func (r *Relay) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.opts.pollInterval)
+	defer ticker.Stop()
+	var lastPurge time.Time
+
+	for {
+		// 一轮取满说明可能还有积压，继续发送
+		for {
+			n, err := r.RelayOnce(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				log.Println("【outboxMng】relay err:", err)
+				break
+			}
+			if n < r.opts.batchSize {
+				break
+			}
+		}
+		if r.opts.retention > 0 && time.Since(lastPurge) > time.Hour {
+			lastPurge = time.Now()
+			if _, err := r.outbox.Purge(ctx, time.Now().Add(-r.opts.retention)); err != nil && ctx.Err() == nil {
+				log.Println("【outboxMng】purge err:", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		case <-r.wake:
+		}
+	}
+}
+
+// RelayOnce 发送一批到期的待发送消息，返回本批条数。
+// 先在短事务内认领（next_attempt_at 推迟到租约结束）并提交，再在事务外逐条发布并回写结果，
+// 发布等待 broker 确认期间不持有行锁
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	msgs, leaseUntil, err := r.claim(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, msg := range msgs {
+		// 剩余租约不足一次发布，剩下的留待租约到期后重新认领
+		if time.Now().Add(r.opts.publishTimeout).After(leaseUntil) {
+			break
+		}
+		updates := r.send(ctx, msg)
+		// attempts 未变说明期间没有其他实例回写过，避免覆盖其结果
+		err = r.outbox.db.WithContext(context.WithoutCancel(ctx)).Table(r.outbox.table).
+			Where("id = ? AND status = ? AND attempts = ?", msg.ID, StatusPending, msg.Attempts).
+			Updates(updates).Error
+		if err != nil {
+			return len(msgs), err
+		}
+	}
+	return len(msgs), nil
+}
+
+// claim 锁定一批到期消息并把 next_attempt_at 推迟到租约结束，其他实例在此之前不会再取到
+func (r *Relay) claim(ctx context.Context) ([]*Message, time.Time, error) {
+	var msgs []*Message
+	leaseUntil := time.Now().Add(r.opts.claimLease)
+	err := r.outbox.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		query := tx.Table(r.outbox.table)
+		if tx.Dialector.Name() != "sqlite" {
+			query = query.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"})
+		}
+		err := query.
+			Where("status = ? AND next_attempt_at <= ?", StatusPending, time.Now()).
+			Order("id").
+			Limit(r.opts.batchSize).
+			Find(&msgs).Error
+		if err != nil || len(msgs) == 0 {
+			return err
+		}
+
+		ids := make([]uint64, len(msgs))
+		for i, msg := range msgs {
+			ids[i] = msg.ID
+		}
+		return tx.Table(r.outbox.table).Where("id IN ?", ids).Update("next_attempt_at", leaseUntil).Error
+	})
+	if err != nil {
+		return nil, leaseUntil, err
+	}
+	return msgs, leaseUntil, nil
+}
+
+// send 发布一条消息，返回需要更新的列
+func (r *Relay) send(ctx context.Context, msg *Message) map[string]interface{} {
+	err := r.publish(ctx, msg)
+	if err == nil {
+		return map[string]interface{}{"status": StatusSent, "sent_at": time.Now(), "last_error": ""}
+	}
+
+	attempts := msg.Attempts + 1
+	lastError := err.Error()
+	if len(lastError) > 1024 {
+		lastError = lastError[:1024]
+	}
+	updates := map[string]interface{}{
+		"attempts":        attempts,
+		"last_error":      lastError,
+		"next_attempt_at": time.Now().Add(r.backoff(attempts)),
+	}
+	if r.opts.maxAttempts > 0 && attempts >= r.opts.maxAttempts {
+		updates["status"] = StatusFailed
+		log.Printf("【outboxMng】message %s failed after %d attempts: %v", msg.MessageID, attempts, err)
+	}
+	return updates
+}
+
+func (r *Relay) publish(ctx context.Context, msg *Message) error {
+	p, ok := r.publisher(msg.Topic)
+	if !ok {
+		return errors.New("outboxMng: no publisher for topic " + msg.Topic)
+	}
+	pub := amqp.Publishing{
+		Headers:      amqp.Table(msg.Headers),
+		ContentType:  msg.ContentType,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    msg.MessageID,
+		Timestamp:    msg.CreatedAt,
+		Type:         msg.Topic,
+		Body:         []byte(msg.Body),
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.opts.publishTimeout)
+	defer cancel()
+	return p.PublishMessage(ctx, pub, true)
+}
+
+// backoff 第 attempts 次失败后的等待时间
+func (r *Relay) backoff(attempts int) time.Duration {
+	d := r.opts.minBackoff
+	for i := 1; i < attempts && d < r.opts.maxBackoff; i++ {
+		d *= 2
+	}
+	if d > r.opts.maxBackoff {
+		d = r.opts.maxBackoff
+	}
+	return d
+}
+
+// Retry 将 StatusFailed 的消息重置为待发送
+func (o *Outbox) Retry(ctx context.Context, messageIDs ...string) (int64, error) {
+	query := o.db.WithContext(ctx).Table(o.table).Where("status = ?", StatusFailed)
+	if len(messageIDs) > 0 {
+		query = query.Where("message_id IN ?", messageIDs)
+	}
+	res := query.Updates(map[string]interface{}{
+		"status":          StatusPending,
+		"attempts":        0,
+		"next_attempt_at": time.Now(),
+	})
+	return res.RowsAffected, res.Error
+}
+
+// Purge 删除 before 之前已发送的消息
+func (o *Outbox) Purge(ctx context.Context, before time.Time) (int64, error) {
+	res := o.db.WithContext(ctx).Table(o.table).
+		Where("status = ? AND sent_at < ?", StatusSent, before).
+		Delete(&Message{})
+	return res.RowsAffected, res.Error
+}
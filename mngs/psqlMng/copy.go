@@ -0,0 +1,155 @@
+package psqlMng
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// CopyOptions text format of CopyFromReader / CopyTo
+type CopyOptions struct {
+	Format    string // csv (default) / text / binary
+	Header    bool   // csv header line
+	Delimiter string // single character, csv default ","
+}
+
+func (o *CopyOptions) clause() string {
+	opts := CopyOptions{Format: "csv"}
+	if o != nil {
+		opts = *o
+	}
+	format := strings.ToLower(opts.Format)
+	if format == "" {
+		format = "csv"
+	}
+	parts := []string{"FORMAT " + format}
+	if opts.Header && format == "csv" {
+		parts = append(parts, "HEADER true")
+	}
+	if opts.Delimiter != "" && format != "binary" {
+		parts = append(parts, "DELIMITER "+quoteLiteral(opts.Delimiter))
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+// CopyFromFunc streams rows into table with COPY FROM STDIN (binary protocol).
+// next returns the next row in column order and io.EOF when done.
+func (m *Manager) CopyFromFunc(ctx context.Context, table string, columns []string, next func() ([]any, error)) (int64, error) {
+	var n int64
+	err := m.withPgxConn(ctx, func(conn *pgx.Conn) (err error) {
+		n, err = conn.CopyFrom(ctx, tableIdentifier(table), columns, &funcSource{next: next})
+		return err
+	})
+	return n, err
+}
+
+// CopyFromRows COPY FROM for rows already in memory
+func (m *Manager) CopyFromRows(ctx context.Context, table string, columns []string, rows [][]any) (int64, error) {
+	var n int64
+	err := m.withPgxConn(ctx, func(conn *pgx.Conn) (err error) {
+		n, err = conn.CopyFrom(ctx, tableIdentifier(table), columns, pgx.CopyFromRows(rows))
+		return err
+	})
+	return n, err
+}
+
+// CopyFromReader streams a CSV / text file from r into table
+func (m *Manager) CopyFromReader(ctx context.Context, table string, columns []string, r io.Reader, opts *CopyOptions) (int64, error) {
+	sql := "COPY " + tableIdentifier(table).Sanitize()
+	if len(columns) > 0 {
+		sql += " (" + columnList(columns) + ")"
+	}
+	sql += " FROM STDIN WITH " + opts.clause()
+
+	var n int64
+	err := m.withPgxConn(ctx, func(conn *pgx.Conn) error {
+		tag, err := conn.PgConn().CopyFrom(ctx, r, sql)
+		n = tag.RowsAffected()
+		return err
+	})
+	return n, err
+}
+
+// CopyTo streams the result of query (or a whole table when query is a bare table name) to w
+func (m *Manager) CopyTo(ctx context.Context, w io.Writer, query string, opts *CopyOptions) (int64, error) {
+	source := strings.TrimSpace(query)
+	if strings.ContainsAny(source, " \t\n") {
+		source = "(" + source + ")"
+	} else {
+		source = tableIdentifier(source).Sanitize()
+	}
+	sql := "COPY " + source + " TO STDOUT WITH " + opts.clause()
+
+	var n int64
+	err := m.withPgxConn(ctx, func(conn *pgx.Conn) error {
+		tag, err := conn.PgConn().CopyTo(ctx, w, sql)
+		n = tag.RowsAffected()
+		return err
+	})
+	return n, err
+}
+
+// withPgxConn borrows a pool connection as *pgx.Conn; COPY does not join a gorm transaction
+func (m *Manager) withPgxConn(ctx context.Context, fn func(conn *pgx.Conn) error) error {
+	if m.db == nil {
+		return errors.New("psqlMng: db is nil")
+	}
+	sqlDB, err := m.db.DB()
+	if err != nil {
+		return err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return conn.Raw(func(driverConn any) error {
+		c, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("psqlMng: driver connection is %T, not pgx", driverConn)
+		}
+		return fn(c.Conn())
+	})
+}
+
+// funcSource adapts a next func to pgx.CopyFromSource
+type funcSource struct {
+	next func() ([]any, error)
+	row  []any
+	err  error
+}
+
+func (s *funcSource) Next() bool {
+	s.row, s.err = s.next()
+	if errors.Is(s.err, io.EOF) {
+		s.err = nil
+		return false
+	}
+	return s.err == nil
+}
+
+func (s *funcSource) Values() ([]any, error) { return s.row, nil }
+
+func (s *funcSource) Err() error { return s.err }
+
+// tableIdentifier schema.table => pgx.Identifier{schema, table}
+func tableIdentifier(table string) pgx.Identifier {
+	return pgx.Identifier(strings.Split(table, "."))
+}
+
+func columnList(columns []string) string {
+	quoted := make([]string, 0, len(columns))
+	for _, col := range columns {
+		quoted = append(quoted, pgx.Identifier{col}.Sanitize())
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
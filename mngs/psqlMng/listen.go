@@ -0,0 +1,287 @@
+package psqlMng
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"gorm.io/gorm"
+)
+
+// Notification a NOTIFY message
+type Notification struct {
+	Channel string
+	Payload string
+	PID     uint32 // backend PID of the notifying session
+}
+
+// ListenerConfig tunes a Listener; zero values use defaults
+type ListenerConfig struct {
+	MinBackoff  time.Duration // first reconnect delay, default 500ms
+	MaxBackoff  time.Duration // reconnect delay cap, default 30s
+	BufferSize  int           // per-subscription buffer, default 64; full buffers drop notifications
+	OnReconnect func()        // called after a reconnect; notifications sent while disconnected are lost
+}
+
+// Listener holds one dedicated connection for LISTEN and fans notifications out to subscriptions.
+// It reconnects with exponential backoff and re-issues LISTEN for every subscribed channel.
+type Listener struct {
+	dsn string
+	cfg ListenerConfig
+
+	mu     sync.Mutex
+	subs   map[string]map[*Subscription]struct{}
+	cancel context.CancelFunc // cancels the current wait so subscription changes are applied
+
+	dirty  atomic.Bool
+	stop   context.CancelFunc
+	done   chan struct{}
+	closed atomic.Bool
+}
+
+// Subscription receives notifications of its channels on C until Close
+type Subscription struct {
+	C <-chan *Notification
+
+	ch       chan *Notification
+	channels []string
+	listener *Listener
+	dropped  atomic.Int64
+	once     sync.Once
+}
+
+// Dropped notifications discarded because C was full
+func (s *Subscription) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Close unsubscribes; C is closed
+func (s *Subscription) Close() {
+	s.once.Do(func() { s.listener.remove(s) })
+}
+
+// NewListener starts a listener on its own connection (not taken from the pool)
+func (m *Manager) NewListener(cfg *ListenerConfig) *Listener {
+	l := &Listener{
+		dsn:  m.config.DSN,
+		subs: map[string]map[*Subscription]struct{}{},
+		done: make(chan struct{}),
+	}
+	if cfg != nil {
+		l.cfg = *cfg
+	}
+	if l.cfg.MinBackoff <= 0 {
+		l.cfg.MinBackoff = 500 * time.Millisecond
+	}
+	if l.cfg.MaxBackoff <= 0 {
+		l.cfg.MaxBackoff = 30 * time.Second
+	}
+	if l.cfg.BufferSize <= 0 {
+		l.cfg.BufferSize = 64
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	l.stop = stop
+	go l.run(ctx)
+	return l
+}
+
+// Notify sends a notification (pg_notify) right away on a pooled connection, outside any transaction;
+// use NotifyTx to send it when a transaction commits
+func (m *Manager) Notify(ctx context.Context, channel, payload string) error {
+	if m.db == nil {
+		return errors.New("psqlMng: db is nil")
+	}
+	return m.db.WithContext(ctx).Exec("SELECT pg_notify(?, ?)", channel, payload).Error
+}
+
+// NotifyTx queues a notification on tx; it is delivered at commit and dropped on rollback
+func NotifyTx(tx *gorm.DB, channel, payload string) error {
+	return tx.Exec("SELECT pg_notify(?, ?)", channel, payload).Error
+}
+
+// Subscribe receives notifications of channels; several subscriptions may share a channel
+func (l *Listener) Subscribe(channels ...string) (*Subscription, error) {
+	if l.closed.Load() {
+		return nil, errors.New("psqlMng: listener closed")
+	}
+	if len(channels) == 0 {
+		return nil, errors.New("psqlMng: no channel")
+	}
+	ch := make(chan *Notification, l.cfg.BufferSize)
+	sub := &Subscription{C: ch, ch: ch, channels: channels, listener: l}
+
+	l.mu.Lock()
+	for _, name := range channels {
+		if l.subs[name] == nil {
+			l.subs[name] = map[*Subscription]struct{}{}
+		}
+		l.subs[name][sub] = struct{}{}
+	}
+	l.mu.Unlock()
+	l.wake()
+	return sub, nil
+}
+
+// Close stops the listener and closes every subscription
+func (l *Listener) Close() {
+	if !l.closed.CompareAndSwap(false, true) {
+		return
+	}
+	l.stop()
+	<-l.done
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	closedSubs := map[*Subscription]struct{}{}
+	for _, subs := range l.subs {
+		for sub := range subs {
+			if _, ok := closedSubs[sub]; !ok {
+				closedSubs[sub] = struct{}{}
+				close(sub.ch)
+			}
+		}
+	}
+	l.subs = map[string]map[*Subscription]struct{}{}
+}
+
+func (l *Listener) remove(sub *Subscription) {
+	l.mu.Lock()
+	found := false
+	for _, name := range sub.channels {
+		if _, ok := l.subs[name][sub]; ok {
+			found = true
+			delete(l.subs[name], sub)
+		}
+		if len(l.subs[name]) == 0 {
+			delete(l.subs, name)
+		}
+	}
+	if found {
+		close(sub.ch)
+	}
+	l.mu.Unlock()
+	l.wake()
+}
+
+// wake interrupts the current wait so the run loop applies LISTEN / UNLISTEN
+func (l *Listener) wake() {
+	l.dirty.Store(true)
+	l.mu.Lock()
+	if l.cancel != nil {
+		l.cancel()
+	}
+	l.mu.Unlock()
+}
+
+func (l *Listener) wanted() map[string]struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	names := make(map[string]struct{}, len(l.subs))
+	for name := range l.subs {
+		names[name] = struct{}{}
+	}
+	return names
+}
+
+// run connect → LISTEN → wait, reconnecting on connection errors
+func (l *Listener) run(ctx context.Context) {
+	defer close(l.done)
+	backoff := l.cfg.MinBackoff
+	reconnect := false
+	for ctx.Err() == nil {
+		conn, err := pgx.Connect(ctx, l.dsn)
+		if err == nil {
+			if reconnect && l.cfg.OnReconnect != nil {
+				l.cfg.OnReconnect()
+			}
+			backoff = l.cfg.MinBackoff
+			err = l.serve(ctx, conn)
+			_ = conn.Close(context.Background())
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		log.Println("【psqlMng】listener disconnected:", err)
+		reconnect = true
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > l.cfg.MaxBackoff {
+			backoff = l.cfg.MaxBackoff
+		}
+	}
+}
+
+// serve runs on one connection until it breaks or ctx is done
+func (l *Listener) serve(ctx context.Context, conn *pgx.Conn) error {
+	listening := map[string]struct{}{}
+	l.dirty.Store(true)
+	for {
+		waitCtx, cancel := context.WithCancel(ctx)
+		l.mu.Lock()
+		l.cancel = cancel
+		l.mu.Unlock()
+
+		if l.dirty.Swap(false) {
+			if err := l.sync(ctx, conn, listening); err != nil {
+				cancel()
+				return err
+			}
+		}
+
+		n, err := conn.WaitForNotification(waitCtx)
+		cancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if waitCtx.Err() != nil {
+				continue // woken up for a subscription change
+			}
+			return err
+		}
+		l.dispatch(&Notification{Channel: n.Channel, Payload: n.Payload, PID: n.PID})
+	}
+}
+
+// sync LISTEN new channels and UNLISTEN channels nobody subscribes to any more
+func (l *Listener) sync(ctx context.Context, conn *pgx.Conn, listening map[string]struct{}) error {
+	wanted := l.wanted()
+	for name := range wanted {
+		if _, ok := listening[name]; ok {
+			continue
+		}
+		if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{name}.Sanitize()); err != nil {
+			return err
+		}
+		listening[name] = struct{}{}
+	}
+	for name := range listening {
+		if _, ok := wanted[name]; ok {
+			continue
+		}
+		if _, err := conn.Exec(ctx, "UNLISTEN "+pgx.Identifier{name}.Sanitize()); err != nil {
+			return err
+		}
+		delete(listening, name)
+	}
+	return nil
+}
+
+func (l *Listener) dispatch(n *Notification) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for sub := range l.subs[n.Channel] {
+		select {
+		case sub.ch <- n:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package psqlMng
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrLockNotAcquired TryAdvisoryLock found the lock held elsewhere
+var ErrLockNotAcquired = errors.New("psqlMng: advisory lock not acquired")
+
+// LockKey derives an advisory lock key from a name
+func LockKey(name string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+// AdvisoryLock takes a session-level advisory lock, waiting until it is free or ctx is done.
+// The lock lives on a dedicated connection which unlock releases and returns to the pool.
+func (m *Manager) AdvisoryLock(ctx context.Context, key int64) (unlock func() error, err error) {
+	conn, err := m.lockConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", key); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return unlocker(conn, key), nil
+}
+
+// TryAdvisoryLock takes a session-level advisory lock without waiting; ErrLockNotAcquired when it is held
+func (m *Manager) TryAdvisoryLock(ctx context.Context, key int64) (unlock func() error, err error) {
+	conn, err := m.lockConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var ok bool
+	if err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&ok); err != nil || !ok {
+		_ = conn.Close()
+		if err == nil {
+			err = ErrLockNotAcquired
+		}
+		return nil, err
+	}
+	return unlocker(conn, key), nil
+}
+
+// WithAdvisoryLock runs fn while holding the session lock key
+func (m *Manager) WithAdvisoryLock(ctx context.Context, key int64, fn func(ctx context.Context) error) error {
+	unlock, err := m.AdvisoryLock(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return fn(ctx)
+}
+
+// AdvisoryXactLock takes a transaction-level advisory lock on tx; it is released at commit / rollback
+func AdvisoryXactLock(tx *gorm.DB, key int64) error {
+	return tx.Exec("SELECT pg_advisory_xact_lock(?)", key).Error
+}
+
+// TryAdvisoryXactLock transaction-level lock without waiting
+func TryAdvisoryXactLock(tx *gorm.DB, key int64) (bool, error) {
+	var ok bool
+	err := tx.Raw("SELECT pg_try_advisory_xact_lock(?)", key).Scan(&ok).Error
+	return ok, err
+}
+
+func (m *Manager) lockConn(ctx context.Context) (*sql.Conn, error) {
+	if m.db == nil {
+		return nil, errors.New("psqlMng: db is nil")
+	}
+	sqlDB, err := m.db.DB()
+	if err != nil {
+		return nil, err
+	}
+	return sqlDB.Conn(ctx)
+}
+
+// unlocker releases key once; if the unlock fails the connection is discarded instead of returned to the pool,
+// since the session may still hold the lock and it is freed only when the session ends
+func unlocker(conn *sql.Conn, key int64) func() error {
+	var once sync.Once
+	var err error
+	return func() error {
+		once.Do(func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if _, err = conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", key); err != nil {
+				_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+			}
+			if closeErr := conn.Close(); err == nil {
+				err = closeErr
+			}
+		})
+		return err
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"time"
 
+	"github.com/wiidz/goutil/mngs/migrateMng"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -42,6 +43,12 @@ func (m *Manager) SetLogger(logger logger.Interface) {
 	m.db.Logger = logger
 }
 
+// NewMigrator creates a versioned migration runner; prefer it over AutoMigrate for
+// changes AutoMigrate cannot express (drops, renames, backfills)
+func (m *Manager) NewMigrator(opts ...migrateMng.Option) *migrateMng.Migrator {
+	return migrateMng.NewMigrator(m.db, opts...)
+}
+
 // AutoMigrate runs gorm automigrate on provided models
 func (m *Manager) AutoMigrate(models ...interface{}) error {
 	if m.db == nil {
@@ -26,6 +26,14 @@ type Manager struct {
 // DB returns underlying *gorm.DB
 func (m *Manager) DB() *gorm.DB { return m.db }
 
+// Use registers a gorm plugin (e.g. auditMng.Auditor)
+func (m *Manager) Use(plugin gorm.Plugin) error {
+	if m.db == nil {
+		return gorm.ErrInvalidDB
+	}
+	return m.db.Use(plugin)
+}
+
 // WithTx executes fn in a transaction
 func (m *Manager) WithTx(ctx context.Context, fn func(ctx context.Context, tx *gorm.DB) error) error {
 	if m.db == nil {
@@ -0,0 +1,38 @@
+package rabbitMngOld
+
+import (
+	"errors"
+
+	"github.com/wiidz/goutil/mngs/amqpMng"
+	"github.com/wiidz/goutil/mngs/busMng"
+)
+
+// Bus busMng.Bus 实现，使用与 Init 相同的 DSN 另建一条自愈连接（amqpMng.Connection），
+// 旧的全局 conn 不具备重连能力，不用于总线
+type Bus struct {
+	*amqpMng.Bus
+	conn *amqpMng.Connection
+}
+
+var _ busMng.Bus = (*Bus)(nil)
+
+// NewBus 创建总线，需先调用 Init
+func NewBus() (*Bus, error) {
+	if dsn == "" {
+		return nil, errors.New("rabbitMngOld: call Init first")
+	}
+	c, err := amqpMng.Dial(dsn, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Bus{Bus: amqpMng.NewBus(c), conn: c}, nil
+}
+
+// Close 关闭所有订阅及总线自己的连接
+func (b *Bus) Close() error {
+	err := b.Bus.Close()
+	if closeErr := b.conn.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -1,9 +1,11 @@
 package rabbitMngOld
 
 import (
+	"context"
 	"fmt"
 	"github.com/streadway/amqp"
 	"log"
+	"strconv"
 )
 
 type Producer struct {
@@ -44,49 +46,79 @@ func (producer *Producer) confirmOne(confirms <-chan amqp.Confirmation) {
 	}
 }
 
-// Publish 发布任务
+// Publish 发布任务，expiration 为消息过期时间（毫秒），<=0 表示不过期
 func (producer *Producer) Publish(routingKey string, body string, expiration int, reliable bool) (err error) {
+	pub := amqp.Publishing{
+		Headers:      amqp.Table{},
+		ContentType:  "text/plain",
+		Body:         []byte(body),
+		DeliveryMode: amqp.Persistent, // 1=non-persistent, 2=persistent
+		Priority:     0,               // 0-9
+	}
+	if expiration > 0 {
+		pub.Expiration = strconv.Itoa(expiration) // 设置2小时7200000  测试五秒
+	}
+	return producer.PublishMessage(context.Background(), routingKey, pub, reliable)
+}
+
+// PublishMessage 发布完整的消息（可带 MessageId、Headers 等属性）
+func (producer *Producer) PublishMessage(ctx context.Context, routingKey string, pub amqp.Publishing, reliable bool) (err error) {
 
 	var ch *amqp.Channel
-	ch, err = conn.Channel()
+	if ch, err = conn.Channel(); err != nil {
+		return fmt.Errorf("open channel: %s", err)
+	}
+	defer ch.Close()
 
 	// Reliable publisher confirms require confirm.select support from the connection.
+	var confirms chan amqp.Confirmation
 	if reliable {
-		log.Printf("enabling publishing confirms.")
-		if err := ch.Confirm(false); err != nil {
+		if err = ch.Confirm(false); err != nil {
 			return fmt.Errorf("channel could not be put into confirm mode: %s", err)
 		}
-
-		confirms := ch.NotifyPublish(make(chan amqp.Confirmation, 1))
-		defer producer.confirmOne(confirms)
+		confirms = ch.NotifyPublish(make(chan amqp.Confirmation, 1))
 	}
 
-	//log.Printf("declared Exchange, publishing %dB body (%q)", len(body), body)
-
 	err = ch.Publish(
 		producer.ExchangeName, // publish to an exchange
 		routingKey,            // routing to 0 or more queues
 		false,                 // mandatory
 		false,                 // immediate
-		amqp.Publishing{
-			Headers:         amqp.Table{},
-			ContentType:     "text/plain",
-			ContentEncoding: "",
-			Body:            []byte(body),
-			DeliveryMode:    amqp.Persistent,    // 1=non-persistent, 2=persistent
-			Priority:        0,                  // 0-9
-			Expiration:      string(expiration), // 设置2小时7200000  测试五秒
-			// a bunch of application/implementation-specific fields
-		},
+		pub,
 	)
-
 	if err != nil {
 		return fmt.Errorf("exchange Publish: %s", err)
 	}
 
+	if reliable {
+		select {
+		case confirmed := <-confirms:
+			if !confirmed.Ack {
+				return fmt.Errorf("failed delivery of delivery tag: %d", confirmed.DeliveryTag)
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
 	return nil
 }
 
+// Sender 固定 routingKey，适配 envelopeMng.Sender
+func (producer *Producer) Sender(routingKey string) *RoutedProducer {
+	return &RoutedProducer{producer: producer, routingKey: routingKey}
+}
+
+// RoutedProducer 绑定了 routingKey 的生产者
+type RoutedProducer struct {
+	producer   *Producer
+	routingKey string
+}
+
+// PublishMessage envelopeMng.Sender
+func (p *RoutedProducer) PublishMessage(ctx context.Context, pub amqp.Publishing, reliable bool) error {
+	return p.producer.PublishMessage(ctx, p.routingKey, pub, reliable)
+}
+
 // PublishDelay 发布延时任务,注意千万不能绑定队列，不然会直接推到队列里去
 // 这个插件的作用是发挥在exchange上的，到时间了，分发到队列里去
 func (producer *Producer) PublishDelay(routingKey, body string, expiration int64, reliable bool) (err error) {
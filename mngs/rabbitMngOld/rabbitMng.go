@@ -7,6 +7,7 @@ import (
 )
 
 var conn *amqp.Connection
+var dsn string // Init 使用的 DSN，NewBus 据此建立自愈连接
 
 // RabbitMQ rabbit队列管理器
 type RabbitMQ struct {
@@ -30,7 +31,7 @@ const XDelayedMessage ExchangeType = "x-delayed-message" // 【XDelayedMessage
 func Init(config *configStruct.RabbitMQConfig) (err error) {
 
 	//【1】构建DSN
-	dsn := "amqp://" + config.Username + ":" + config.Password + "@" + config.Host + "/"
+	dsn = "amqp://" + config.Username + ":" + config.Password + "@" + config.Host + "/"
 
 	//【2】构建DB对象
 	log.Println("【rabbitMq-dsn】", dsn)
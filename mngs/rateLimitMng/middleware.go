@@ -0,0 +1,141 @@
+package rateLimitMng
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// KeyFunc 从请求中提取限流维度（IP、用户ID等），返回空字符串时不限流
+type KeyFunc func(r *http.Request) string
+
+// KeyByIP 按连接的对端地址（RemoteAddr）限流，不读取 X-Forwarded-For / X-Real-IP，客户端无法伪造；
+// 部署在反向代理之后时使用 KeyByIPBehind
+func KeyByIP(r *http.Request) string {
+	return remoteIP(r)
+}
+
+// KeyByIPBehind 部署在反向代理之后时按客户端 IP 限流，trustedProxies 为可信代理的 IP 或 CIDR。
+// 只有对端地址属于可信代理时才读取转发头：X-Forwarded-For 从右向左跳过可信代理，取第一个不可信地址；
+// 没有 X-Forwarded-For 时读取 X-Real-IP。对端不可信时直接使用 RemoteAddr
+func KeyByIPBehind(trustedProxies ...string) (KeyFunc, error) {
+	nets := make([]*net.IPNet, 0, len(trustedProxies))
+	for _, proxy := range trustedProxies {
+		if !strings.Contains(proxy, "/") {
+			if ip := net.ParseIP(proxy); ip != nil && ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("rateLimitMng: invalid trusted proxy %q: %w", proxy, err)
+		}
+		nets = append(nets, ipNet)
+	}
+	trusted := func(addr string) bool {
+		ip := net.ParseIP(strings.TrimSpace(addr))
+		if ip == nil {
+			return false
+		}
+		for _, ipNet := range nets {
+			if ipNet.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(r *http.Request) string {
+		remote := remoteIP(r)
+		if !trusted(remote) {
+			return remote
+		}
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			hops := strings.Split(forwarded, ",")
+			for i := len(hops) - 1; i >= 0; i-- {
+				hop := strings.TrimSpace(hops[i])
+				if hop != "" && !trusted(hop) {
+					return hop
+				}
+			}
+			return remote
+		}
+		if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); realIP != "" {
+			return realIP
+		}
+		return remote
+	}, nil
+}
+
+// remoteIP RemoteAddr 中的 IP 部分
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// KeyByHeader 按指定请求头限流，例如登录态中的用户标识
+func KeyByHeader(name string) KeyFunc {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// KeyByContext 按 context 中的值限流（例如 SetRouterFlag 之后链路中写入的 login_id）
+func KeyByContext(name string) KeyFunc {
+	return func(r *http.Request) string {
+		switch val := r.Context().Value(name).(type) {
+		case string:
+			return val
+		case int:
+			return strconv.Itoa(val)
+		case uint64:
+			return strconv.FormatUint(val, 10)
+		}
+		return ""
+	}
+}
+
+// Middleware 返回一个 http 中间件，可与 networkHelper.SetRouterFlag 组成同一调用链
+// 被拒绝时返回 429，并写入 X-RateLimit-* 与 Retry-After 头
+func (mng *RateLimitMng) Middleware(rule *Rule, keyFunc KeyFunc) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := keyFunc(r)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			res, err := mng.Allow(r.Context(), rule, key)
+			if err != nil {
+				// 限流组件故障时放行，避免 redis 抖动导致整站不可用
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("X-RateLimit-Limit", strconv.FormatInt(res.Limit, 10))
+			w.Header().Set("X-RateLimit-Remaining", strconv.FormatInt(res.Remaining, 10))
+			if !res.Allowed {
+				retrySeconds := int64(math.Ceil(res.RetryAfter.Seconds()))
+				w.Header().Set("Retry-After", strconv.FormatInt(retrySeconds, 10))
+				w.Header().Set("Content-Type", "application/json; charset=utf-8")
+				w.WriteHeader(http.StatusTooManyRequests)
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{
+					"msg":  "操作过于频繁，请稍后再试",
+					"data": nil,
+				})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
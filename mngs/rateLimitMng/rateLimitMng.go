@@ -0,0 +1,170 @@
+package rateLimitMng
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+	"github.com/google/uuid"
+	"github.com/wiidz/goutil/mngs/redisMng"
+)
+
+// slidingWindowScript 滑动窗口：有序集合保存每次请求的毫秒时间戳
+// KEYS[1] 键名；ARGV[1] 窗口毫秒；ARGV[2] 上限；ARGV[3] 本次数量；ARGV[4] 成员前缀
+// 返回 {是否放行, 剩余额度, 重试等待毫秒}
+var slidingWindowScript = redis.NewScript(`
+redis.replicate_commands()
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local window = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+local n = tonumber(ARGV[3])
+
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - window)
+local count = redis.call("ZCARD", KEYS[1])
+
+if count + n <= limit then
+	for i = 1, n do
+		redis.call("ZADD", KEYS[1], now, ARGV[4] .. ":" .. i)
+	end
+	redis.call("PEXPIRE", KEYS[1], window)
+	return {1, limit - count - n, 0}
+end
+
+local retry = 0
+local idx = count + n - limit - 1
+local oldest = redis.call("ZRANGE", KEYS[1], idx, idx, "WITHSCORES")
+if oldest[2] then
+	retry = tonumber(oldest[2]) + window - now
+end
+local remaining = limit - count
+if remaining < 0 then
+	remaining = 0
+end
+return {0, remaining, retry}
+`)
+
+// tokenBucketScript 令牌桶：哈希保存剩余令牌数与上次计算时间
+// KEYS[1] 键名；ARGV[1] 容量；ARGV[2] 补满毫秒；ARGV[3] 本次数量
+// 返回 {是否放行, 剩余令牌, 重试等待毫秒}
+var tokenBucketScript = redis.NewScript(`
+redis.replicate_commands()
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local capacity = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local n = tonumber(ARGV[3])
+local rate = capacity / window
+
+local data = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(data[1])
+local ts = tonumber(data[2])
+if tokens == nil or ts == nil then
+	tokens = capacity
+	ts = now
+end
+if now > ts then
+	tokens = math.min(capacity, tokens + (now - ts) * rate)
+end
+
+local allowed = 0
+local retry = 0
+if tokens >= n then
+	tokens = tokens - n
+	allowed = 1
+else
+	retry = math.ceil((n - tokens) / rate)
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", now)
+redis.call("PEXPIRE", KEYS[1], window)
+return {allowed, math.floor(tokens), retry}
+`)
+
+// NewRateLimitMng 返回一个基于 redis 的限流管理器
+func NewRateLimitMng(redisM *redisMng.RedisMng) *RateLimitMng {
+	return &RateLimitMng{
+		RedisMng: redisM,
+		Prefix:   "rate_limit",
+	}
+}
+
+// Allow 判断 key 的一次请求是否放行
+func (mng *RateLimitMng) Allow(ctx context.Context, rule *Rule, key string) (*Result, error) {
+	return mng.AllowN(ctx, rule, key, 1)
+}
+
+// AllowN 判断 key 的 n 次请求是否放行（要么全部放行，要么全部拒绝）
+func (mng *RateLimitMng) AllowN(ctx context.Context, rule *Rule, key string, n int64) (*Result, error) {
+	if err := rule.validate(); err != nil {
+		return nil, err
+	}
+	if n <= 0 {
+		return nil, ErrInvalidRule
+	}
+	if n > rule.Limit {
+		return nil, ErrExceedLimit
+	}
+
+	var (
+		raw []interface{}
+		err error
+	)
+	keyName := mng.keyName(rule, key)
+	switch rule.Algorithm {
+	case SlidingWindow:
+		raw, err = slidingWindowScript.Run(ctx, mng.RedisMng.Client, []string{keyName},
+			rule.Window.Milliseconds(), rule.Limit, n, uuid.NewString()).Slice()
+	case TokenBucket:
+		raw, err = tokenBucketScript.Run(ctx, mng.RedisMng.Client, []string{keyName},
+			rule.Limit, rule.Window.Milliseconds(), n).Slice()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseResult(rule, raw)
+}
+
+// Reset 清空 key 的限流记录
+func (mng *RateLimitMng) Reset(ctx context.Context, rule *Rule, key string) error {
+	return mng.RedisMng.Client.Del(ctx, mng.keyName(rule, key)).Err()
+}
+
+// keyName 拼接 redis 键：{prefix}:{rule}:{key}
+func (mng *RateLimitMng) keyName(rule *Rule, key string) string {
+	return mng.Prefix + ":" + rule.Name + ":" + key
+}
+
+// validate 校验规则
+func (rule *Rule) validate() error {
+	if rule == nil || rule.Name == "" || rule.Limit <= 0 || rule.Window < time.Millisecond {
+		return ErrInvalidRule
+	}
+	if rule.Algorithm != SlidingWindow && rule.Algorithm != TokenBucket {
+		return ErrInvalidRule
+	}
+	return nil
+}
+
+// parseResult 解析 lua 返回的 {allowed, remaining, retry_ms}
+func parseResult(rule *Rule, raw []interface{}) (*Result, error) {
+	if len(raw) != 3 {
+		return nil, redis.Nil
+	}
+	nums := make([]int64, 3)
+	for i, v := range raw {
+		switch val := v.(type) {
+		case int64:
+			nums[i] = val
+		case string:
+			nums[i], _ = strconv.ParseInt(val, 10, 64)
+		}
+	}
+	return &Result{
+		Allowed:    nums[0] == 1,
+		Limit:      rule.Limit,
+		Remaining:  nums[1],
+		RetryAfter: time.Duration(nums[2]) * time.Millisecond,
+	}, nil
+}
@@ -0,0 +1,45 @@
+package rateLimitMng
+
+import (
+	"errors"
+	"time"
+
+	"github.com/wiidz/goutil/mngs/redisMng"
+)
+
+// Algorithm 限流算法
+type Algorithm int8
+
+const (
+	SlidingWindow Algorithm = 1 // 滑动窗口：Window 内最多 Limit 次
+	TokenBucket   Algorithm = 2 // 令牌桶：容量 Limit，每 Window 补满一次
+)
+
+var (
+	// ErrInvalidRule 限流规则不合法
+	ErrInvalidRule = errors.New("rateLimitMng: invalid rule")
+	// ErrExceedLimit 单次请求数量超过规则上限，永远无法通过
+	ErrExceedLimit = errors.New("rateLimitMng: request count exceeds limit")
+)
+
+// Rule 限流规则
+type Rule struct {
+	Name      string        // 规则名称，作为 redis 键前缀的一部分，例如 sms / captcha / ai
+	Algorithm Algorithm     // 限流算法
+	Limit     int64         // 滑动窗口：窗口内允许的次数；令牌桶：桶容量
+	Window    time.Duration // 滑动窗口：窗口长度；令牌桶：从空桶补满所需时间
+}
+
+// Result 单次限流判定结果
+type Result struct {
+	Allowed    bool          // 是否放行
+	Limit      int64         // 规则上限
+	Remaining  int64         // 剩余额度
+	RetryAfter time.Duration // 被拒绝时，距离可再次尝试的时间
+}
+
+// RateLimitMng 基于 redis 的限流管理器
+type RateLimitMng struct {
+	RedisMng *redisMng.RedisMng
+	Prefix   string // redis 键前缀，默认 rate_limit
+}
@@ -0,0 +1,74 @@
+package redisMng
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+)
+
+// 泛型方法统一使用 json 编解码，保证结构体写入后可以原样读出
+
+// encodeValue 将值编码为 json 字符串
+func encodeValue[T any](value T) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// encodeValues 批量编码，返回可直接传给 redis 命令的参数
+func encodeValues[T any](values []T) ([]interface{}, error) {
+	res := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		str, err := encodeValue(v)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, str)
+	}
+	return res, nil
+}
+
+// decodeValue 将 json 字符串解码为 T
+func decodeValue[T any](raw string) (value T, err error) {
+	err = json.Unmarshal([]byte(raw), &value)
+	return
+}
+
+// decodeValues 批量解码
+func decodeValues[T any](raws []string) ([]T, error) {
+	res := make([]T, 0, len(raws))
+	for _, raw := range raws {
+		v, err := decodeValue[T](raw)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, v)
+	}
+	return res, nil
+}
+
+// SetJSON 以 json 编码写入任意值
+func SetJSON[T any](ctx context.Context, mng *RedisMng, key string, value T, expire time.Duration) error {
+	str, err := encodeValue(value)
+	if err != nil {
+		return err
+	}
+	return mng.Client.Set(ctx, key, str, expire).Err()
+}
+
+// GetJSON 读取 json 编码的值，键不存在时 exist 为 false
+func GetJSON[T any](ctx context.Context, mng *RedisMng, key string) (value T, exist bool, err error) {
+	raw, err := mng.Client.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return value, false, nil
+	}
+	if err != nil {
+		return value, false, err
+	}
+	value, err = decodeValue[T](raw)
+	return value, err == nil, err
+}
@@ -0,0 +1,87 @@
+package redisMng
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+)
+
+// -------BEGIN------计数器相关的操作-----BEGIN--------
+
+// Incr 自增 1，返回自增后的值
+func (mng *RedisMng) Incr(ctx context.Context, key string) (int64, error) {
+	return mng.Client.Incr(ctx, key).Result()
+}
+
+// IncrBy 增加指定值，返回增加后的值
+func (mng *RedisMng) IncrBy(ctx context.Context, key string, increment int64) (int64, error) {
+	return mng.Client.IncrBy(ctx, key, increment).Result()
+}
+
+// IncrByFloat 增加指定浮点值，返回增加后的值
+func (mng *RedisMng) IncrByFloat(ctx context.Context, key string, increment float64) (float64, error) {
+	return mng.Client.IncrByFloat(ctx, key, increment).Result()
+}
+
+// Decr 自减 1，返回自减后的值
+func (mng *RedisMng) Decr(ctx context.Context, key string) (int64, error) {
+	return mng.Client.Decr(ctx, key).Result()
+}
+
+// DecrBy 减少指定值，返回减少后的值
+func (mng *RedisMng) DecrBy(ctx context.Context, key string, decrement int64) (int64, error) {
+	return mng.Client.DecrBy(ctx, key, decrement).Result()
+}
+
+// incrWithExpireScript 自增，结果为 1（新建的键）时设置过期时间，两步在同一脚本中原子执行
+var incrWithExpireScript = redis.NewScript(`
+local n = redis.call("INCR", KEYS[1])
+if n == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return n
+`)
+
+// IncrWithExpire 自增并在首次创建时设置过期时间，适合按时间段计数；
+// 使用 Lua 脚本保证不会出现自增成功但未设置过期时间的永久计数键
+func (mng *RedisMng) IncrWithExpire(ctx context.Context, key string, expire time.Duration) (int64, error) {
+	return incrWithExpireScript.Run(ctx, mng.Client, []string{key}, expire.Milliseconds()).Int64()
+}
+
+// -------END------计数器相关的操作----END---------
+
+// -------BEGIN------键及过期时间相关的操作-----BEGIN--------
+
+// Exists 判断键是否存在
+func (mng *RedisMng) Exists(ctx context.Context, key string) (bool, error) {
+	n, err := mng.Client.Exists(ctx, key).Result()
+	return n > 0, err
+}
+
+// Del 删除键，返回删除数量
+func (mng *RedisMng) Del(ctx context.Context, keys ...string) (int64, error) {
+	return mng.Client.Del(ctx, keys...).Result()
+}
+
+// Expire 设置过期时间
+func (mng *RedisMng) Expire(ctx context.Context, key string, expire time.Duration) (bool, error) {
+	return mng.Client.Expire(ctx, key, expire).Result()
+}
+
+// ExpireAt 设置过期时间点
+func (mng *RedisMng) ExpireAt(ctx context.Context, key string, at time.Time) (bool, error) {
+	return mng.Client.ExpireAt(ctx, key, at).Result()
+}
+
+// Persist 移除过期时间
+func (mng *RedisMng) Persist(ctx context.Context, key string) (bool, error) {
+	return mng.Client.Persist(ctx, key).Result()
+}
+
+// TTL 剩余过期时间，-1 表示永不过期，-2 表示键不存在
+func (mng *RedisMng) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return mng.Client.TTL(ctx, key).Result()
+}
+
+// -------END------键及过期时间相关的操作----END---------
@@ -0,0 +1,81 @@
+package redisMng
+
+import (
+	"context"
+
+	"github.com/go-redis/redis/v9"
+)
+
+// LPush 从列表头部插入，返回插入后列表长度
+func LPush[T any](ctx context.Context, mng *RedisMng, key string, values ...T) (int64, error) {
+	args, err := encodeValues(values)
+	if err != nil {
+		return 0, err
+	}
+	return mng.Client.LPush(ctx, key, args...).Result()
+}
+
+// RPush 从列表尾部插入，返回插入后列表长度
+func RPush[T any](ctx context.Context, mng *RedisMng, key string, values ...T) (int64, error) {
+	args, err := encodeValues(values)
+	if err != nil {
+		return 0, err
+	}
+	return mng.Client.RPush(ctx, key, args...).Result()
+}
+
+// LPop 从列表头部弹出，列表为空时 exist 为 false
+func LPop[T any](ctx context.Context, mng *RedisMng, key string) (value T, exist bool, err error) {
+	return popValue[T](mng.Client.LPop(ctx, key))
+}
+
+// RPop 从列表尾部弹出，列表为空时 exist 为 false
+func RPop[T any](ctx context.Context, mng *RedisMng, key string) (value T, exist bool, err error) {
+	return popValue[T](mng.Client.RPop(ctx, key))
+}
+
+// LIndex 读取列表指定下标的元素，下标越界时 exist 为 false
+func LIndex[T any](ctx context.Context, mng *RedisMng, key string, index int64) (value T, exist bool, err error) {
+	return popValue[T](mng.Client.LIndex(ctx, key, index))
+}
+
+// LRange 读取列表 [start, stop] 区间，stop 为 -1 表示到末尾
+func LRange[T any](ctx context.Context, mng *RedisMng, key string, start, stop int64) ([]T, error) {
+	raws, err := mng.Client.LRange(ctx, key, start, stop).Result()
+	if err != nil {
+		return nil, err
+	}
+	return decodeValues[T](raws)
+}
+
+// LRem 删除列表中与 value 相等的元素，count 含义同 redis LREM
+func LRem[T any](ctx context.Context, mng *RedisMng, key string, count int64, value T) (int64, error) {
+	str, err := encodeValue(value)
+	if err != nil {
+		return 0, err
+	}
+	return mng.Client.LRem(ctx, key, count, str).Result()
+}
+
+// LLen 列表长度
+func (mng *RedisMng) LLen(ctx context.Context, key string) (int64, error) {
+	return mng.Client.LLen(ctx, key).Result()
+}
+
+// LTrim 只保留列表 [start, stop] 区间，常用于固定长度的最近记录
+func (mng *RedisMng) LTrim(ctx context.Context, key string, start, stop int64) error {
+	return mng.Client.LTrim(ctx, key, start, stop).Err()
+}
+
+// popValue 解析返回单个元素的命令，redis.Nil 视为不存在
+func popValue[T any](cmd *redis.StringCmd) (value T, exist bool, err error) {
+	raw, err := cmd.Result()
+	if err == redis.Nil {
+		return value, false, nil
+	}
+	if err != nil {
+		return value, false, err
+	}
+	value, err = decodeValue[T](raw)
+	return value, err == nil, err
+}
@@ -0,0 +1,224 @@
+package redisMng
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrLockNotObtained 锁已被其他持有者占用
+	ErrLockNotObtained = errors.New("redisMng: lock not obtained")
+	// ErrLockNotHeld 锁已过期或已被其他持有者占用
+	ErrLockNotHeld = errors.New("redisMng: lock not held")
+)
+
+// unlockScript 仅当 token 一致时删除锁（compare-and-delete）
+var unlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// refreshScript 仅当 token 一致时续期锁
+var refreshScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// LockOption 用于定制加锁行为
+type LockOption func(*lockOptions)
+
+type lockOptions struct {
+	retryInterval time.Duration // 阻塞加锁时的重试间隔
+	watchdog      bool          // 是否开启自动续期
+	renewInterval time.Duration // 自动续期间隔，默认 ttl/3
+}
+
+// WithLockRetryInterval 指定 Lock 阻塞等待时的重试间隔（默认 100ms）
+func WithLockRetryInterval(interval time.Duration) LockOption {
+	return func(opts *lockOptions) {
+		if interval > 0 {
+			opts.retryInterval = interval
+		}
+	}
+}
+
+// WithLockWatchdog 开启看门狗，持有期间按 interval 自动续期（interval<=0 时取 ttl/3）
+func WithLockWatchdog(interval time.Duration) LockOption {
+	return func(opts *lockOptions) {
+		opts.watchdog = true
+		opts.renewInterval = interval
+	}
+}
+
+// Lock 分布式锁持有凭证
+type Lock struct {
+	mng   *RedisMng
+	Key   string
+	Token string
+	TTL   time.Duration
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// TryLock 尝试获取锁，未获取到时立即返回 ErrLockNotObtained
+func (mng *RedisMng) TryLock(ctx context.Context, key string, ttl time.Duration, opts ...LockOption) (*Lock, error) {
+	cfg := newLockOptions(opts...)
+	return mng.tryLock(ctx, key, ttl, cfg)
+}
+
+// Lock 阻塞获取锁，直到成功或 ctx 结束
+func (mng *RedisMng) Lock(ctx context.Context, key string, ttl time.Duration, opts ...LockOption) (*Lock, error) {
+	cfg := newLockOptions(opts...)
+
+	ticker := time.NewTicker(cfg.retryInterval)
+	defer ticker.Stop()
+
+	for {
+		lock, err := mng.tryLock(ctx, key, ttl, cfg)
+		if err == nil {
+			return lock, nil
+		}
+		if !errors.Is(err, ErrLockNotObtained) {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Unlock 释放锁，仅持有者本人可以释放
+func (mng *RedisMng) Unlock(ctx context.Context, lock *Lock) error {
+	if lock == nil {
+		return ErrLockNotHeld
+	}
+	return lock.Unlock(ctx)
+}
+
+// Unlock 释放锁并停止看门狗
+func (l *Lock) Unlock(ctx context.Context) error {
+	l.stopWatchdog()
+
+	res, err := unlockScript.Run(ctx, l.mng.Client, []string{l.Key}, l.Token).Int64()
+	if err != nil {
+		return err
+	}
+	if res == 0 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+// Refresh 手动续期锁
+func (l *Lock) Refresh(ctx context.Context, ttl time.Duration) error {
+	res, err := refreshScript.Run(ctx, l.mng.Client, []string{l.Key}, l.Token, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return err
+	}
+	if res == 0 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+// TTLRemain 查询锁剩余有效期，锁已不属于当前持有者时返回 ErrLockNotHeld
+func (l *Lock) TTLRemain(ctx context.Context) (time.Duration, error) {
+	val, err := l.mng.Client.Get(ctx, l.Key).Result()
+	if err == redis.Nil || (err == nil && val != l.Token) {
+		return 0, ErrLockNotHeld
+	}
+	if err != nil {
+		return 0, err
+	}
+	return l.mng.Client.PTTL(ctx, l.Key).Result()
+}
+
+func newLockOptions(opts ...LockOption) *lockOptions {
+	cfg := &lockOptions{
+		retryInterval: 100 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(cfg)
+		}
+	}
+	return cfg
+}
+
+func (mng *RedisMng) tryLock(ctx context.Context, key string, ttl time.Duration, cfg *lockOptions) (*Lock, error) {
+	token := uuid.NewString()
+	ok, err := mng.Client.SetNX(ctx, key, token, ttl).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrLockNotObtained
+	}
+
+	lock := &Lock{
+		mng:   mng,
+		Key:   key,
+		Token: token,
+		TTL:   ttl,
+	}
+	if cfg.watchdog {
+		interval := cfg.renewInterval
+		if interval <= 0 {
+			interval = ttl / 3
+		}
+		lock.startWatchdog(interval)
+	}
+	return lock, nil
+}
+
+// startWatchdog 在后台定期续期，直到 Unlock 或续期失败
+func (l *Lock) startWatchdog(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	l.stop = make(chan struct{})
+	l.done = make(chan struct{})
+
+	go func() {
+		defer close(l.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-l.stop:
+				return
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), interval)
+				err := l.Refresh(ctx, l.TTL)
+				cancel()
+				if errors.Is(err, ErrLockNotHeld) {
+					return
+				}
+			}
+		}
+	}()
+}
+
+func (l *Lock) stopWatchdog() {
+	if l.stop == nil {
+		return
+	}
+	l.stopOnce.Do(func() {
+		close(l.stop)
+		<-l.done
+	})
+}
@@ -0,0 +1,96 @@
+package redisMng
+
+import (
+	"context"
+)
+
+// SAdd 向集合添加成员，返回新增数量
+func SAdd[T any](ctx context.Context, mng *RedisMng, key string, members ...T) (int64, error) {
+	args, err := encodeValues(members)
+	if err != nil {
+		return 0, err
+	}
+	return mng.Client.SAdd(ctx, key, args...).Result()
+}
+
+// SRem 从集合移除成员，返回移除数量
+func SRem[T any](ctx context.Context, mng *RedisMng, key string, members ...T) (int64, error) {
+	args, err := encodeValues(members)
+	if err != nil {
+		return 0, err
+	}
+	return mng.Client.SRem(ctx, key, args...).Result()
+}
+
+// SIsMember 判断是否为集合成员
+func SIsMember[T any](ctx context.Context, mng *RedisMng, key string, member T) (bool, error) {
+	str, err := encodeValue(member)
+	if err != nil {
+		return false, err
+	}
+	return mng.Client.SIsMember(ctx, key, str).Result()
+}
+
+// SMembers 读取集合全部成员
+func SMembers[T any](ctx context.Context, mng *RedisMng, key string) ([]T, error) {
+	raws, err := mng.Client.SMembers(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	return decodeValues[T](raws)
+}
+
+// SRandMember 随机读取 count 个成员（不移除）
+func SRandMember[T any](ctx context.Context, mng *RedisMng, key string, count int64) ([]T, error) {
+	raws, err := mng.Client.SRandMemberN(ctx, key, count).Result()
+	if err != nil {
+		return nil, err
+	}
+	return decodeValues[T](raws)
+}
+
+// SPop 随机弹出一个成员，集合为空时 exist 为 false
+func SPop[T any](ctx context.Context, mng *RedisMng, key string) (value T, exist bool, err error) {
+	return popValue[T](mng.Client.SPop(ctx, key))
+}
+
+// SMove 将成员从 source 移动到 destination
+func SMove[T any](ctx context.Context, mng *RedisMng, source, destination string, member T) (bool, error) {
+	str, err := encodeValue(member)
+	if err != nil {
+		return false, err
+	}
+	return mng.Client.SMove(ctx, source, destination, str).Result()
+}
+
+// SInter 多个集合的交集
+func SInter[T any](ctx context.Context, mng *RedisMng, keys ...string) ([]T, error) {
+	raws, err := mng.Client.SInter(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	return decodeValues[T](raws)
+}
+
+// SUnion 多个集合的并集
+func SUnion[T any](ctx context.Context, mng *RedisMng, keys ...string) ([]T, error) {
+	raws, err := mng.Client.SUnion(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	return decodeValues[T](raws)
+}
+
+// SDiff 第一个集合相对其余集合的差集
+func SDiff[T any](ctx context.Context, mng *RedisMng, keys ...string) ([]T, error) {
+	raws, err := mng.Client.SDiff(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	return decodeValues[T](raws)
+}
+
+// SCard 集合成员数量
+func (mng *RedisMng) SCard(ctx context.Context, key string) (int64, error) {
+	return mng.Client.SCard(ctx, key).Result()
+}
@@ -0,0 +1,186 @@
+package redisMng
+
+import (
+	"context"
+
+	"github.com/go-redis/redis/v9"
+)
+
+// ZMember 有序集合成员及分数
+type ZMember[T any] struct {
+	Member T       `json:"member"`
+	Score  float64 `json:"score"`
+}
+
+// ZAdd 添加或更新有序集合成员，返回新增数量
+func ZAdd[T any](ctx context.Context, mng *RedisMng, key string, members ...ZMember[T]) (int64, error) {
+	zs := make([]redis.Z, 0, len(members))
+	for _, m := range members {
+		str, err := encodeValue(m.Member)
+		if err != nil {
+			return 0, err
+		}
+		zs = append(zs, redis.Z{Score: m.Score, Member: str})
+	}
+	return mng.Client.ZAdd(ctx, key, zs...).Result()
+}
+
+// ZIncrBy 增加成员分数，返回增加后的分数
+func ZIncrBy[T any](ctx context.Context, mng *RedisMng, key string, increment float64, member T) (float64, error) {
+	str, err := encodeValue(member)
+	if err != nil {
+		return 0, err
+	}
+	return mng.Client.ZIncrBy(ctx, key, increment, str).Result()
+}
+
+// ZScore 读取成员分数，成员不存在时 exist 为 false
+func ZScore[T any](ctx context.Context, mng *RedisMng, key string, member T) (score float64, exist bool, err error) {
+	str, err := encodeValue(member)
+	if err != nil {
+		return 0, false, err
+	}
+	score, err = mng.Client.ZScore(ctx, key, str).Result()
+	if err == redis.Nil {
+		return 0, false, nil
+	}
+	return score, err == nil, err
+}
+
+// ZRem 移除成员，返回移除数量
+func ZRem[T any](ctx context.Context, mng *RedisMng, key string, members ...T) (int64, error) {
+	args, err := encodeValues(members)
+	if err != nil {
+		return 0, err
+	}
+	return mng.Client.ZRem(ctx, key, args...).Result()
+}
+
+// ZRange 按分数从低到高读取 [start, stop] 区间的成员
+func ZRange[T any](ctx context.Context, mng *RedisMng, key string, start, stop int64) ([]T, error) {
+	raws, err := mng.Client.ZRange(ctx, key, start, stop).Result()
+	if err != nil {
+		return nil, err
+	}
+	return decodeValues[T](raws)
+}
+
+// ZRevRange 按分数从高到低读取 [start, stop] 区间的成员
+func ZRevRange[T any](ctx context.Context, mng *RedisMng, key string, start, stop int64) ([]T, error) {
+	raws, err := mng.Client.ZRevRange(ctx, key, start, stop).Result()
+	if err != nil {
+		return nil, err
+	}
+	return decodeValues[T](raws)
+}
+
+// ZRangeWithScores 按分数从低到高读取成员及分数
+func ZRangeWithScores[T any](ctx context.Context, mng *RedisMng, key string, start, stop int64) ([]ZMember[T], error) {
+	zs, err := mng.Client.ZRangeWithScores(ctx, key, start, stop).Result()
+	if err != nil {
+		return nil, err
+	}
+	return decodeZMembers[T](zs)
+}
+
+// ZRevRangeWithScores 按分数从高到低读取成员及分数
+func ZRevRangeWithScores[T any](ctx context.Context, mng *RedisMng, key string, start, stop int64) ([]ZMember[T], error) {
+	zs, err := mng.Client.ZRevRangeWithScores(ctx, key, start, stop).Result()
+	if err != nil {
+		return nil, err
+	}
+	return decodeZMembers[T](zs)
+}
+
+// ZRangeByScore 按分数区间读取成员，min/max 支持 "-inf"、"(1.5" 等 redis 语法，count 为 0 时不限制数量
+func ZRangeByScore[T any](ctx context.Context, mng *RedisMng, key, min, max string, offset, count int64) ([]ZMember[T], error) {
+	zs, err := mng.Client.ZRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{
+		Min:    min,
+		Max:    max,
+		Offset: offset,
+		Count:  count,
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	return decodeZMembers[T](zs)
+}
+
+// ZRevRankWithScore 排行榜名次及分数，rank 从 1 开始，成员不存在时 exist 为 false
+func ZRevRankWithScore[T any](ctx context.Context, mng *RedisMng, key string, member T) (rank int64, score float64, exist bool, err error) {
+	str, err := encodeValue(member)
+	if err != nil {
+		return 0, 0, false, err
+	}
+
+	pipe := mng.Client.Pipeline()
+	rankCmd := pipe.ZRevRank(ctx, key, str)
+	scoreCmd := pipe.ZScore(ctx, key, str)
+	if _, err = pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return 0, 0, false, err
+	}
+
+	rank, err = rankCmd.Result()
+	if err == redis.Nil {
+		return 0, 0, false, nil
+	}
+	if err != nil {
+		return 0, 0, false, err
+	}
+	score, err = scoreCmd.Result()
+	if err != nil {
+		return 0, 0, false, err
+	}
+	return rank + 1, score, true, nil
+}
+
+// ZRevPage 排行榜分页（分数从高到低），page 从 1 开始，同时返回总数
+func ZRevPage[T any](ctx context.Context, mng *RedisMng, key string, page, size int64) (list []ZMember[T], total int64, err error) {
+	if page <= 0 {
+		page = 1
+	}
+	if size <= 0 {
+		size = 10
+	}
+	start := (page - 1) * size
+
+	pipe := mng.Client.Pipeline()
+	cardCmd := pipe.ZCard(ctx, key)
+	rangeCmd := pipe.ZRevRangeWithScores(ctx, key, start, start+size-1)
+	if _, err = pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, 0, err
+	}
+
+	total = cardCmd.Val()
+	list, err = decodeZMembers[T](rangeCmd.Val())
+	return
+}
+
+// ZCard 有序集合成员数量
+func (mng *RedisMng) ZCard(ctx context.Context, key string) (int64, error) {
+	return mng.Client.ZCard(ctx, key).Result()
+}
+
+// ZCount 分数在 [min, max] 区间内的成员数量
+func (mng *RedisMng) ZCount(ctx context.Context, key, min, max string) (int64, error) {
+	return mng.Client.ZCount(ctx, key, min, max).Result()
+}
+
+// ZRemRangeByRank 按名次区间删除，常用于只保留排行榜前 N 名
+func (mng *RedisMng) ZRemRangeByRank(ctx context.Context, key string, start, stop int64) (int64, error) {
+	return mng.Client.ZRemRangeByRank(ctx, key, start, stop).Result()
+}
+
+// decodeZMembers 解码带分数的成员列表
+func decodeZMembers[T any](zs []redis.Z) ([]ZMember[T], error) {
+	res := make([]ZMember[T], 0, len(zs))
+	for _, z := range zs {
+		raw, _ := z.Member.(string)
+		member, err := decodeValue[T](raw)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, ZMember[T]{Member: member, Score: z.Score})
+	}
+	return res, nil
+}
@@ -0,0 +1,220 @@
+package repoMng
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/wiidz/goutil/helpers/cursorHelper"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrStopIteration return it from FindInBatches / Each callbacks to stop early without an error
+var ErrStopIteration = errors.New("repoMng: stop iteration")
+
+const defaultBatchSize = 500
+
+// UpsertOption configures Upsert.
+type UpsertOption func(*upsertOptions)
+
+type upsertOptions struct {
+	conflict  []string
+	update    []string
+	doNothing bool
+	batchSize int
+}
+
+// OnConflict sets the conflict target (unique index columns).
+// Postgres requires it unless the conflict is on the primary key; MySQL ignores it and uses any unique key.
+func OnConflict(cols ...string) UpsertOption {
+	return func(o *upsertOptions) { o.conflict = cols }
+}
+
+// UpdateColumns limits the columns overwritten on conflict; all non-key columns are updated by default.
+func UpdateColumns(cols ...string) UpsertOption {
+	return func(o *upsertOptions) { o.update = cols }
+}
+
+// DoNothing keeps existing rows untouched on conflict (INSERT IGNORE semantics).
+func DoNothing() UpsertOption {
+	return func(o *upsertOptions) { o.doNothing = true }
+}
+
+// BatchSize sets how many rows are sent per INSERT statement (default 500).
+func BatchSize(n int) UpsertOption {
+	return func(o *upsertOptions) { o.batchSize = n }
+}
+
+// CreateBatch inserts rows in chunks of batchSize (default 500) inside one statement per chunk.
+func (r *Repo[T]) CreateBatch(ctx context.Context, rows []*T, batchSize int) error {
+	if r.db == nil {
+		return gorm.ErrInvalidDB
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+	for _, row := range rows {
+		r.fillAudit(ctx, row, true)
+	}
+	return r.db.WithContext(ctx).CreateInBatches(rows, batchSize).Error
+}
+
+// Upsert inserts rows, updating (or skipping) those that hit a unique key.
+// It compiles to ON DUPLICATE KEY UPDATE on MySQL and ON CONFLICT ... DO UPDATE on Postgres.
+func (r *Repo[T]) Upsert(ctx context.Context, rows []*T, opts ...UpsertOption) error {
+	if r.db == nil {
+		return gorm.ErrInvalidDB
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	o := upsertOptions{batchSize: defaultBatchSize}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.batchSize <= 0 {
+		o.batchSize = defaultBatchSize
+	}
+
+	onConflict := clause.OnConflict{DoNothing: o.doNothing}
+	for _, col := range o.conflict {
+		onConflict.Columns = append(onConflict.Columns, clause.Column{Name: col})
+	}
+	if !o.doNothing {
+		updates, err := r.upsertUpdates(o.update)
+		if err != nil {
+			return err
+		}
+		if updates == nil {
+			onConflict.UpdateAll = true
+		} else {
+			onConflict.DoUpdates = updates
+			onConflict.DoNothing = len(updates) == 0
+		}
+	}
+	for _, row := range rows {
+		r.fillAudit(ctx, row, true)
+	}
+	return r.db.WithContext(ctx).Clauses(onConflict).CreateInBatches(rows, o.batchSize).Error
+}
+
+// upsertUpdates the ON CONFLICT assignments: the given columns, or every column a full-row update
+// may rewrite (created_by and other immutable columns are kept). The OptimisticLock column is
+// never taken from the incoming row but incremented, so a concurrent Update still sees a conflict.
+// nil means the model cannot be parsed and gorm's UpdateAll is used.
+func (r *Repo[T]) upsertUpdates(columns []string) ([]clause.Assignment, error) {
+	sch := r.schema()
+	if sch == nil {
+		if len(columns) > 0 {
+			return clause.AssignmentColumns(columns), nil
+		}
+		return nil, nil
+	}
+	var version string
+	if r.opts.version != "" {
+		field, err := r.versionField()
+		if err != nil {
+			return nil, err
+		}
+		version = field.DBName
+	}
+
+	if len(columns) == 0 {
+		skip := map[string]bool{version: true}
+		for _, col := range r.immutableColumns(sch) {
+			skip[col] = true
+		}
+		for _, field := range sch.Fields {
+			// same columns gorm's UpdateAll would rewrite
+			if field.DBName == "" || !field.Creatable || field.PrimaryKey || field.AutoCreateTime > 0 || skip[field.DBName] {
+				continue
+			}
+			if field.HasDefaultValue && field.DefaultValueInterface == nil && !strings.EqualFold(field.DefaultValue, "NULL") {
+				continue
+			}
+			columns = append(columns, field.DBName)
+		}
+	}
+
+	updates := make([]clause.Assignment, 0, len(columns)+1)
+	for _, assignment := range clause.AssignmentColumns(columns) {
+		if assignment.Column.Name != version {
+			updates = append(updates, assignment)
+		}
+	}
+	if version != "" {
+		updates = append(updates, clause.Assignment{
+			Column: clause.Column{Name: version},
+			Value:  clause.Expr{SQL: "? + 1", Vars: []any{clause.Column{Table: clause.CurrentTable, Name: version}}},
+		})
+	}
+	return updates, nil
+}
+
+// FindInBatches walks all matching rows in primary-key order, batchSize rows at a time.
+// Each chunk is fetched with a keyset condition (pk > last pk), so memory stays flat and late
+// chunks are as cheap as early ones. Do not pass WithOrder / WithPage.
+func (r *Repo[T]) FindInBatches(ctx context.Context, batchSize int, fn func(batch []*T) error, opts ...Selector) error {
+	if r.db == nil {
+		return gorm.ErrInvalidDB
+	}
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+	keys, err := r.primaryKeys()
+	if err != nil {
+		return err
+	}
+
+	page := cursorHelper.Page{Keys: keys, Size: batchSize, SkipCount: true}
+	for {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		batch, info, err := r.ListByCursor(ctx, page, opts...)
+		if err != nil {
+			return err
+		}
+		if len(batch) > 0 {
+			if err = fn(batch); err != nil {
+				if errors.Is(err, ErrStopIteration) {
+					return nil
+				}
+				return err
+			}
+		}
+		if !info.HasNext {
+			return nil
+		}
+		page.Cursor = info.Next
+	}
+}
+
+// Each calls fn for every matching row, loading them in keyset chunks of 500.
+func (r *Repo[T]) Each(ctx context.Context, fn func(row *T) error, opts ...Selector) error {
+	return r.FindInBatches(ctx, defaultBatchSize, func(batch []*T) error {
+		for _, row := range batch {
+			if err := fn(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, opts...)
+}
+
+// primaryKeys sort keys for keyset iteration
+func (r *Repo[T]) primaryKeys() ([]cursorHelper.SortKey, error) {
+	sch := r.schema()
+	if sch == nil || len(sch.PrimaryFieldDBNames) == 0 {
+		return nil, errors.New("repoMng: keyset iteration requires a gorm model with a primary key")
+	}
+	keys := make([]cursorHelper.SortKey, 0, len(sch.PrimaryFieldDBNames))
+	for _, name := range sch.PrimaryFieldDBNames {
+		keys = append(keys, cursorHelper.SortKey{Column: name})
+	}
+	return keys, nil
+}
@@ -0,0 +1,357 @@
+package repoMng
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/wiidz/goutil/mngs/identityMng"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// ErrVersionConflict is matched (errors.Is) by every *ConflictError.
+var ErrVersionConflict = errors.New("repoMng: version conflict")
+
+// ConflictError is returned by Update when the row was changed (or removed) by someone else
+// since it was read. Reload the row and retry.
+type ConflictError struct {
+	Table   string
+	Key     any   // primary key value
+	Version int64 // version the caller tried to update from
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("repoMng: %s(%v) was modified concurrently (version %d)", e.Table, e.Key, e.Version)
+}
+
+func (e *ConflictError) Unwrap() error { return ErrVersionConflict }
+
+// RepoOption enables opt-in behaviors on a Repo.
+type RepoOption func(*repoOptions)
+
+type repoOptions struct {
+	softDelete string
+	version    string
+	createdBy  string
+	updatedBy  string
+	actor      func(ctx context.Context) string
+}
+
+// SoftDelete marks rows deleted by setting column (usually deleted_at) instead of removing them;
+// reads skip them unless WithTrashed / OnlyTrashed is given.
+// Models with a gorm.DeletedAt field are soft-deleted by gorm itself and need no option.
+func SoftDelete(column string) RepoOption {
+	return func(o *repoOptions) { o.softDelete = column }
+}
+
+// OptimisticLock turns Update into compare-and-swap on an integer version column (usually version).
+// A stale version makes Update return *ConflictError.
+func OptimisticLock(column string) RepoOption {
+	return func(o *repoOptions) { o.version = column }
+}
+
+// AuditColumns fills createdBy on Create and updatedBy on Create/Update/soft Delete with the actor
+// (the login ID in ctx by default). Pass "" to skip either column.
+func AuditColumns(createdBy, updatedBy string) RepoOption {
+	return func(o *repoOptions) { o.createdBy, o.updatedBy = createdBy, updatedBy }
+}
+
+// ActorFunc overrides how AuditColumns resolves the current actor (default identityMng.LoginIDFromContext).
+func ActorFunc(fn func(ctx context.Context) string) RepoOption {
+	return func(o *repoOptions) { o.actor = fn }
+}
+
+// -------BEGIN------soft delete selectors-----BEGIN--------
+
+type trashedMode int8
+
+const (
+	trashedWith trashedMode = 1
+	trashedOnly trashedMode = 2
+)
+
+// WithTrashed includes soft-deleted rows.
+func WithTrashed() Selector {
+	return selFn(func(db *gorm.DB) *gorm.DB { return db.Set("__trashed__", trashedWith) })
+}
+
+// OnlyTrashed returns soft-deleted rows only.
+func OnlyTrashed() Selector {
+	return selFn(func(db *gorm.DB) *gorm.DB { return db.Set("__trashed__", trashedOnly) })
+}
+
+// softColumn returns the soft-delete column and whether gorm manages it (gorm.DeletedAt field).
+func (r *Repo[T]) softColumn() (string, bool) {
+	if sch := r.schema(); sch != nil {
+		for _, field := range sch.Fields {
+			if field.FieldType == reflect.TypeOf(gorm.DeletedAt{}) && field.DBName != "" {
+				return field.DBName, true
+			}
+		}
+	}
+	return r.opts.softDelete, false
+}
+
+// query prepares a read/delete query: selectors first, then the soft-delete scope.
+func (r *Repo[T]) query(ctx context.Context, opts ...Selector) *gorm.DB {
+	qb := apply(r.db.WithContext(ctx), opts...)
+	if _, all := qb.Get("__all_rows__"); all {
+		qb = qb.Session(&gorm.Session{AllowGlobalUpdate: true})
+	}
+	column, managed := r.softColumn()
+	if column == "" {
+		return qb
+	}
+
+	mode, _ := qb.Get("__trashed__")
+	switch mode {
+	case trashedWith:
+		return qb.Unscoped()
+	case trashedOnly:
+		return qb.Unscoped().Where(clause.Expr{SQL: "? IS NOT NULL", Vars: []any{clause.Column{Table: clause.CurrentTable, Name: column}}})
+	}
+	if managed {
+		return qb
+	}
+	return qb.Where(clause.Expr{SQL: "? IS NULL", Vars: []any{clause.Column{Table: clause.CurrentTable, Name: column}}})
+}
+
+// Restore clears the soft-delete column on matching trashed rows.
+func (r *Repo[T]) Restore(ctx context.Context, opts ...Selector) (int64, error) {
+	if r.db == nil {
+		return 0, gorm.ErrInvalidDB
+	}
+	column, _ := r.softColumn()
+	if column == "" {
+		return 0, errors.New("repoMng: Restore requires soft delete")
+	}
+	if err := r.requireSelector(ctx, opts); err != nil {
+		return 0, err
+	}
+	values := map[string]any{column: nil}
+	if by := r.actor(ctx); by != "" && r.opts.updatedBy != "" {
+		values[r.opts.updatedBy] = by
+	}
+	res := r.query(ctx, append(opts, OnlyTrashed())...).Model(new(T)).Updates(values)
+	return res.RowsAffected, res.Error
+}
+
+// ForceDelete removes matching rows physically, bypassing soft delete.
+func (r *Repo[T]) ForceDelete(ctx context.Context, opts ...Selector) error {
+	if r.db == nil {
+		return gorm.ErrInvalidDB
+	}
+	if err := r.requireSelector(ctx, opts); err != nil {
+		return err
+	}
+	return r.query(ctx, append(opts, WithTrashed())...).Delete(new(T)).Error
+}
+
+// requireSelector rejects bulk writes whose selectors add no condition (gorm.ErrMissingWhereClause),
+// unless AllRows is passed. The soft-delete and tenant conditions added by the repo do not count.
+// Selectors are applied to a dry-run query on a fresh session so that WithScopes / WithCond are seen too.
+func (r *Repo[T]) requireSelector(ctx context.Context, opts []Selector) error {
+	probe := apply(r.db.Session(&gorm.Session{NewDB: true, DryRun: true, SkipHooks: true}).WithContext(ctx), opts...)
+	if _, all := probe.Get("__all_rows__"); all {
+		return nil
+	}
+	var rows []T
+	probe = probe.Unscoped().Find(&rows)
+	if probe.Error != nil {
+		return probe.Error
+	}
+	if c, ok := probe.Statement.Clauses["WHERE"]; ok {
+		if where, ok := c.Expression.(clause.Where); ok && len(where.Exprs) > 0 {
+			return nil
+		}
+	}
+	return gorm.ErrMissingWhereClause
+}
+
+// -------END------soft delete selectors----END---------
+
+// -------BEGIN------optimistic lock & audit-----BEGIN--------
+
+// updateVersioned UPDATE ... SET version = version + 1 WHERE pk = ? AND version = ?
+func (r *Repo[T]) updateVersioned(ctx context.Context, m *T, cols []string) error {
+	field, err := r.versionField()
+	if err != nil {
+		return err
+	}
+	sch := r.schema()
+	rv := reflect.ValueOf(m)
+	current, _ := field.ValueOf(ctx, rv)
+	version, isNull := versionValue(current)
+	if err = field.Set(ctx, rv, nextVersion(field, version)); err != nil {
+		return err
+	}
+
+	// a NULL version compiles to IS NULL
+	var expected any = version
+	if isNull {
+		expected = nil
+	}
+	qb := r.db.WithContext(ctx).Model(m).Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: expected})
+	if len(cols) > 0 {
+		qb = qb.Select(append(cols, field.DBName))
+	} else {
+		qb = qb.Select("*").Omit(r.immutableColumns(sch)...)
+	}
+	res := qb.Updates(m)
+	if res.Error == nil && res.RowsAffected == 0 {
+		res.Error = &ConflictError{Table: sch.Table, Key: r.primaryValue(ctx, sch, rv), Version: version}
+	}
+	if res.Error != nil {
+		_ = field.Set(ctx, rv, current)
+	}
+	return res.Error
+}
+
+// versionField the OptimisticLock field, or why it cannot be used.
+func (r *Repo[T]) versionField() (*schema.Field, error) {
+	if r.schema() == nil {
+		return nil, errors.New("repoMng: cannot parse model")
+	}
+	return r.version, r.versionErr
+}
+
+// resolveVersion looks up the OptimisticLock column; it must be an integer or a pointer to one.
+func resolveVersion(sch *schema.Schema, column string) (*schema.Field, error) {
+	field := sch.LookUpField(column)
+	if field == nil {
+		return nil, fmt.Errorf("repoMng: version column %s not found on %s", column, sch.Name)
+	}
+	switch field.IndirectFieldType.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return field, nil
+	}
+	return nil, fmt.Errorf("repoMng: version column %s on %s is %s, want an integer", column, sch.Name, field.FieldType)
+}
+
+// nextVersion version+1 in the field's type; pointer fields get a new pointer so the caller's value is not written through.
+func nextVersion(field *schema.Field, version int64) any {
+	next := reflect.New(field.IndirectFieldType)
+	if next.Elem().CanInt() {
+		next.Elem().SetInt(version + 1)
+	} else {
+		next.Elem().SetUint(uint64(version + 1))
+	}
+	if field.FieldType.Kind() == reflect.Ptr {
+		return next.Interface()
+	}
+	return next.Elem().Interface()
+}
+
+// versionValue reads an integer version, dereferencing pointers; nil reports isNull.
+func versionValue(v any) (version int64, isNull bool) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return 0, true
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), false
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(rv.Uint()), false
+	}
+	return 0, !rv.IsValid()
+}
+
+// immutableColumns columns never rewritten by a full-row update
+func (r *Repo[T]) immutableColumns(sch *schema.Schema) []string {
+	cols := append([]string{}, sch.PrimaryFieldDBNames...)
+	for _, field := range sch.Fields {
+		if field.AutoCreateTime > 0 && field.DBName != "" {
+			cols = append(cols, field.DBName)
+		}
+	}
+	if r.opts.createdBy != "" {
+		cols = append(cols, r.opts.createdBy)
+	}
+	return cols
+}
+
+func (r *Repo[T]) primaryValue(ctx context.Context, sch *schema.Schema, rv reflect.Value) any {
+	if sch.PrioritizedPrimaryField == nil {
+		return nil
+	}
+	v, _ := sch.PrioritizedPrimaryField.ValueOf(ctx, rv)
+	return v
+}
+
+// fillAudit sets created_by (onCreate) and updated_by on m when the model has those fields.
+func (r *Repo[T]) fillAudit(ctx context.Context, m *T, onCreate bool) {
+	if r.opts.createdBy == "" && r.opts.updatedBy == "" {
+		return
+	}
+	by := r.actor(ctx)
+	sch := r.schema()
+	if by == "" || sch == nil {
+		return
+	}
+	rv := reflect.ValueOf(m)
+	set := func(column string) {
+		if column == "" {
+			return
+		}
+		if field := sch.LookUpField(column); field != nil {
+			_ = field.Set(ctx, rv, by)
+		}
+	}
+	if onCreate {
+		if field := sch.LookUpField(r.opts.createdBy); field != nil {
+			if _, zero := field.ValueOf(ctx, rv); zero {
+				set(r.opts.createdBy)
+			}
+		}
+	}
+	set(r.opts.updatedBy)
+}
+
+// auditedCols adds updated_by to an explicit column list.
+func (r *Repo[T]) auditedCols(cols []string) []string {
+	if len(cols) == 0 || r.opts.updatedBy == "" {
+		return cols
+	}
+	return append(cols, r.opts.updatedBy)
+}
+
+func (r *Repo[T]) actor(ctx context.Context) string {
+	if r.opts.actor != nil {
+		return r.opts.actor(ctx)
+	}
+	return identityMng.LoginIDFromContext(ctx)
+}
+
+// schema parses T once, nil when T is not a gorm model.
+func (r *Repo[T]) schema() *schema.Schema {
+	r.schemaOnce.Do(func() {
+		stmt := &gorm.Statement{DB: r.db}
+		if err := stmt.Parse(new(T)); err == nil {
+			r.sch = stmt.Schema
+			if r.opts.version != "" {
+				r.version, r.versionErr = resolveVersion(r.sch, r.opts.version)
+			}
+		}
+	})
+	return r.sch
+}
+
+// softDeleteValues the assignments for a repo-managed soft delete
+func (r *Repo[T]) softDeleteValues(ctx context.Context, column string) map[string]any {
+	values := map[string]any{column: time.Now()}
+	if by := r.actor(ctx); by != "" && r.opts.updatedBy != "" {
+		values[r.opts.updatedBy] = by
+	}
+	return values
+}
+
+// -------END------optimistic lock & audit----END---------
@@ -13,6 +13,8 @@ type Manager struct {
 	mu  sync.RWMutex
 	dbs map[string]*gorm.DB
 	def string
+
+	tenancy *tenancy
 }
 
 func NewManager() *Manager { return &Manager{dbs: make(map[string]*gorm.DB)} }
@@ -59,8 +61,14 @@ func (m *Manager) InTx(name string, ctx context.Context, fn func(ctx context.Con
 }
 
 // Set is a typed-access view on top of a *gorm.DB.
-type Set struct{ db *gorm.DB }
+type Set struct {
+	db     *gorm.DB
+	tenant string
+}
 
 func (s *Set) DB() *gorm.DB { return s.db }
 
-func (s *Set) BindTx(tx *gorm.DB) *Set { return &Set{db: tx} }
+// Tenant the tenant ID of a Set returned by Manager.Tenant, empty otherwise.
+func (s *Set) Tenant() string { return s.tenant }
+
+func (s *Set) BindTx(tx *gorm.DB) *Set { return &Set{db: tx, tenant: s.tenant} }
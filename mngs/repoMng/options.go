@@ -1,8 +1,11 @@
 package repoMng
 
 import (
+	"encoding/json"
 	"strings"
 
+	"github.com/wiidz/goutil/helpers/condHelper"
+
 	"gorm.io/gorm"
 	"gorm.io/plugin/dbresolver"
 )
@@ -55,6 +58,17 @@ func WithWriteRoute() Selector {
 	return selFn(func(db *gorm.DB) *gorm.DB { return db.Clauses(dbresolver.Write) })
 }
 
+// AllRows lets Delete / Restore / ForceDelete run without a filter, affecting every row
+// (still limited by the soft-delete and tenant scopes). Without it those calls return gorm.ErrMissingWhereClause.
+func AllRows() Selector {
+	return selFn(func(db *gorm.DB) *gorm.DB { return db.Set("__all_rows__", true) })
+}
+
+// WithoutCount skips the COUNT query in List; total is returned as -1
+func WithoutCount() Selector {
+	return selFn(func(db *gorm.DB) *gorm.DB { return db.Set("__skip_count__", true) })
+}
+
 type pager struct{ page, size int }
 
 func WithPage(page, size int) Selector {
@@ -77,3 +91,42 @@ func applyPage(db *gorm.DB, opts ...Selector) *gorm.DB {
 	}
 	return db.Offset((page - 1) * size).Limit(size)
 }
+
+// JSON / JSONB (Postgres operators, MySQL JSON functions)
+
+// WithJSONEq matches rows whose JSON column has value at path ($.a.b), compared as text.
+func WithJSONEq(column, path string, v any) Selector {
+	return WithCond(condHelper.JSONEq(column, path, v))
+}
+
+// WithJSONPath compares the value at path with op (= != > >= < <= like ...), as text.
+func WithJSONPath(column, path, op string, v any) Selector {
+	return WithCond(condHelper.JSONPath(column, path, op, v))
+}
+
+// WithJSONContains matches rows whose JSON column contains v (column @> v on Postgres).
+// v may be a JSON string or any value that is marshalled to JSON.
+func WithJSONContains(column string, v any) Selector {
+	doc, ok := v.(string)
+	if !ok {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return selFn(func(db *gorm.DB) *gorm.DB {
+				_ = db.AddError(err)
+				return db
+			})
+		}
+		doc = string(b)
+	}
+	return WithCond(condHelper.JSONContains(column, doc))
+}
+
+// WithJSONHasKey matches rows whose JSON column has path ($.key or $.a.b).
+func WithJSONHasKey(column, path string) Selector {
+	return WithCond(condHelper.JSONHasKey(column, path))
+}
+
+// WithCond applies a condHelper condition; columns are checked against allow (identifier syntax only when empty)
+func WithCond(cond condHelper.Cond, allow ...string) Selector {
+	return selFn(condHelper.Scope(cond, condHelper.Allow(allow...)))
+}
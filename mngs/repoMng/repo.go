@@ -2,14 +2,32 @@ package repoMng
 
 import (
 	"context"
+	"sync"
 
+	"github.com/wiidz/goutil/helpers/cursorHelper"
 	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 )
 
-type Repo[T any] struct{ db *gorm.DB }
+type Repo[T any] struct {
+	db   *gorm.DB
+	opts repoOptions
+
+	schemaOnce sync.Once
+	sch        *schema.Schema
+	version    *schema.Field // OptimisticLock column, resolved with the schema
+	versionErr error
+}
 
 // RepoOf returns a generic repository for type T bound to db.
-func RepoOf[T any](db *gorm.DB) *Repo[T] { return &Repo[T]{db: db} }
+// opts enable soft delete, optimistic locking and audit columns.
+func RepoOf[T any](db *gorm.DB, opts ...RepoOption) *Repo[T] {
+	r := &Repo[T]{db: db}
+	for _, opt := range opts {
+		opt(&r.opts)
+	}
+	return r
+}
 
 // Reads
 func (r *Repo[T]) GetByID(ctx context.Context, id any) (*T, error) {
@@ -17,7 +35,7 @@ func (r *Repo[T]) GetByID(ctx context.Context, id any) (*T, error) {
 		return nil, gorm.ErrInvalidDB
 	}
 	var m T
-	if err := r.db.WithContext(ctx).First(&m, id).Error; err != nil {
+	if err := r.query(ctx).First(&m, id).Error; err != nil {
 		return nil, err
 	}
 	return &m, nil
@@ -27,7 +45,7 @@ func (r *Repo[T]) First(ctx context.Context, opts ...Selector) (*T, error) {
 	if r.db == nil {
 		return nil, gorm.ErrInvalidDB
 	}
-	qb := apply(r.db.WithContext(ctx), opts...)
+	qb := r.query(ctx, opts...)
 	var m T
 	if err := qb.First(&m).Error; err != nil {
 		return nil, err
@@ -39,10 +57,12 @@ func (r *Repo[T]) List(ctx context.Context, opts ...Selector) ([]*T, int64, erro
 	if r.db == nil {
 		return nil, 0, gorm.ErrInvalidDB
 	}
-	qb := apply(r.db.WithContext(ctx), opts...)
-	var total int64
-	if err := qb.Model(new(T)).Count(&total).Error; err != nil {
-		return nil, 0, err
+	qb := r.query(ctx, opts...)
+	total := int64(-1)
+	if _, skip := qb.Get("__skip_count__"); !skip {
+		if err := qb.Model(new(T)).Count(&total).Error; err != nil {
+			return nil, 0, err
+		}
 	}
 	qb = applyPage(qb, opts...)
 	var rows []T
@@ -56,11 +76,30 @@ func (r *Repo[T]) List(ctx context.Context, opts ...Selector) ([]*T, int64, erro
 	return res, total, nil
 }
 
+// ListByCursor keyset pagination ordered by page.Keys; do not combine with WithOrder / WithPage
+func (r *Repo[T]) ListByCursor(ctx context.Context, page cursorHelper.Page, opts ...Selector) ([]*T, *cursorHelper.PageInfo, error) {
+	if r.db == nil {
+		return nil, nil, gorm.ErrInvalidDB
+	}
+	qb := r.query(ctx, opts...)
+	var rows []T
+	info, err := cursorHelper.Paginate(qb, page, &rows)
+	if err != nil {
+		return nil, nil, err
+	}
+	res := make([]*T, 0, len(rows))
+	for i := range rows {
+		res = append(res, &rows[i])
+	}
+	return res, info, nil
+}
+
 // Writes
 func (r *Repo[T]) Create(ctx context.Context, m *T) error {
 	if r.db == nil {
 		return gorm.ErrInvalidDB
 	}
+	r.fillAudit(ctx, m, true)
 	return r.db.WithContext(ctx).Create(m).Error
 }
 
@@ -68,6 +107,11 @@ func (r *Repo[T]) Update(ctx context.Context, m *T, cols ...string) error {
 	if r.db == nil {
 		return gorm.ErrInvalidDB
 	}
+	r.fillAudit(ctx, m, false)
+	cols = r.auditedCols(cols)
+	if r.opts.version != "" {
+		return r.updateVersioned(ctx, m, cols)
+	}
 	if len(cols) == 0 {
 		return r.db.WithContext(ctx).Save(m).Error
 	}
@@ -78,6 +122,12 @@ func (r *Repo[T]) Delete(ctx context.Context, opts ...Selector) error {
 	if r.db == nil {
 		return gorm.ErrInvalidDB
 	}
-	qb := apply(r.db.WithContext(ctx), opts...)
+	if err := r.requireSelector(ctx, opts); err != nil {
+		return err
+	}
+	qb := r.query(ctx, opts...)
+	if column, managed := r.softColumn(); column != "" && !managed {
+		return qb.Model(new(T)).Updates(r.softDeleteValues(ctx, column)).Error
+	}
 	return qb.Delete(new(T)).Error
 }
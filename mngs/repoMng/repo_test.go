@@ -0,0 +1,209 @@
+package repoMng
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wiidz/goutil/helpers/condHelper"
+	"github.com/wiidz/goutil/mngs/identityMng"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+type testUser struct {
+	ID        uint64
+	Name      string
+	DeletedAt gorm.DeletedAt
+}
+
+type testPlain struct {
+	ID   uint64
+	Name string
+}
+
+// dryRunDB builds SQL without a database and records every INSERT / DELETE / UPDATE statement
+func dryRunDB(t *testing.T) (*gorm.DB, *[]string) {
+	t.Helper()
+	db, err := gorm.Open(mysql.New(mysql.Config{DSN: "user:pass@tcp(127.0.0.1:3306)/test", SkipInitializeWithVersion: true}),
+		&gorm.Config{DryRun: true, SkipDefaultTransaction: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stmts []string
+	record := func(tx *gorm.DB) { stmts = append(stmts, tx.Statement.SQL.String()) }
+	if err = db.Callback().Delete().After("gorm:delete").Register("test:record", record); err != nil {
+		t.Fatal(err)
+	}
+	if err = db.Callback().Update().After("gorm:update").Register("test:record", record); err != nil {
+		t.Fatal(err)
+	}
+	if err = db.Callback().Create().After("gorm:create").Register("test:record", record); err != nil {
+		t.Fatal(err)
+	}
+	return db, &stmts
+}
+
+func TestBulkWritesRequireSelector(t *testing.T) {
+	ctx := context.Background()
+	db, _ := dryRunDB(t)
+	users := RepoOf[testUser](db)
+	plain := RepoOf[testPlain](db, SoftDelete("deleted_at"))
+
+	tests := []struct {
+		name string
+		run  func() error
+	}{
+		{"delete gorm soft delete", func() error { return users.Delete(ctx) }},
+		{"delete repo soft delete", func() error { return plain.Delete(ctx) }},
+		{"delete with only trashed", func() error { return users.Delete(ctx, OnlyTrashed()) }},
+		{"delete with order only", func() error { return users.Delete(ctx, WithOrder("id")) }},
+		{"delete with empty cond", func() error { return users.Delete(ctx, WithCond(condHelper.And())) }},
+		{"force delete", func() error { return users.ForceDelete(ctx, WithTrashed()) }},
+		{"restore", func() error { _, err := users.Restore(ctx); return err }},
+		{"restore repo soft delete", func() error { _, err := plain.Restore(ctx); return err }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.run(); !errors.Is(err, gorm.ErrMissingWhereClause) {
+				t.Errorf("err = %v, want gorm.ErrMissingWhereClause", err)
+			}
+		})
+	}
+}
+
+func TestBulkWritesWithSelector(t *testing.T) {
+	ctx := context.Background()
+	db, stmts := dryRunDB(t)
+	users := RepoOf[testUser](db)
+
+	tests := []struct {
+		name string
+		run  func() error
+		want string
+	}{
+		{"delete eq", func() error { return users.Delete(ctx, WithEq("name", "a")) },
+			"UPDATE `test_users` SET `deleted_at`=? WHERE name = ? AND `test_users`.`deleted_at` IS NULL"},
+		{"delete cond", func() error { return users.Delete(ctx, WithCond(condHelper.Gt("id", 3))) },
+			"UPDATE `test_users` SET `deleted_at`=? WHERE `id` > ? AND `test_users`.`deleted_at` IS NULL"},
+		{"delete scope", func() error {
+			return users.Delete(ctx, WithScopes(func(db *gorm.DB) *gorm.DB { return db.Where("id < ?", 9) }))
+		}, "UPDATE `test_users` SET `deleted_at`=? WHERE id < ? AND `test_users`.`deleted_at` IS NULL"},
+		{"delete all rows", func() error { return users.Delete(ctx, AllRows()) },
+			"UPDATE `test_users` SET `deleted_at`=? WHERE `test_users`.`deleted_at` IS NULL"},
+		{"force delete", func() error { return users.ForceDelete(ctx, WithIn("id", []int{1, 2})) },
+			"DELETE FROM `test_users` WHERE id IN (?,?)"},
+		{"restore", func() error { _, err := users.Restore(ctx, WithEq("id", 1)); return err },
+			"UPDATE `test_users` SET `deleted_at`=? WHERE id = ? AND `test_users`.`deleted_at` IS NOT NULL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			*stmts = nil
+			if err := tt.run(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(*stmts) != 1 || strings.TrimSpace((*stmts)[0]) != tt.want {
+				t.Errorf("sql = %q, want %q", *stmts, tt.want)
+			}
+		})
+	}
+}
+
+type testVersioned struct {
+	ID      uint64
+	Name    string
+	Version int32
+}
+
+type testVersionedPtr struct {
+	ID      uint64
+	Name    string
+	Version *int64
+}
+
+type testVersionedNull struct {
+	ID      uint64
+	Version sql.NullInt64
+}
+
+// In dry-run mode no row is affected, so every versioned Update ends in a ConflictError.
+func TestOptimisticLockVersionTypes(t *testing.T) {
+	ctx := context.Background()
+	db, stmts := dryRunDB(t)
+
+	row := &testVersioned{ID: 1, Version: 5}
+	err := RepoOf[testVersioned](db, OptimisticLock("version")).Update(ctx, row)
+	if !errors.Is(err, ErrVersionConflict) || row.Version != 5 {
+		t.Errorf("int32: err = %v, version = %d", err, row.Version)
+	}
+
+	v := int64(3)
+	ptr := &testVersionedPtr{ID: 1, Version: &v}
+	err = RepoOf[testVersionedPtr](db, OptimisticLock("version")).Update(ctx, ptr)
+	if !errors.Is(err, ErrVersionConflict) || ptr.Version != &v || v != 3 {
+		t.Errorf("pointer: err = %v, version = %v (%d)", err, ptr.Version, v)
+	}
+
+	*stmts = nil
+	null := &testVersionedPtr{ID: 1}
+	err = RepoOf[testVersionedPtr](db, OptimisticLock("version")).Update(ctx, null)
+	if !errors.Is(err, ErrVersionConflict) || null.Version != nil {
+		t.Errorf("nil pointer: err = %v, version = %v", err, null.Version)
+	}
+	if len(*stmts) != 1 || !strings.Contains((*stmts)[0], "`test_versioned_ptrs`.`version` IS NULL") {
+		t.Errorf("nil pointer sql = %q", *stmts)
+	}
+
+	err = RepoOf[testVersionedNull](db, OptimisticLock("version")).Update(ctx, &testVersionedNull{ID: 1})
+	if err == nil || errors.Is(err, ErrVersionConflict) {
+		t.Errorf("sql.NullInt64: err = %v, want unsupported type", err)
+	}
+}
+
+type testAudited struct {
+	ID        uint64
+	Email     string
+	Name      string
+	CreatedBy string
+	UpdatedBy string
+	Version   int64
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func TestUpsertKeepsImmutableColumns(t *testing.T) {
+	ctx := identityMng.ContextWithLoginID(context.Background(), "u1")
+	db, stmts := dryRunDB(t)
+	repo := RepoOf[testAudited](db, OptimisticLock("version"), AuditColumns("created_by", "updated_by"))
+
+	tests := []struct {
+		name string
+		opts []UpsertOption
+		want string
+	}{
+		{"update all", []UpsertOption{OnConflict("email")},
+			"ON DUPLICATE KEY UPDATE `email`=VALUES(`email`),`name`=VALUES(`name`),`updated_by`=VALUES(`updated_by`),`updated_at`=VALUES(`updated_at`),`version`=`test_auditeds`.`version` + 1"},
+		{"update columns", []UpsertOption{OnConflict("email"), UpdateColumns("name", "version")},
+			"ON DUPLICATE KEY UPDATE `name`=VALUES(`name`),`version`=`test_auditeds`.`version` + 1"},
+		{"do nothing", []UpsertOption{OnConflict("email"), DoNothing()},
+			"ON DUPLICATE KEY UPDATE `id`=`id`"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			*stmts = nil
+			row := &testAudited{Email: "a@b.c", Name: "a"}
+			if err := repo.Upsert(ctx, []*testAudited{row}, tt.opts...); err != nil {
+				t.Fatal(err)
+			}
+			if len(*stmts) != 1 || !strings.HasSuffix(strings.TrimSpace((*stmts)[0]), tt.want) {
+				t.Errorf("sql = %q, want suffix %q", *stmts, tt.want)
+			}
+			if row.CreatedBy != "u1" || row.UpdatedBy != "u1" {
+				t.Errorf("audit columns not filled: %+v", row)
+			}
+		})
+	}
+}
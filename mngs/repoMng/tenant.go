@@ -0,0 +1,500 @@
+package repoMng
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrNoTenant the context carries no tenant
+	ErrNoTenant = errors.New("repoMng: no tenant in context")
+	// ErrTenancyDisabled Tenant was called before SetupTenancy
+	ErrTenancyDisabled = errors.New("repoMng: tenancy is not set up")
+	// ErrTenantUnscoped a TenantRow statement could not be limited to the tenant (Table / Raw / Exec,
+	// or a model without the tenant column); use WithoutTenantScope for deliberate cross-tenant access
+	ErrTenantUnscoped = errors.New("repoMng: statement cannot be scoped to the tenant")
+)
+
+// TenantStrategy how tenants are isolated.
+type TenantStrategy int8
+
+const (
+	// TenantDatabase one database (and connection pool) per tenant, opened by TenantConfig.Open.
+	TenantDatabase TenantStrategy = iota + 1
+	// TenantSchema one Postgres schema per tenant; each tenant gets a pool whose search_path is its schema.
+	TenantSchema
+	// TenantRow shared tables with a tenant column; reads/updates/deletes are filtered and creates are stamped.
+	TenantRow
+)
+
+// TenantConfig configures Manager.SetupTenancy.
+type TenantConfig struct {
+	Strategy TenantStrategy
+
+	// Resolve extracts the tenant from ctx (default TenantFromContext).
+	Resolve func(ctx context.Context) (string, error)
+
+	// Open opens a tenant's database (TenantDatabase, optional for TenantSchema).
+	Open func(ctx context.Context, tenant string) (*gorm.DB, error)
+
+	// DSN base Postgres DSN for TenantSchema when Open is nil; search_path is appended per tenant.
+	DSN string
+	// SchemaName maps a tenant to its schema (default: the tenant ID itself).
+	SchemaName func(tenant string) string
+	// GormConfig used when opening tenant pools from DSN.
+	GormConfig *gorm.Config
+
+	// Base registered DB shared by all tenants in TenantRow mode (default: the default DB).
+	Base string
+	// Column tenant column for TenantRow (default tenant_id).
+	Column string
+
+	// IdleTimeout closes tenant pools unused for this long (default 10 minutes).
+	// Do not hold a tenant Set longer than this.
+	IdleTimeout time.Duration
+	// MaxOpenConns / MaxIdleConns per tenant pool (default 5 / 2).
+	MaxOpenConns int
+	MaxIdleConns int
+}
+
+type tenantCtxKey struct{}
+
+// ContextWithTenant stores the tenant ID in ctx; usually done by an HTTP middleware.
+func ContextWithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantCtxKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant ID stored by ContextWithTenant.
+func TenantFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	tenant, ok := ctx.Value(tenantCtxKey{}).(string)
+	return tenant, ok && tenant != ""
+}
+
+// tenantPool a lazily opened per-tenant connection pool
+type tenantPool struct {
+	ready    chan struct{}
+	db       *gorm.DB
+	err      error
+	lastUsed atomic.Int64
+}
+
+type tenancy struct {
+	cfg TenantConfig
+
+	mu    sync.Mutex
+	pools map[string]*tenantPool
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// SetupTenancy enables Manager.Tenant. Call once during startup.
+func (m *Manager) SetupTenancy(cfg TenantConfig) error {
+	if cfg.Resolve == nil {
+		cfg.Resolve = func(ctx context.Context) (string, error) {
+			if tenant, ok := TenantFromContext(ctx); ok {
+				return tenant, nil
+			}
+			return "", ErrNoTenant
+		}
+	}
+	if cfg.SchemaName == nil {
+		cfg.SchemaName = func(tenant string) string { return tenant }
+	}
+	if cfg.Column == "" {
+		cfg.Column = "tenant_id"
+	}
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = 10 * time.Minute
+	}
+	if cfg.MaxOpenConns <= 0 {
+		cfg.MaxOpenConns = 5
+	}
+	if cfg.MaxIdleConns <= 0 {
+		cfg.MaxIdleConns = 2
+	}
+
+	switch cfg.Strategy {
+	case TenantDatabase:
+		if cfg.Open == nil {
+			return errors.New("repoMng: TenantDatabase requires Open")
+		}
+	case TenantSchema:
+		if cfg.Open == nil && cfg.DSN == "" {
+			return errors.New("repoMng: TenantSchema requires Open or DSN")
+		}
+	case TenantRow:
+		base := m.tenantBase(cfg)
+		if base == nil {
+			return errors.New("repoMng: TenantRow requires a registered base DB")
+		}
+		if err := base.Use(&tenantScope{column: cfg.Column}); err != nil && !errors.Is(err, gorm.ErrRegistered) {
+			return err
+		}
+	default:
+		return fmt.Errorf("repoMng: unknown tenant strategy %d", cfg.Strategy)
+	}
+
+	t := &tenancy{cfg: cfg, pools: map[string]*tenantPool{}, stop: make(chan struct{})}
+	m.mu.Lock()
+	old := m.tenancy
+	m.tenancy = t
+	m.mu.Unlock()
+	if old != nil {
+		_ = old.close()
+	}
+	if cfg.Strategy != TenantRow {
+		go t.evictLoop()
+	}
+	return nil
+}
+
+// Tenant returns the Set of the tenant in ctx. The handler does not need to know the strategy:
+// the Set's DB is the tenant database, a pool pinned to the tenant schema, or the shared DB
+// with the tenant scope applied.
+func (m *Manager) Tenant(ctx context.Context) (*Set, error) {
+	m.mu.RLock()
+	t := m.tenancy
+	m.mu.RUnlock()
+	if t == nil {
+		return nil, ErrTenancyDisabled
+	}
+	tenant, err := t.cfg.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if tenant == "" {
+		return nil, ErrNoTenant
+	}
+
+	if t.cfg.Strategy == TenantRow {
+		base := m.tenantBase(t.cfg)
+		if base == nil {
+			return nil, gorm.ErrInvalidDB
+		}
+		return &Set{db: base.Set(tenantSettingKey, tenant).Session(&gorm.Session{}), tenant: tenant}, nil
+	}
+	db, err := t.pool(ctx, tenant)
+	if err != nil {
+		return nil, err
+	}
+	return &Set{db: db, tenant: tenant}, nil
+}
+
+// InTenantTx runs fn in a transaction on the tenant of ctx.
+func (m *Manager) InTenantTx(ctx context.Context, fn func(ctx context.Context, s *Set) error) error {
+	s, err := m.Tenant(ctx)
+	if err != nil {
+		return err
+	}
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error { return fn(ctx, s.BindTx(tx)) })
+}
+
+// CloseTenants closes all tenant pools and stops idle eviction.
+func (m *Manager) CloseTenants() error {
+	m.mu.Lock()
+	t := m.tenancy
+	m.tenancy = nil
+	m.mu.Unlock()
+	if t == nil {
+		return nil
+	}
+	return t.close()
+}
+
+func (m *Manager) tenantBase(cfg TenantConfig) *gorm.DB {
+	name := cfg.Base
+	if name == "" {
+		name = m.def
+	}
+	return m.For(name).db
+}
+
+// -------BEGIN------tenant pools-----BEGIN--------
+
+// pool returns the tenant pool, opening it on first use; concurrent callers wait for one open.
+func (t *tenancy) pool(ctx context.Context, tenant string) (*gorm.DB, error) {
+	t.mu.Lock()
+	p, ok := t.pools[tenant]
+	if !ok {
+		p = &tenantPool{ready: make(chan struct{})}
+		t.pools[tenant] = p
+	}
+	t.mu.Unlock()
+
+	if !ok {
+		p.db, p.err = t.open(ctx, tenant)
+		p.lastUsed.Store(time.Now().UnixNano())
+		close(p.ready)
+		if p.err != nil {
+			// do not cache failures; the next request retries
+			t.mu.Lock()
+			if t.pools[tenant] == p {
+				delete(t.pools, tenant)
+			}
+			t.mu.Unlock()
+		}
+	} else {
+		select {
+		case <-p.ready:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	p.lastUsed.Store(time.Now().UnixNano())
+	return p.db, nil
+}
+
+func (t *tenancy) open(ctx context.Context, tenant string) (*gorm.DB, error) {
+	var (
+		db  *gorm.DB
+		err error
+	)
+	if t.cfg.Open != nil {
+		db, err = t.cfg.Open(ctx, tenant)
+	} else {
+		db, err = t.openSchema(tenant)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repoMng: open tenant %s: %w", tenant, err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.SetMaxOpenConns(t.cfg.MaxOpenConns)
+		sqlDB.SetMaxIdleConns(t.cfg.MaxIdleConns)
+	}
+	return db, nil
+}
+
+var schemaNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,62}$`)
+
+// openSchema opens a Postgres pool whose connections start with search_path = tenant schema.
+func (t *tenancy) openSchema(tenant string) (*gorm.DB, error) {
+	schemaName := t.cfg.SchemaName(tenant)
+	if !schemaNameRe.MatchString(schemaName) {
+		return nil, fmt.Errorf("invalid schema name %q", schemaName)
+	}
+	gormCfg := t.cfg.GormConfig
+	if gormCfg == nil {
+		gormCfg = &gorm.Config{}
+	}
+	cfgCopy := *gormCfg
+	return gorm.Open(postgres.Open(SearchPathDSN(t.cfg.DSN, schemaName)), &cfgCopy)
+}
+
+// SearchPathDSN appends search_path to a Postgres DSN (URL or key=value form).
+func SearchPathDSN(dsn, schemaName string) string {
+	if strings.Contains(dsn, "://") {
+		if u, err := url.Parse(dsn); err == nil {
+			q := u.Query()
+			q.Set("search_path", schemaName)
+			u.RawQuery = q.Encode()
+			return u.String()
+		}
+	}
+	return strings.TrimSpace(dsn) + " search_path=" + schemaName
+}
+
+// evictLoop closes pools idle longer than IdleTimeout.
+func (t *tenancy) evictLoop() {
+	ticker := time.NewTicker(t.cfg.IdleTimeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.stop:
+			return
+		case <-ticker.C:
+			t.evictIdle(time.Now().Add(-t.cfg.IdleTimeout))
+		}
+	}
+}
+
+func (t *tenancy) evictIdle(before time.Time) {
+	var idle []*tenantPool
+	t.mu.Lock()
+	for tenant, p := range t.pools {
+		select {
+		case <-p.ready:
+		default:
+			continue // still opening
+		}
+		if p.lastUsed.Load() < before.UnixNano() {
+			idle = append(idle, p)
+			delete(t.pools, tenant)
+		}
+	}
+	t.mu.Unlock()
+	for _, p := range idle {
+		closeGormDB(p.db)
+	}
+}
+
+func (t *tenancy) close() error {
+	t.stopOnce.Do(func() { close(t.stop) })
+	t.mu.Lock()
+	pools := t.pools
+	t.pools = map[string]*tenantPool{}
+	t.mu.Unlock()
+
+	var errs []error
+	for _, p := range pools {
+		<-p.ready
+		errs = append(errs, closeGormDB(p.db))
+	}
+	return errors.Join(errs...)
+}
+
+func closeGormDB(db *gorm.DB) error {
+	if db == nil {
+		return nil
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// -------END------tenant pools----END---------
+
+// -------BEGIN------row scope-----BEGIN--------
+
+const (
+	tenantSettingKey = "repoMng:tenant"
+	tenantSkipKey    = "repoMng:tenant_skip"
+)
+
+// WithoutTenantScope opts a TenantRow session out of the tenant scope for statements that cannot be
+// scoped (Table / Raw / Exec, models without the tenant column) or that must cross tenants.
+// Use as db.Scopes(repoMng.WithoutTenantScope) or repoMng.WithScopes(repoMng.WithoutTenantScope).
+func WithoutTenantScope(db *gorm.DB) *gorm.DB {
+	return db.Set(tenantSkipKey, true)
+}
+
+// tenantScope gorm plugin for TenantRow: filters by the tenant column and stamps it on create/update.
+// It fails closed: on a tenant session, statements it cannot scope return ErrTenantUnscoped
+// unless WithoutTenantScope is applied.
+type tenantScope struct {
+	column string
+}
+
+func (s *tenantScope) Name() string { return "repoMng:tenant" }
+
+func (s *tenantScope) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Create().Before("gorm:create").Register("repoMng:tenant_stamp", s.stamp); err != nil {
+		return err
+	}
+	if err := cb.Query().Before("gorm:query").Register("repoMng:tenant_filter", s.filter); err != nil {
+		return err
+	}
+	if err := cb.Row().Before("gorm:row").Register("repoMng:tenant_filter", s.filter); err != nil {
+		return err
+	}
+	if err := cb.Raw().Before("gorm:raw").Register("repoMng:tenant_filter", s.filter); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register("repoMng:tenant_filter", s.filter); err != nil {
+		return err
+	}
+	// full-row updates must not move the row to another tenant
+	if err := cb.Update().Before("gorm:update").Register("repoMng:tenant_stamp", s.stamp); err != nil {
+		return err
+	}
+	return cb.Delete().Before("gorm:delete").Register("repoMng:tenant_filter", s.filter)
+}
+
+// tenantOf the tenant of a TenantRow session, false when absent or opted out
+func tenantOf(db *gorm.DB) (string, bool) {
+	if _, skip := db.Get(tenantSkipKey); skip {
+		return "", false
+	}
+	v, ok := db.Get(tenantSettingKey)
+	if !ok {
+		return "", false
+	}
+	tenant, ok := v.(string)
+	return tenant, ok && tenant != ""
+}
+
+// scopable reports whether stmt targets a model with the tenant column and is not prebuilt SQL;
+// otherwise it records ErrTenantUnscoped on db.
+func (s *tenantScope) scopable(db *gorm.DB) bool {
+	stmt := db.Statement
+	if stmt.SQL.Len() > 0 {
+		_ = db.AddError(fmt.Errorf("%w: raw SQL", ErrTenantUnscoped))
+		return false
+	}
+	if stmt.Schema == nil && stmt.Model != nil {
+		_ = stmt.Parse(stmt.Model)
+	}
+	if stmt.Schema == nil {
+		_ = db.AddError(fmt.Errorf("%w: table %q has no model", ErrTenantUnscoped, stmt.Table))
+		return false
+	}
+	if stmt.Schema.LookUpField(s.column) == nil {
+		_ = db.AddError(fmt.Errorf("%w: %s has no %s column", ErrTenantUnscoped, stmt.Schema.Name, s.column))
+		return false
+	}
+	return true
+}
+
+func (s *tenantScope) filter(db *gorm.DB) {
+	tenant, ok := tenantOf(db)
+	if !ok || db.Error != nil || !s.scopable(db) {
+		return
+	}
+	stmt := db.Statement
+	stmt.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: s.column}, Value: tenant},
+	}})
+}
+
+func (s *tenantScope) stamp(db *gorm.DB) {
+	tenant, ok := tenantOf(db)
+	if !ok || db.Error != nil || !s.scopable(db) {
+		return
+	}
+	field := db.Statement.Schema.LookUpField(s.column)
+	ctx := db.Statement.Context
+	set := func(rv reflect.Value) {
+		switch m := rv.Interface().(type) {
+		case map[string]interface{}:
+			m[field.DBName] = tenant
+		default:
+			if rv.Kind() != reflect.Struct {
+				return
+			}
+			if err := field.Set(ctx, rv, tenant); err != nil {
+				_ = db.AddError(err)
+			}
+		}
+	}
+	switch rv := reflect.Indirect(db.Statement.ReflectValue); rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			set(reflect.Indirect(rv.Index(i)))
+		}
+	case reflect.Struct, reflect.Map:
+		set(rv)
+	}
+}
+
+// -------END------row scope----END---------
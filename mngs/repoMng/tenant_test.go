@@ -0,0 +1,88 @@
+package repoMng
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+type testOrder struct {
+	ID       uint64
+	TenantID string
+	Amount   int
+}
+
+func tenantSet(t *testing.T) *Set {
+	t.Helper()
+	db, _ := dryRunDB(t)
+	mgr := NewManager()
+	mgr.SetupDefault(db)
+	if err := mgr.SetupTenancy(TenantConfig{Strategy: TenantRow}); err != nil {
+		t.Fatal(err)
+	}
+	s, err := mgr.Tenant(ContextWithTenant(context.Background(), "t1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestTenantRowScoped(t *testing.T) {
+	db := tenantSet(t).DB()
+
+	var orders []testOrder
+	stmt := db.Where("amount > ?", 1).Find(&orders).Statement
+	if sql := stmt.SQL.String(); !strings.Contains(sql, "`test_orders`.`tenant_id` = ?") {
+		t.Errorf("query not filtered: %s", sql)
+	}
+
+	order := testOrder{Amount: 1}
+	if err := db.Create(&order).Error; err != nil || order.TenantID != "t1" {
+		t.Errorf("create not stamped: tenant %q, err %v", order.TenantID, err)
+	}
+
+	values := map[string]interface{}{"amount": 2}
+	if err := db.Model(&testOrder{}).Create(values).Error; err != nil || values["tenant_id"] != "t1" {
+		t.Errorf("map create not stamped: %v, err %v", values, err)
+	}
+}
+
+func TestTenantRowFailsClosed(t *testing.T) {
+	db := tenantSet(t).DB()
+
+	tests := []struct {
+		name string
+		run  func(db *gorm.DB) *gorm.DB
+	}{
+		{"table query", func(db *gorm.DB) *gorm.DB {
+			var rows []map[string]interface{}
+			return db.Table("test_orders").Find(&rows)
+		}},
+		{"table update", func(db *gorm.DB) *gorm.DB {
+			return db.Table("test_orders").Where("id = ?", 1).Updates(map[string]interface{}{"amount": 1})
+		}},
+		{"raw query", func(db *gorm.DB) *gorm.DB {
+			var rows []testOrder
+			return db.Raw("SELECT * FROM test_orders").Find(&rows)
+		}},
+		{"exec", func(db *gorm.DB) *gorm.DB { return db.Exec("DELETE FROM test_orders") }},
+		{"model without column", func(db *gorm.DB) *gorm.DB {
+			var rows []testPlain
+			return db.Find(&rows)
+		}},
+		{"create without column", func(db *gorm.DB) *gorm.DB { return db.Create(&testPlain{Name: "a"}) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.run(db).Error; !errors.Is(err, ErrTenantUnscoped) {
+				t.Errorf("err = %v, want ErrTenantUnscoped", err)
+			}
+			if err := tt.run(db.Scopes(WithoutTenantScope)).Error; err != nil {
+				t.Errorf("opted out: unexpected error %v", err)
+			}
+		})
+	}
+}
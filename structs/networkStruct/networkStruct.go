@@ -2,6 +2,8 @@ package networkStruct
 
 import (
 	"net/http"
+
+	"github.com/wiidz/goutil/helpers/cursorHelper"
 )
 
 type ContentType int8
@@ -36,6 +38,8 @@ type ReadCommonStruct struct {
 	PageNow  int    `json:"page_now" belong:"etc" default:"1"`
 	PageSize int    `json:"page_size" belong:"etc" default:"10"`
 	Order    string `json:"order" belong:"etc" default:"id asc"`
+
+	cursorHelper.Request // 游标分页：cursor / use_cursor / skip_count，传入 cursor 时忽略 page_now
 }
 
 type Method int8
@@ -137,6 +141,8 @@ type Params struct {
 	PageSize int    `json:"page_size" belong:"etc" default:"10"`  // [read]
 	Order    string `json:"order" belong:"etc" default:"ids asc"` // [read]
 
+	cursorHelper.Request // [read] - 游标分页
+
 	// 根据前端参数处理后的数据
 	Condition map[string]interface{} // [read、update、delete] 条件
 	Value     map[string]interface{} // [update、insert] - 数据